@@ -17,8 +17,10 @@ limitations under the License.
 package v1beta1
 
 import (
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // BuildArg represents a build argument used when building a container image.
@@ -116,15 +118,37 @@ type ModprobeArgs struct {
 	Unload []string `json:"unload,omitempty"`
 }
 
+// FirmwareSource is the format of the firmware(s) shipped in the module loader image.
+// +kubebuilder:validation:Enum=Directory;Tarball
+type FirmwareSource string
+
+const (
+	FirmwareSourceDirectory FirmwareSource = "Directory"
+	FirmwareSourceTarball   FirmwareSource = "Tarball"
+)
+
 type ModprobeSpec struct {
 	// ModuleName is the name of the Module to be loaded.
 	ModuleName string `json:"moduleName"`
 
+	// ModulesLoadingOrder is an optional ordered list of kernel modules to be loaded in the same container.
+	// The modules are loaded one after the other in the order of the list, and unloaded in the reverse order.
+	// Parameters are only passed to ModuleName, which must be part of the list.
+	// If empty, only ModuleName is loaded.
+	// +optional
+	ModulesLoadingOrder []string `json:"modulesLoadingOrder,omitempty"`
+
 	// Parameters is an optional list of kernel module parameters to be provided to modprobe.
 	// They should be in the form of key=value and will be separated by spaces in the modprobe command.
 	// The resulting loading command will be: `modprobe module_name ${Parameters}`.
 	Parameters []string `json:"parameters,omitempty"`
 
+	// UnloadParameters is an optional list of parameters to be provided to modprobe when unloading the module.
+	// The resulting unloading command will be: `modprobe ${Args.Unload} module_name ${UnloadParameters}`.
+	// They cannot be used with RawArgs.Unload.
+	// +optional
+	UnloadParameters []string `json:"unloadParameters,omitempty"`
+
 	// DirName is the root directory for modules.
 	// It adds `-d ${DirName}` to the modprobe command-line.
 	// +kubebuilder:default=/opt
@@ -135,9 +159,12 @@ type ModprobeSpec struct {
 	// +optional
 	Args *ModprobeArgs `json:"args,omitempty"`
 
-	// If RawArgs are specified, they are passed straight to the modprobe binary; all other properties in this
-	// object are ignored.
+	// If RawArgs are specified, they are passed straight to the modprobe binary.
 	// The resulting commands will be: `modprobe ${RawArgs}`.
+	// RawArgs.Load cannot be used with the properties of this object that configure the load command, such as Args.Load,
+	// Parameters, FirmwarePath or PreLoadCommand, and RawArgs.Unload cannot be used with those that configure the
+	// unload command, such as Args.Unload, UnloadParameters or PostUnloadCommand. DirName can only be left to its
+	// default.
 	// +optional
 	RawArgs *ModprobeArgs `json:"rawArgs,omitempty"`
 
@@ -145,17 +172,268 @@ type ModprobeSpec struct {
 	// The firmware(s) will be copied to the host for the kernel to find them.
 	// +optional
 	FirmwarePath string `json:"firmwarePath,omitempty"`
+
+	// FirmwareSource is the format of FirmwarePath.
+	// With Directory, FirmwarePath is a directory that is copied to the host each time the module is loaded.
+	// With Tarball, FirmwarePath is a tar archive that an init container named firmware-extract extracts to the host
+	// before the module loader container starts, which then does not copy anything.
+	// Defaults to Directory.
+	// +optional
+	FirmwareSource FirmwareSource `json:"firmwareSource,omitempty"`
+
+	// FirmwareHostPath is the directory on the host under which the firmware(s) are copied.
+	// It should match the kernel's firmware_class.path parameter.
+	// Defaults to /var/lib/firmware.
+	// +optional
+	FirmwareHostPath string `json:"firmwareHostPath,omitempty"`
+
+	// FirmwareSubDir is the directory, relative to FirmwareHostPath, to which the firmware(s) are copied.
+	// It cannot contain "..".
+	// Defaults to the name of the Module.
+	// +optional
+	FirmwareSubDir string `json:"firmwareSubDir,omitempty"`
+
+	// RetainFirmwareOnUnload, if true, keeps the firmware(s) copied to the host when the module is unloaded.
+	// By default, they are removed.
+	// +optional
+	RetainFirmwareOnUnload bool `json:"retainFirmwareOnUnload,omitempty"`
+
+	// FirmwareVersionScoped, if true, copies the firmware(s) to ${FirmwareHostPath}/${module name}/${kernel version}
+	// instead of ${FirmwareHostPath}/${module name}, so that unloading the module for one kernel version does not
+	// remove the firmware(s) still used for another kernel version.
+	// The kernel module must then request its firmware(s) from that directory.
+	// +optional
+	FirmwareVersionScoped bool `json:"firmwareVersionScoped,omitempty"`
+
+	// FirmwareDecompress, if true, decompresses the .zst and .xz files found in FirmwarePath once they have been
+	// copied to the host, for kernels that cannot load compressed firmware.
+	// +optional
+	FirmwareDecompress bool `json:"firmwareDecompress,omitempty"`
+
+	// RunDepmod, if true, runs depmod for the target kernel before loading the module(s), so that modprobe can
+	// resolve modules that were not yet in modules.dep.
+	// +optional
+	RunDepmod bool `json:"runDepmod,omitempty"`
+
+	// Force, if true, loads the module(s) even if their version magic or symbol versions do not match the running
+	// kernel, which is typically only useful to test pre-release modules.
+	// It cannot be used with RawArgs.Load.
+	// +optional
+	Force bool `json:"force,omitempty"`
+
+	// ForceVermagic, if true, loads the module(s) even if their version magic does not match the running kernel.
+	// It cannot be used with RawArgs.Load or UseInsmod.
+	// +optional
+	ForceVermagic bool `json:"forceVermagic,omitempty"`
+
+	// ForceModversion, if true, loads the module(s) even if their symbol versions do not match the running kernel.
+	// It cannot be used with RawArgs.Load or UseInsmod.
+	// +optional
+	ForceModversion bool `json:"forceModversion,omitempty"`
+
+	// UseInsmod, if true, loads the module with insmod and unloads it with rmmod instead of using modprobe.
+	// This is useful for images that ship a self-contained .ko file without modules.dep.
+	// ModulePath must be set when UseInsmod is true, and ModulesLoadingOrder cannot be used with it.
+	// +optional
+	UseInsmod bool `json:"useInsmod,omitempty"`
+
+	// ModulePath is the path of the .ko file in the image, passed to insmod when UseInsmod is true.
+	// +optional
+	ModulePath string `json:"modulePath,omitempty"`
+
+	// InTreeModuleToRemove is the name of an in-tree kernel module that conflicts with the out-of-tree module.
+	// It is unloaded before the out-of-tree module is loaded, and loaded again after the out-of-tree module is
+	// unloaded. The in-tree module not being present is not considered an error.
+	// +optional
+	InTreeModuleToRemove string `json:"inTreeModuleToRemove,omitempty"`
+
+	// LoadTimeoutSeconds is the number of seconds after which a hanging modprobe or insmod invocation is killed
+	// when loading the module(s), making the postStart hook fail and the module loader container restart.
+	// By default, there is no timeout.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LoadTimeoutSeconds *int64 `json:"loadTimeoutSeconds,omitempty"`
+
+	// PreLoadCommand is an optional list of shell commands run one after the other before the module(s) are
+	// loaded, for instance to write a configuration file to /etc/modprobe.d.
+	// Loading is aborted if any of them fails. They cannot be used with RawArgs.Load.
+	// +optional
+	PreLoadCommand []string `json:"preLoadCommand,omitempty"`
+
+	// PostUnloadCommand is an optional list of shell commands run one after the other once the module(s) have been
+	// unloaded, typically to undo the changes made by PreLoadCommand.
+	// They cannot be used with RawArgs.Unload.
+	// +optional
+	PostUnloadCommand []string `json:"postUnloadCommand,omitempty"`
+
+	// PostLoadVerify is an optional list of shell commands run one after the other once the module(s) have been
+	// loaded, for instance `grep -q 1.2.3 /proc/driver/foo/version`, to check that the driver initialized properly.
+	// Loading fails, and the node is not labeled as ready, if any of them fails. They cannot be used with
+	// RawArgs.Load.
+	// +optional
+	PostLoadVerify []string `json:"postLoadVerify,omitempty"`
+
+	// PreUnloadCommand is an optional list of shell commands run one after the other before the module(s) are
+	// unloaded, for instance to drain the workloads still using the device.
+	// Unloading is aborted if any of them fails.
+	// +optional
+	PreUnloadCommand []string `json:"preUnloadCommand,omitempty"`
+
+	// UnloadGracePeriodSeconds is the number of seconds to wait before running PreUnloadCommand and unloading the
+	// module(s) when the module loader pod terminates.
+	// The pod's termination grace period is extended accordingly.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	UnloadGracePeriodSeconds *int64 `json:"unloadGracePeriodSeconds,omitempty"`
+
+	// ShellPath is the absolute path of the shell used to run the load and unload commands in the module loader
+	// container.
+	// Defaults to /bin/sh.
+	// +optional
+	ShellPath string `json:"shellPath,omitempty"`
+
+	// ModprobePath is the path of the modprobe binary in the module loader container, for images on which it is not
+	// on the PATH.
+	// Defaults to modprobe.
+	// +optional
+	ModprobePath string `json:"modprobePath,omitempty"`
+
+	// ExecForm, if true, runs the load command directly instead of through ShellPath when it needs no shell feature,
+	// so that parameters containing special characters are passed verbatim.
+	// The shell is still used if firmware is copied, if several modules are loaded, or if PreLoadCommand,
+	// InTreeModuleToRemove or RunDepmod is set.
+	// +optional
+	ExecForm bool `json:"execForm,omitempty"`
+}
+
+// ReadinessProbeSpec configures the probe that checks that the kernel module is loaded on the node.
+type ReadinessProbeSpec struct {
+	// +optional
+	// PeriodSeconds is how often (in seconds) to perform the probe.
+	// Defaults to 10 seconds.
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// +optional
+	// TimeoutSeconds is the number of seconds after which the probe times out.
+	// Defaults to 1 second.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// +optional
+	// FailureThreshold is the number of consecutive failures for the probe to be considered failed.
+	// Defaults to 3.
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// LivenessProbeSpec configures the probe that restarts the module loader container if the kernel module is not
+// loaded on the node anymore.
+type LivenessProbeSpec struct {
+	// +optional
+	// PeriodSeconds is how often (in seconds) to perform the probe.
+	// Defaults to 10 seconds.
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// +optional
+	// TimeoutSeconds is the number of seconds after which the probe times out.
+	// Defaults to 1 second.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// +optional
+	// FailureThreshold is the number of consecutive failures after which the container is restarted.
+	// Defaults to 3.
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// StartupProbeSpec configures the probe that delays the readiness and liveness probes of the module loader container
+// until the kernel module has finished initializing.
+type StartupProbeSpec struct {
+	// +optional
+	// Command is the command run in the container to check if the kernel module has finished initializing.
+	// Defaults to checking that the kernel module is loaded.
+	Command []string `json:"command,omitempty"`
+
+	// +optional
+	// InitialDelaySeconds is the number of seconds after the container has started before the probe is first run.
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+
+	// +optional
+	// PeriodSeconds is how often (in seconds) to perform the probe.
+	// Defaults to 10 seconds.
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+
+	// +optional
+	// TimeoutSeconds is the number of seconds after which the probe times out.
+	// Defaults to 1 second.
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// +optional
+	// FailureThreshold is the number of consecutive failures after which the container is restarted.
+	// Defaults to 3.
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// ModuleLoaderSecurityContext holds the user and group identities the module loader pods run with.
+// The module loader container always keeps the SYS_MODULE capability, whatever the identities.
+type ModuleLoaderSecurityContext struct {
+	// +optional
+	// RunAsUser is the UID the module loader container runs as.
+	// Defaults to 0 (root).
+	// +kubebuilder:validation:Minimum=0
+	RunAsUser *int64 `json:"runAsUser,omitempty"`
+
+	// +optional
+	// RunAsGroup is the GID the module loader container runs as.
+	// Defaults to the group set in the image.
+	// +kubebuilder:validation:Minimum=0
+	RunAsGroup *int64 `json:"runAsGroup,omitempty"`
+
+	// +optional
+	// FSGroup is the supplemental group applied to all containers of the module loader pods, and that owns the
+	// volumes that support it.
+	// +kubebuilder:validation:Minimum=0
+	FSGroup *int64 `json:"fsGroup,omitempty"`
 }
 
 type ModuleLoaderContainerSpec struct {
+	// Arguments to the entrypoint.
+	// If Command or Args is set, they replace the default `sleep infinity` command of the module loader container.
+	// More info: https://kubernetes.io/docs/tasks/inject-data-application/define-command-argument-container/#running-a-command-in-a-shell
+	// +optional
+	Args []string `json:"args,omitempty"`
+
 	// Build contains build instructions.
 	// +optional
 	Build *Build `json:"build,omitempty"`
 
+	// Entrypoint array. Not executed within a shell.
+	// If Command or Args is set, they replace the default `sleep infinity` command of the module loader container;
+	// the container image's ENTRYPOINT is used if only Args is set.
+	// More info: https://kubernetes.io/docs/tasks/inject-data-application/define-command-argument-container/#running-a-command-in-a-shell
+	// +optional
+	Command []string `json:"command,omitempty"`
+
 	// ContainerImage is a top-level field
 	// +optional
 	ContainerImage string `json:"containerImage,omitempty"`
 
+	// DisableLifecycleHooks, if true, prevents the operator from loading and unloading the kernel module with the
+	// postStart and preStop hooks of the container.
+	// It is meant to be used when the container loads the module itself, for instance from its entrypoint and
+	// unloads it upon receiving SIGTERM. Unless Command or Args is set, the image's ENTRYPOINT and CMD are then used
+	// instead of `sleep infinity`.
+	// +optional
+	DisableLifecycleHooks bool `json:"disableLifecycleHooks,omitempty"`
+
+	// DisableKernelVersionEnv, if true, prevents the operator from setting the KERNEL_FULL_VERSION environment variable
+	// to the targeted kernel version in the container.
+	// +optional
+	DisableKernelVersionEnv bool `json:"disableKernelVersionEnv,omitempty"`
+
+	// +optional
+	// HostDevPaths restricts the MountHostDev mount to these paths under /dev, for instance /dev/foo0, which are
+	// mounted at the same path in the module loader container instead of the whole /dev.
+	// It requires MountHostDev.
+	HostDevPaths []string `json:"hostDevPaths,omitempty"`
+
 	// Image pull policy.
 	// One of Always, Never, IfNotPresent.
 	// Defaults to Always if :latest tag is specified, or IfNotPresent otherwise.
@@ -170,22 +448,175 @@ type ModuleLoaderContainerSpec struct {
 	// +kubebuilder:validation:MinItems=1
 	KernelMappings []KernelMapping `json:"kernelMappings"`
 
+	// +optional
+	// LivenessProbe, if set, restarts the module loader container if the kernel module was unloaded from the node,
+	// for instance by running rmmod manually. The kernel module is then loaded again when the container restarts.
+	LivenessProbe *LivenessProbeSpec `json:"livenessProbe,omitempty"`
+
 	// Modprobe is a set of properties to customize which module modprobe loads and with which properties.
 	Modprobe ModprobeSpec `json:"modprobe"`
 
+	// +optional
+	// MountHostDev, if true, mounts the host /dev read-write in the module loader container, for modules that create
+	// device nodes or change their permissions when they are loaded.
+	MountHostDev bool `json:"mountHostDev,omitempty"`
+
+	// Name is the name of the module loader container.
+	// Defaults to module-loader.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +optional
+	Name string `json:"name,omitempty"`
+
 	// +optional
 	// Pull contains settings determining how to check if the ModuleLoader image already exists.
 	Pull *PullOptions `json:"pull"`
+
+	// +optional
+	// ReadinessProbe, if set, makes the module loader pod ready only once the kernel module is loaded on the node.
+	ReadinessProbe *ReadinessProbeSpec `json:"readinessProbe,omitempty"`
+
+	// +optional
+	// StartupProbe, if set, gives the kernel module time to initialize before the readiness and liveness probes
+	// start.
+	StartupProbe *StartupProbeSpec `json:"startupProbe,omitempty"`
+
+	// +optional
+	// ReadOnlyRootFilesystem, if true, mounts the root filesystem of the module loader container read-only.
+	// An emptyDir volume is then mounted on /tmp; the firmware(s) are still copied to the host through a writable
+	// volume.
+	ReadOnlyRootFilesystem bool `json:"readOnlyRootFilesystem,omitempty"`
+
+	// SELinuxType is the SELinux type of the module loader container.
+	// Defaults to spc_t if not set. If set to an empty string, no SELinux options are set on the container.
+	// +optional
+	SELinuxType *string `json:"seLinuxType,omitempty"`
+
+	// Compute Resources required by this container.
+	// Limits cannot be lower than requests.
+	// More info: https://kubernetes.io/docs/concepts/configuration/manage-resources-containers/
+	// +optional
+	Resources v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Version, if set, identifies the build of the module loader image, for instance a commit SHA.
+	// It is set as the kmm.node.kubernetes.io/version label on the module loader pods; changing it rolls them out.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`
+	// +optional
+	Version string `json:"version,omitempty"`
 }
 
 type ModuleLoaderSpec struct {
+	// +optional
+	// Affinity is the pod's scheduling constraints.
+	// The kernel version requirement is always added to the required node affinity terms.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#affinity-and-anti-affinity
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
 	// Container holds the properties for the module loader container that runs modprobe.
 	Container ModuleLoaderContainerSpec `json:"container"`
 
+	// +optional
+	// DependsOn is a list of names of Modules that must be loaded on a node before this Module.
+	// The module loader pods are only scheduled on nodes on which all those Modules are ready.
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// +optional
+	// DNSConfig specifies the DNS parameters of the pod, in addition to those generated from DNSPolicy.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-dns-config
+	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
+
+	// +optional
+	// DNSPolicy is the DNS policy of the pod, for instance Default to resolve names with the node's configuration.
+	// Defaults to ClusterFirst. DNSConfig must be set if DNSPolicy is None.
+	// More info: https://kubernetes.io/docs/concepts/services-networking/dns-pod-service/#pod-s-dns-policy
+	DNSPolicy v1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// +optional
+	// ExtraModulesHostPaths is a list of additional directories on the host that contain kernel modules.
+	// They are mounted read-only at the same path in the module loader container, in addition to /lib/modules and
+	// /usr/lib/modules. The paths must be absolute.
+	ExtraModulesHostPaths []string `json:"extraModulesHostPaths,omitempty"`
+
+	// +optional
+	// HostIPC, if true, makes the pod use the host's IPC namespace.
+	HostIPC bool `json:"hostIPC,omitempty"`
+
+	// +optional
+	// InitContainers are run one after the other before the module loader container starts, for instance to verify
+	// the signature of the kernel module or to decompress its firmware.
+	// If the module loader copies firmware, the firmware directory on the host is mounted in them at the same path.
+	// More info: https://kubernetes.io/docs/concepts/workloads/pods/init-containers/
+	InitContainers []v1.Container `json:"initContainers,omitempty"`
+
+	// +optional
+	// HostNetwork, if true, makes the pod use the host's network namespace.
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+
+	// +optional
+	// HostPID, if true, makes the pod use the host's PID namespace, for instance to coordinate with a daemon running
+	// on the host.
+	HostPID bool `json:"hostPID,omitempty"`
+
+	// +optional
+	// ModprobeConfigMap is the name of a ConfigMap in the Module's namespace whose keys are mounted read-only as
+	// files in /etc/modprobe.d in the module loader container, for instance to set the module options in a
+	// modprobe.d snippet. It replaces the content of /etc/modprobe.d in the image.
+	// The module loader pods are not restarted when the ConfigMap changes.
+	ModprobeConfigMap *v1.LocalObjectReference `json:"modprobeConfigMap,omitempty"`
+
+	// +optional
+	// DaemonSetLabels are additional labels set on the DaemonSets, for instance for cost allocation.
+	// They cannot override the labels managed by KMM.
+	DaemonSetLabels map[string]string `json:"daemonSetLabels,omitempty"`
+
+	// +optional
+	// PodAnnotations are additional annotations set on the pods.
+	// Annotations in the KMM domain are reserved and ignored.
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// +optional
+	// PriorityClassName is the name of the PriorityClass of the pod.
+	// Defaults to system-node-critical.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// +optional
+	// SecurityContext overrides the user and group identities of the module loader pods.
+	// The module loader container runs as root by default.
+	SecurityContext *ModuleLoaderSecurityContext `json:"securityContext,omitempty"`
+
 	// +optional
 	// ServiceAccountName is the name of the ServiceAccount to use to run this pod.
 	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// +optional
+	// Sysctls are namespaced sysctls set in the pod before the kernel module is loaded.
+	// Only the sysctls considered safe by Kubernetes are allowed, unless AllowUnsafeSysctls is true.
+	// More info: https://kubernetes.io/docs/tasks/administer-cluster/sysctl-cluster/
+	Sysctls []v1.Sysctl `json:"sysctls,omitempty"`
+
+	// +optional
+	// AllowUnsafeSysctls, if true, allows namespaced sysctls that are not considered safe by Kubernetes in Sysctls.
+	// They must also be allowed on the kubelet with --allowed-unsafe-sysctls.
+	AllowUnsafeSysctls bool `json:"allowUnsafeSysctls,omitempty"`
+
+	// +optional
+	// TolerateAllTaints, if true, adds a toleration matching all taints to Tolerations, so that the module is loaded
+	// on all the nodes selected by the Module regardless of their taints.
+	TolerateAllTaints bool `json:"tolerateAllTaints,omitempty"`
+
+	// +optional
+	// Tolerations are the pod's tolerations.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// +optional
+	// UpdateStrategy is the strategy used to replace the module loader pods when the DaemonSets are updated.
+	// Defaults to a rolling update with at most one unavailable pod.
+	// More info: https://kubernetes.io/docs/tasks/manage-daemon/update-daemon-set/
+	UpdateStrategy appsv1.DaemonSetUpdateStrategy `json:"updateStrategy,omitempty"`
 }
 
 type DevicePluginContainerSpec struct {
@@ -235,19 +666,146 @@ type DevicePluginContainerSpec struct {
 	// +optional
 	Resources v1.ResourceRequirements `json:"resources,omitempty" protobuf:"bytes,8,opt,name=resources"`
 
+	// SecurityContext defines the security options the container should be run with.
+	// If not set, the container runs privileged.
+	// If set without Privileged nor Capabilities, the container runs privileged with the other options applied.
+	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/security-context/
+	// +optional
+	SecurityContext *v1.SecurityContext `json:"securityContext,omitempty"`
+
 	// VolumeMounts is a list of volume mounts that are appended to the default ones.
 	// +optional
 	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
 }
 
+// HostPathMount describes a directory of the host mounted in a container.
+type HostPathMount struct {
+	// HostPath is the absolute path of the directory on the host.
+	HostPath string `json:"hostPath"`
+
+	// MountPath is the absolute path at which HostPath is mounted in the container.
+	MountPath string `json:"mountPath"`
+
+	// +optional
+	// ReadOnly, if true, mounts HostPath read-only.
+	ReadOnly bool `json:"readOnly,omitempty"`
+}
+
+// ProjectedServiceAccountToken describes a ServiceAccount token projected in a container.
+type ProjectedServiceAccountToken struct {
+	// +optional
+	// Audience is the intended audience of the token.
+	// Defaults to the identifier of the API server.
+	Audience string `json:"audience,omitempty"`
+
+	// +optional
+	// ExpirationSeconds is the requested duration of validity of the token.
+	// Defaults to 1 hour.
+	// +kubebuilder:validation:Minimum=600
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+
+	// MountPath is the absolute path of the directory in which the token is written, in a file named token.
+	MountPath string `json:"mountPath"`
+}
+
+// DevicePluginPodDisruptionBudget describes the PodDisruptionBudget protecting the device plugin pods.
+type DevicePluginPodDisruptionBudget struct {
+	// MaxUnavailable is the maximum number or percentage of device plugin pods that can be unavailable after an
+	// eviction.
+	MaxUnavailable intstr.IntOrString `json:"maxUnavailable"`
+}
+
 type DevicePluginSpec struct {
+	// +optional
+	// Affinity is the pod's scheduling constraints, for instance to only run the device plugin on nodes on which the
+	// hardware was detected. The pods are still only scheduled on nodes on which the module is loaded.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/assign-pod-node/#affinity-and-anti-affinity
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	// AutomountServiceAccountToken indicates whether the ServiceAccount token should be mounted automatically in
+	// the pod.
+	// Defaults to the ServiceAccount's setting.
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
+
 	Container DevicePluginContainerSpec `json:"container"`
 
+	// +optional
+	// HostPathMounts is a list of host directories mounted in the device plugin container, in addition to Volumes.
+	// KMM creates the corresponding volumes and volume mounts.
+	HostPathMounts []HostPathMount `json:"hostPathMounts,omitempty"`
+
+	// +optional
+	// DaemonSetLabels are additional labels set on the DaemonSets, for instance for cost allocation.
+	// They cannot override the labels managed by KMM.
+	DaemonSetLabels map[string]string `json:"daemonSetLabels,omitempty"`
+
+	// +optional
+	// MountModuleFirmware, if true, mounts read-only in the device plugin container the host directory to which the
+	// module loader copies the firmware(s) of the Module, at the same path.
+	// The module loader must set Modprobe.FirmwarePath.
+	MountModuleFirmware bool `json:"mountModuleFirmware,omitempty"`
+
+	// +optional
+	// Namespace is the namespace in which the device plugin DaemonSet and PodDisruptionBudget are created.
+	// Owner references cannot cross namespaces: if it differs from the Module's namespace, the objects are annotated
+	// with kmm.node.kubernetes.io/owner instead, and KMM deletes them when they are no longer needed.
+	// The pods are created in that namespace: ServiceAccountName, ImageRepoSecret and ImageRepoSecrets must name
+	// objects that exist there.
+	// Defaults to the Module's namespace.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Namespace string `json:"namespace,omitempty"`
+
+	// +optional
+	// NodeSelector contains node labels that nodes must have to run the device plugin, in addition to the Module's
+	// selector and the label indicating that the module is loaded, for instance
+	// feature.node.kubernetes.io/pci-10de.present: "true".
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// +optional
+	// PodAnnotations are additional annotations set on the pods.
+	// Annotations in the KMM domain are reserved and ignored.
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// +optional
+	// PodDisruptionBudget, if set, makes KMM create a PodDisruptionBudget limiting the number of device plugin pods
+	// that can be evicted at the same time, for instance during node drains.
+	PodDisruptionBudget *DevicePluginPodDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+
+	// +optional
+	// PriorityClassName is the name of the PriorityClass of the pod.
+	// Defaults to system-node-critical.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/pod-priority-preemption/
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// +optional
+	// ProjectedServiceAccountToken, if set, projects a token of the pod's ServiceAccount in the device plugin
+	// container.
+	ProjectedServiceAccountToken *ProjectedServiceAccountToken `json:"projectedServiceAccountToken,omitempty"`
+
 	// +optional
 	// ServiceAccountName is the name of the ServiceAccount to use to run this pod.
 	// More info: https://kubernetes.io/docs/tasks/configure-pod-container/configure-service-account/
 	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 
+	// +optional
+	// Tolerations are the pod's tolerations.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/taint-and-toleration/
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// +optional
+	// TopologySpreadConstraints describes how the device plugin pods should be spread across topology domains.
+	// Their label selectors must match the labels of the device plugin pods.
+	// More info: https://kubernetes.io/docs/concepts/scheduling-eviction/topology-spread-constraints/
+	TopologySpreadConstraints []v1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// +optional
+	// UpdateStrategy is the strategy used to replace the device plugin pods when the DaemonSet is updated.
+	// Defaults to a rolling update with at most one unavailable pod.
+	// More info: https://kubernetes.io/docs/tasks/manage-daemon/update-daemon-set/
+	UpdateStrategy appsv1.DaemonSetUpdateStrategy `json:"updateStrategy,omitempty"`
+
 	Volumes []v1.Volume `json:"volumes,omitempty"`
 }
 
@@ -267,6 +825,11 @@ type ModuleSpec struct {
 	// +optional
 	ImageRepoSecret *v1.LocalObjectReference `json:"imageRepoSecret,omitempty"`
 
+	// ImageRepoSecrets is an optional list of additional secrets that are used to pull both the module loader and
+	// the device plugin.
+	// +optional
+	ImageRepoSecrets []v1.LocalObjectReference `json:"imageRepoSecrets,omitempty"`
+
 	// Selector describes on which nodes the Module should be loaded and optionally built.
 	Selector map[string]string `json:"selector"`
 }
@@ -280,6 +843,9 @@ type DaemonSetStatus struct {
 	DesiredNumber int32 `json:"desiredNumber"`
 	// number of the actually deployed and running pods
 	AvailableNumber int32 `json:"availableNumber"`
+	// number of the nodes targeted by the module selector on which the kernel module is loaded
+	// +optional
+	LoadedNumber int32 `json:"loadedNumber,omitempty"`
 }
 
 // ModuleStatus defines the observed state of Module.
@@ -125,6 +125,11 @@ func (in *DevicePluginContainerSpec) DeepCopyInto(out *DevicePluginContainerSpec
 		}
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.VolumeMounts != nil {
 		in, out := &in.VolumeMounts, &out.VolumeMounts
 		*out = make([]v1.VolumeMount, len(*in))
@@ -144,10 +149,87 @@ func (in *DevicePluginContainerSpec) DeepCopy() *DevicePluginContainerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DevicePluginPodDisruptionBudget) DeepCopyInto(out *DevicePluginPodDisruptionBudget) {
+	*out = *in
+	out.MaxUnavailable = in.MaxUnavailable
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DevicePluginPodDisruptionBudget.
+func (in *DevicePluginPodDisruptionBudget) DeepCopy() *DevicePluginPodDisruptionBudget {
+	if in == nil {
+		return nil
+	}
+	out := new(DevicePluginPodDisruptionBudget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevicePluginSpec) DeepCopyInto(out *DevicePluginSpec) {
 	*out = *in
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AutomountServiceAccountToken != nil {
+		in, out := &in.AutomountServiceAccountToken, &out.AutomountServiceAccountToken
+		*out = new(bool)
+		**out = **in
+	}
 	in.Container.DeepCopyInto(&out.Container)
+	if in.HostPathMounts != nil {
+		in, out := &in.HostPathMounts, &out.HostPathMounts
+		*out = make([]HostPathMount, len(*in))
+		copy(*out, *in)
+	}
+	if in.DaemonSetLabels != nil {
+		in, out := &in.DaemonSetLabels, &out.DaemonSetLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(DevicePluginPodDisruptionBudget)
+		**out = **in
+	}
+	if in.ProjectedServiceAccountToken != nil {
+		in, out := &in.ProjectedServiceAccountToken, &out.ProjectedServiceAccountToken
+		*out = new(ProjectedServiceAccountToken)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.UpdateStrategy.DeepCopyInto(&out.UpdateStrategy)
 	if in.Volumes != nil {
 		in, out := &in.Volumes, &out.Volumes
 		*out = make([]v1.Volume, len(*in))
@@ -167,6 +249,21 @@ func (in *DevicePluginSpec) DeepCopy() *DevicePluginSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostPathMount) DeepCopyInto(out *HostPathMount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostPathMount.
+func (in *HostPathMount) DeepCopy() *HostPathMount {
+	if in == nil {
+		return nil
+	}
+	out := new(HostPathMount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *KanikoParams) DeepCopyInto(out *KanikoParams) {
 	*out = *in
@@ -207,6 +304,21 @@ func (in *KernelMapping) DeepCopy() *KernelMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LivenessProbeSpec) DeepCopyInto(out *LivenessProbeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LivenessProbeSpec.
+func (in *LivenessProbeSpec) DeepCopy() *LivenessProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LivenessProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModprobeArgs) DeepCopyInto(out *ModprobeArgs) {
 	*out = *in
@@ -235,11 +347,21 @@ func (in *ModprobeArgs) DeepCopy() *ModprobeArgs {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModprobeSpec) DeepCopyInto(out *ModprobeSpec) {
 	*out = *in
+	if in.ModulesLoadingOrder != nil {
+		in, out := &in.ModulesLoadingOrder, &out.ModulesLoadingOrder
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Parameters != nil {
 		in, out := &in.Parameters, &out.Parameters
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UnloadParameters != nil {
+		in, out := &in.UnloadParameters, &out.UnloadParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Args != nil {
 		in, out := &in.Args, &out.Args
 		*out = new(ModprobeArgs)
@@ -250,6 +372,36 @@ func (in *ModprobeSpec) DeepCopyInto(out *ModprobeSpec) {
 		*out = new(ModprobeArgs)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.LoadTimeoutSeconds != nil {
+		in, out := &in.LoadTimeoutSeconds, &out.LoadTimeoutSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.PreLoadCommand != nil {
+		in, out := &in.PreLoadCommand, &out.PreLoadCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostUnloadCommand != nil {
+		in, out := &in.PostUnloadCommand, &out.PostUnloadCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PostLoadVerify != nil {
+		in, out := &in.PostLoadVerify, &out.PostLoadVerify
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreUnloadCommand != nil {
+		in, out := &in.PreUnloadCommand, &out.PreUnloadCommand
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.UnloadGracePeriodSeconds != nil {
+		in, out := &in.UnloadGracePeriodSeconds, &out.UnloadGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModprobeSpec.
@@ -324,11 +476,26 @@ func (in *ModuleList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleLoaderContainerSpec) DeepCopyInto(out *ModuleLoaderContainerSpec) {
 	*out = *in
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Build != nil {
 		in, out := &in.Build, &out.Build
 		*out = new(Build)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostDevPaths != nil {
+		in, out := &in.HostDevPaths, &out.HostDevPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.KernelMappings != nil {
 		in, out := &in.KernelMappings, &out.KernelMappings
 		*out = make([]KernelMapping, len(*in))
@@ -336,12 +503,33 @@ func (in *ModuleLoaderContainerSpec) DeepCopyInto(out *ModuleLoaderContainerSpec
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.LivenessProbe != nil {
+		in, out := &in.LivenessProbe, &out.LivenessProbe
+		*out = new(LivenessProbeSpec)
+		**out = **in
+	}
 	in.Modprobe.DeepCopyInto(&out.Modprobe)
 	if in.Pull != nil {
 		in, out := &in.Pull, &out.Pull
 		*out = new(PullOptions)
 		**out = **in
 	}
+	if in.ReadinessProbe != nil {
+		in, out := &in.ReadinessProbe, &out.ReadinessProbe
+		*out = new(ReadinessProbeSpec)
+		**out = **in
+	}
+	if in.StartupProbe != nil {
+		in, out := &in.StartupProbe, &out.StartupProbe
+		*out = new(StartupProbeSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SELinuxType != nil {
+		in, out := &in.SELinuxType, &out.SELinuxType
+		*out = new(string)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleLoaderContainerSpec.
@@ -354,10 +542,104 @@ func (in *ModuleLoaderContainerSpec) DeepCopy() *ModuleLoaderContainerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ModuleLoaderSecurityContext) DeepCopyInto(out *ModuleLoaderSecurityContext) {
+	*out = *in
+	if in.RunAsUser != nil {
+		in, out := &in.RunAsUser, &out.RunAsUser
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RunAsGroup != nil {
+		in, out := &in.RunAsGroup, &out.RunAsGroup
+		*out = new(int64)
+		**out = **in
+	}
+	if in.FSGroup != nil {
+		in, out := &in.FSGroup, &out.FSGroup
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleLoaderSecurityContext.
+func (in *ModuleLoaderSecurityContext) DeepCopy() *ModuleLoaderSecurityContext {
+	if in == nil {
+		return nil
+	}
+	out := new(ModuleLoaderSecurityContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ModuleLoaderSpec) DeepCopyInto(out *ModuleLoaderSpec) {
 	*out = *in
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Container.DeepCopyInto(&out.Container)
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExtraModulesHostPaths != nil {
+		in, out := &in.ExtraModulesHostPaths, &out.ExtraModulesHostPaths
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ModprobeConfigMap != nil {
+		in, out := &in.ModprobeConfigMap, &out.ModprobeConfigMap
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.DaemonSetLabels != nil {
+		in, out := &in.DaemonSetLabels, &out.DaemonSetLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodAnnotations != nil {
+		in, out := &in.PodAnnotations, &out.PodAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(ModuleLoaderSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sysctls != nil {
+		in, out := &in.Sysctls, &out.Sysctls
+		*out = make([]v1.Sysctl, len(*in))
+		copy(*out, *in)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.UpdateStrategy.DeepCopyInto(&out.UpdateStrategy)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ModuleLoaderSpec.
@@ -384,6 +666,11 @@ func (in *ModuleSpec) DeepCopyInto(out *ModuleSpec) {
 		*out = new(v1.LocalObjectReference)
 		**out = **in
 	}
+	if in.ImageRepoSecrets != nil {
+		in, out := &in.ImageRepoSecrets, &out.ImageRepoSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.Selector != nil {
 		in, out := &in.Selector, &out.Selector
 		*out = make(map[string]string, len(*in))
@@ -524,6 +811,26 @@ func (in *PreflightValidationStatus) DeepCopy() *PreflightValidationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectedServiceAccountToken) DeepCopyInto(out *ProjectedServiceAccountToken) {
+	*out = *in
+	if in.ExpirationSeconds != nil {
+		in, out := &in.ExpirationSeconds, &out.ExpirationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectedServiceAccountToken.
+func (in *ProjectedServiceAccountToken) DeepCopy() *ProjectedServiceAccountToken {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectedServiceAccountToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullOptions) DeepCopyInto(out *PullOptions) {
 	*out = *in
@@ -553,3 +860,38 @@ func (in *PushOptions) DeepCopy() *PushOptions {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReadinessProbeSpec) DeepCopyInto(out *ReadinessProbeSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReadinessProbeSpec.
+func (in *ReadinessProbeSpec) DeepCopy() *ReadinessProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ReadinessProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StartupProbeSpec) DeepCopyInto(out *StartupProbeSpec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StartupProbeSpec.
+func (in *StartupProbeSpec) DeepCopy() *StartupProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StartupProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -18,7 +18,9 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/auth"
@@ -32,6 +34,7 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -87,6 +90,7 @@ func NewModuleReconciler(
 //+kubebuilder:rbac:groups="core",resources=nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups="core",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="batch",resources=jobs,verbs=create;list;watch
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=create;delete;get;list;patch;watch
 
 // Reconcile lists all nodes and looks for kernels that match its mappings.
 // For each mapping that matches at least one node in the cluster, it creates a DaemonSet running the container image
@@ -100,6 +104,17 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			logger.Info("Module deleted")
+
+			// device plugin objects in other namespaces cannot be owned by the Module
+			deleted, err := r.daemonAPI.DeleteCrossNamespaceDevicePlugins(ctx, req.Name, req.Namespace, "")
+			if err != nil {
+				return res, fmt.Errorf("could not delete cross-namespace device plugins: %v", err)
+			}
+
+			if len(deleted) > 0 {
+				logger.Info("Deleted cross-namespace device plugins", "names", deleted)
+			}
+
 			return ctrl.Result{}, nil
 		}
 
@@ -118,25 +133,43 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return res, fmt.Errorf("could get kernel mappings and nodes for modules %s: %w", mod.Name, err)
 	}
 
-	dsByKernelVersion, err := r.daemonAPI.ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace)
+	dsByKernelVersion, duplicateDS, err := r.daemonAPI.ModuleDaemonSetsByKernelVersion(ctx, mod.Name, mod.Namespace)
 	if err != nil {
 		return res, fmt.Errorf("could get DaemonSets for module %s: %v", mod.Name, err)
 	}
 
-	for kernelVersion, m := range mappings {
-		requeue, err := r.handleBuild(ctx, mod, m, kernelVersion)
+	for _, ds := range duplicateDS {
+		logger.Info("Deleting duplicate DaemonSet", "name", ds.Name)
+
+		if err = r.Client.Delete(ctx, ds); err != nil && !apierrors.IsNotFound(err) {
+			kernelVersion := r.daemonAPI.KernelVersion(ds)
+
+			dupErr := &daemonset.DuplicateDaemonSetError{KernelVersion: kernelVersion}
+
+			if kept := dsByKernelVersion[daemonset.DaemonSetKey(kernelVersion, daemonset.Arch(ds))]; kept != nil {
+				dupErr.Names = append(dupErr.Names, kept.Name)
+			}
+
+			dupErr.Names = append(dupErr.Names, ds.Name)
+
+			return res, fmt.Errorf("%w: could not delete %s: %v", dupErr, ds.Name, err)
+		}
+	}
+
+	for ka, m := range mappings {
+		requeue, err := r.handleBuild(ctx, mod, m, ka.kernelVersion)
 		if err != nil {
-			return res, fmt.Errorf("failed to handle build for kernel version %s: %w", kernelVersion, err)
+			return res, fmt.Errorf("failed to handle build for kernel version %s: %w", ka.kernelVersion, err)
 		}
 		if requeue {
-			logger.Info("Build requires a requeue; skipping handling driver container for now", "kernelVersion", kernelVersion, "image", m)
+			logger.Info("Build requires a requeue; skipping handling driver container for now", "kernelVersion", ka.kernelVersion, "image", m)
 			res.Requeue = true
 			continue
 		}
 
-		err = r.handleDriverContainer(ctx, mod, m, dsByKernelVersion, kernelVersion)
+		err = r.handleDriverContainer(ctx, mod, m, dsByKernelVersion, ka.kernelVersion, daemonSetArch(dsByKernelVersion, ka))
 		if err != nil {
-			return res, fmt.Errorf("failed to handle driver container for kernel version %s: %v", kernelVersion, err)
+			return res, fmt.Errorf("failed to handle driver container for kernel version %s: %w", ka.kernelVersion, err)
 		}
 	}
 
@@ -148,12 +181,43 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 
 	logger.Info("Garbage-collecting DaemonSets")
 
-	// Garbage collect old DaemonSets for which there are no nodes.
-	validKernels := sets.StringKeySet(mappings)
+	// Garbage collect old DaemonSets for which there are no nodes, and the device plugin DaemonSet if it is not
+	// wanted anymore.
+	validKernels := sets.NewString()
+
+	for ka := range mappings {
+		validKernels.Insert(daemonset.DaemonSetKey(ka.kernelVersion, daemonSetArch(dsByKernelVersion, ka)))
+	}
+
+	devicePluginNamespace := daemonset.DevicePluginNamespace(mod)
+	devicePluginEnabled := mod.Spec.DevicePlugin != nil && devicePluginNamespace == mod.Namespace
+
+	gcResult, err := r.daemonAPI.GarbageCollect(ctx, dsByKernelVersion, validKernels, devicePluginEnabled)
+	if err != nil {
+		if gcResult != nil {
+			logger.Info("Garbage-collected some DaemonSets", "names", gcResult.Deleted)
+		}
+
+		// the kernel versions of the nodes may not be known yet; keep the DaemonSets rather than failing
+		if gcResult == nil || len(gcResult.Failed) > 0 || !errors.Is(err, daemonset.ErrGarbageCollectionSafeguard) {
+			return res, fmt.Errorf("could not garbage collect DaemonSets: %v", err)
+		}
 
-	deleted, err := r.daemonAPI.GarbageCollect(ctx, dsByKernelVersion, validKernels)
+		logger.Info("Too few valid kernel versions; keeping stale DaemonSets", "valid kernel versions", validKernels.List())
+	}
+
+	keepNamespace := ""
+	if mod.Spec.DevicePlugin != nil && devicePluginNamespace != mod.Namespace {
+		keepNamespace = devicePluginNamespace
+	}
+
+	deleted, err := r.daemonAPI.DeleteCrossNamespaceDevicePlugins(ctx, mod.Name, mod.Namespace, keepNamespace)
 	if err != nil {
-		return res, fmt.Errorf("could not garbage collect DaemonSets: %v", err)
+		return res, fmt.Errorf("could not delete cross-namespace device plugins: %v", err)
+	}
+
+	if len(deleted) > 0 {
+		logger.Info("Deleted cross-namespace device plugins", "names", deleted)
 	}
 
 	err = r.statusUpdaterAPI.ModuleUpdateStatus(ctx, mod, nodesWithMapping, targetedNodes, dsByKernelVersion)
@@ -161,16 +225,42 @@ func (r *ModuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return res, fmt.Errorf("failed to update status of the module: %w", err)
 	}
 
-	logger.Info("Garbage-collected DaemonSets", "names", deleted)
+	logger.Info("Garbage-collected DaemonSets", "names", gcResult.Deleted)
+
+	if !gcResult.RetainedUntil.IsZero() {
+		logger.Info("Some DaemonSets are retained; requeueing", "until", gcResult.RetainedUntil)
+		res.RequeueAfter = time.Until(gcResult.RetainedUntil)
+	}
 
 	return res, nil
 }
 
+// kernelArch identifies the nodes served by one module loader DaemonSet.
+type kernelArch struct {
+	kernelVersion string
+	arch          string
+}
+
+// daemonSetArch returns the architecture of the module loader DaemonSet serving the nodes of ka.
+// DaemonSets created before the module loaders were pinned to an architecture are indexed by their kernel version only.
+// They keep serving all architectures instead of being replaced, which would unload the module on all their nodes.
+func daemonSetArch(dsByKernelVersion map[string]*appsv1.DaemonSet, ka kernelArch) string {
+	if _, ok := dsByKernelVersion[daemonset.DaemonSetKey(ka.kernelVersion, ka.arch)]; ok {
+		return ka.arch
+	}
+
+	if _, ok := dsByKernelVersion[ka.kernelVersion]; ok {
+		return ""
+	}
+
+	return ka.arch
+}
+
 func (r *ModuleReconciler) getRelevantKernelMappingsAndNodes(ctx context.Context,
 	mod *kmmv1beta1.Module,
-	targetedNodes []v1.Node) (map[string]*kmmv1beta1.KernelMapping, []v1.Node, error) {
+	targetedNodes []v1.Node) (map[kernelArch]*kmmv1beta1.KernelMapping, []v1.Node, error) {
 
-	mappings := make(map[string]*kmmv1beta1.KernelMapping)
+	mappings := make(map[kernelArch]*kmmv1beta1.KernelMapping)
 	logger := log.FromContext(ctx)
 
 	nodes := make([]v1.Node, 0, len(targetedNodes))
@@ -179,12 +269,16 @@ func (r *ModuleReconciler) getRelevantKernelMappingsAndNodes(ctx context.Context
 		osConfig := r.kernelAPI.GetNodeOSConfig(&node)
 		kernelVersion := node.Status.NodeInfo.KernelVersion
 
+		// the image may be templated with the architecture: each one gets its own mapping
+		ka := kernelArch{kernelVersion: kernelVersion, arch: node.Status.NodeInfo.Architecture}
+
 		nodeLogger := logger.WithValues(
 			"node", node.Name,
 			"kernel version", kernelVersion,
+			"architecture", ka.arch,
 		)
 
-		if image, ok := mappings[kernelVersion]; ok {
+		if image, ok := mappings[ka]; ok {
 			nodes = append(nodes, node)
 			nodeLogger.V(1).Info("Using cached image", "image", image)
 			continue
@@ -208,7 +302,7 @@ func (r *ModuleReconciler) getRelevantKernelMappingsAndNodes(ctx context.Context
 			"build", m.Build != nil,
 		)
 
-		mappings[kernelVersion] = m
+		mappings[ka] = m
 		nodes = append(nodes, node)
 	}
 	return mappings, nodes, nil
@@ -274,22 +368,27 @@ func (r *ModuleReconciler) handleDriverContainer(ctx context.Context,
 	mod *kmmv1beta1.Module,
 	km *kmmv1beta1.KernelMapping,
 	dsByKernelVersion map[string]*appsv1.DaemonSet,
-	kernelVersion string) error {
+	kernelVersion string,
+	arch string) error {
 	ds := &appsv1.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{Namespace: mod.Namespace},
 	}
 
 	logger := log.FromContext(ctx)
-	if existingDS := dsByKernelVersion[kernelVersion]; existingDS != nil {
-		logger.Info("updating existing driver container DS", "kernel version", kernelVersion, "image", km, "name", ds.Name)
+	if existingDS := dsByKernelVersion[daemonset.DaemonSetKey(kernelVersion, arch)]; existingDS != nil {
+		logger.Info("updating existing driver container DS", "kernel version", kernelVersion, "architecture", arch, "image", km, "name", ds.Name)
 		ds = existingDS
 	} else {
-		logger.Info("creating new driver container DS", "kernel version", kernelVersion, "image", km)
-		ds.GenerateName = mod.Name + "-"
+		logger.Info("creating new driver container DS", "kernel version", kernelVersion, "architecture", arch, "image", km)
+		ds.Name = daemonset.DaemonSetName(mod.Name, kernelVersion, arch)
 	}
 
 	opRes, err := controllerutil.CreateOrPatch(ctx, r.Client, ds, func() error {
-		return r.daemonAPI.SetDriverContainerAsDesired(ctx, ds, km.ContainerImage, *mod, kernelVersion)
+		if err := r.daemonAPI.SetDriverContainerAsDesired(ctx, ds, km.ContainerImage, *mod, kernelVersion, arch); err != nil {
+			return err
+		}
+
+		return r.daemonAPI.ValidateDaemonSet(ctx, ds)
 	})
 
 	if err == nil {
@@ -304,32 +403,76 @@ func (r *ModuleReconciler) handleDriverContainer(ctx context.Context,
 
 func (r *ModuleReconciler) handleDevicePlugin(ctx context.Context, mod *kmmv1beta1.Module) error {
 	if mod.Spec.DevicePlugin == nil {
-		return nil
+		return r.deleteDevicePluginPodDisruptionBudget(ctx, mod)
 	}
 
 	logger := log.FromContext(ctx)
+	namespace := daemonset.DevicePluginNamespace(mod)
 	ds := &appsv1.DaemonSet{
-		ObjectMeta: metav1.ObjectMeta{Namespace: mod.Namespace},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
 	}
-	name := mod.Name + "-device-plugin"
+	name := daemonset.DevicePluginName(mod)
 	ds.Name = name
-	err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: mod.Namespace}, ds)
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, ds)
 	if err != nil && !apierrors.IsNotFound(err) {
-		return fmt.Errorf("failed to get the device plugin daemonset %s/%s: %w", name, mod.Namespace, err)
+		return fmt.Errorf("failed to get the device plugin daemonset %s/%s: %w", name, namespace, err)
 	}
 
 	opRes, err := controllerutil.CreateOrPatch(ctx, r.Client, ds, func() error {
 		return r.daemonAPI.SetDevicePluginAsDesired(ctx, ds, mod)
 	})
 
-	if err == nil {
-		if opRes == controllerutil.OperationResultCreated {
-			r.metricsAPI.SetCompletedStage(mod.Name, mod.Namespace, "", metrics.DevicePluginStage, false)
+	if err != nil {
+		return err
+	}
+
+	if opRes == controllerutil.OperationResultCreated {
+		r.metricsAPI.SetCompletedStage(mod.Name, mod.Namespace, "", metrics.DevicePluginStage, false)
+	}
+	logger.Info("Reconciled Device Plugin", "name", ds.Name, "result", opRes)
+
+	if mod.Spec.DevicePlugin.PodDisruptionBudget == nil {
+		return r.deleteDevicePluginPodDisruptionBudget(ctx, mod)
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+
+	opRes, err = controllerutil.CreateOrPatch(ctx, r.Client, pdb, func() error {
+		return r.daemonAPI.SetDevicePluginPodDisruptionBudgetAsDesired(ctx, pdb, mod)
+	})
+	if err != nil {
+		return fmt.Errorf("could not reconcile the device plugin PodDisruptionBudget: %v", err)
+	}
+
+	logger.Info("Reconciled Device Plugin PodDisruptionBudget", "name", pdb.Name, "result", opRes)
+
+	return nil
+}
+
+// deleteDevicePluginPodDisruptionBudget deletes the device plugin PodDisruptionBudget of mod, if any, so that it does not
+// keep blocking evictions once it is not configured anymore.
+// PodDisruptionBudgets in another namespace than mod's are deleted by DeleteCrossNamespaceDevicePlugins.
+func (r *ModuleReconciler) deleteDevicePluginPodDisruptionBudget(ctx context.Context, mod *kmmv1beta1.Module) error {
+	pdb := &policyv1.PodDisruptionBudget{}
+	nsn := types.NamespacedName{Name: daemonset.DevicePluginName(mod), Namespace: daemonset.DevicePluginNamespace(mod)}
+
+	if err := r.Client.Get(ctx, nsn, pdb); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
 		}
-		logger.Info("Reconciled Device Plugin", "name", ds.Name, "result", opRes)
+
+		return fmt.Errorf("could not get the device plugin PodDisruptionBudget %s: %v", nsn, err)
 	}
 
-	return err
+	if err := r.Client.Delete(ctx, pdb); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete the device plugin PodDisruptionBudget %s: %v", nsn, err)
+	}
+
+	log.FromContext(ctx).Info("Deleted the device plugin PodDisruptionBudget", "name", nsn)
+
+	return nil
 }
 
 func (r *ModuleReconciler) setKMMOMetrics(ctx context.Context) {
@@ -359,6 +502,13 @@ func (r *ModuleReconciler) SetupWithManager(mgr ctrl.Manager, kernelLabel string
 		For(&kmmv1beta1.Module{}).
 		Owns(&appsv1.DaemonSet{}).
 		Owns(&batchv1.Job{}).
+		Watches(
+			&source.Kind{Type: &appsv1.DaemonSet{}},
+			handler.EnqueueRequestsFromMapFunc(r.filter.FindModuleForCrossNamespaceDevicePlugin),
+			builder.WithPredicates(
+				filter.CrossNamespaceDevicePluginPredicate(),
+			),
+		).
 		Watches(
 			&source.Kind{Type: &v1.Node{}},
 			handler.EnqueueRequestsFromMapFunc(r.filter.FindModulesForNode),
@@ -2,6 +2,8 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
@@ -17,10 +19,13 @@ import (
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -70,6 +75,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 			Return(
 				apierrors.NewNotFound(schema.GroupResource{}, moduleName),
 			)
+		mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil)
 
 		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
 		Expect(
@@ -118,8 +124,216 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
 
 		gomock.InOrder(
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil),
-			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString()),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, dsByKernelVersion).Return(nil),
+		)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+	})
+
+	It("should requeue when the garbage collection retained DaemonSets", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+		gcResult := daemonset.GCResult{RetainedUntil: time.Now().Add(time.Hour)}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(), false).Return(&gcResult, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, dsByKernelVersion).Return(nil),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+	})
+
+	It("should not fail when the garbage collection safeguard kept DaemonSets", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+		gcResult := daemonset.GCResult{Deleted: []string{}, Failed: map[string]error{}}
+		gcErr := utilerrors.NewAggregate([]error{daemonset.ErrGarbageCollectionSafeguard})
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(), false).Return(&gcResult, gcErr),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, dsByKernelVersion).Return(nil),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+	})
+
+	It("should return an error when the garbage collection failed to delete DaemonSets", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+		gcResult := daemonset.GCResult{Deleted: []string{}, Failed: map[string]error{"ds": errors.New("some error")}}
+		gcErr := utilerrors.NewAggregate([]error{errors.New("some error"), daemonset.ErrGarbageCollectionSafeguard})
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(), false).Return(&gcResult, gcErr),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		_, err := mr.Reconcile(context.Background(), req)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should delete duplicate DaemonSets", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+
+		duplicateDS := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "duplicate", Namespace: namespace},
+		}
+
+		gomock.InOrder(
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(
+				dsByKernelVersion,
+				[]*appsv1.DaemonSet{duplicateDS},
+				nil,
+			),
+			clnt.EXPECT().Delete(ctx, duplicateDS),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
 			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, dsByKernelVersion).Return(nil),
 		)
 
@@ -128,8 +342,459 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(res).To(Equal(reconcile.Result{}))
 	})
 
-	It("should remove obsolete DaemonSets when no nodes match the selector", func() {
-		const kernelVersion = "1.2.3"
+	It("should return a DuplicateDaemonSetError if a duplicate DaemonSet cannot be deleted", func() {
+		const kernelVersion = "1.2.3"
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		keptDS := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "kept", Namespace: namespace},
+		}
+
+		duplicateDS := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "duplicate", Namespace: namespace},
+		}
+
+		gomock.InOrder(
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(
+				map[string]*appsv1.DaemonSet{kernelVersion: keptDS},
+				[]*appsv1.DaemonSet{duplicateDS},
+				nil,
+			),
+			clnt.EXPECT().Delete(ctx, duplicateDS).Return(errors.New("some error")),
+			mockDC.EXPECT().KernelVersion(duplicateDS).Return(kernelVersion),
+		)
+
+		_, err := mr.Reconcile(context.Background(), req)
+		Expect(err).To(HaveOccurred())
+
+		var dupErr *daemonset.DuplicateDaemonSetError
+
+		Expect(errors.As(err, &dupErr)).To(BeTrue())
+		Expect(dupErr.KernelVersion).To(Equal(kernelVersion))
+		Expect(dupErr.Names).To(Equal([]string{"kept", "duplicate"}))
+	})
+
+	It("should remove obsolete DaemonSets when no nodes match the selector", func() {
+		const kernelVersion = "1.2.3"
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "some-daemonset",
+				Namespace: namespace,
+			},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		dsByKernelVersion := map[string]*appsv1.DaemonSet{kernelVersion: &ds}
+
+		gomock.InOrder(
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, dsByKernelVersion).Return(nil),
+		)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+	})
+
+	It("should create a DaemonSet when a node matches the selector", func() {
+		const (
+			imageName     = "test-image"
+			kernelVersion = "1.2.3"
+		)
+
+		mappings := []kmmv1beta1.KernelMapping{
+			{
+				ContainerImage: imageName,
+				Literal:        kernelVersion,
+			},
+		}
+
+		osConfig := module.NodeOSConfig{}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						KernelMappings: mappings,
+					},
+				},
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		nodeList := v1.NodeList{
+			Items: []v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node1",
+						Labels: map[string]string{"key": "value"},
+					},
+					Status: v1.NodeStatus{
+						NodeInfo: v1.NodeSystemInfo{KernelVersion: kernelVersion},
+					},
+				},
+			},
+		}
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      daemonset.DaemonSetName(moduleName, kernelVersion, ""),
+				Namespace: namespace,
+			},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(0),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = nodeList.Items
+					return nil
+				},
+			),
+			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[0]).Return(&osConfig),
+			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
+			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, ""),
+			mockDC.EXPECT().ValidateDaemonSet(ctx, gomock.Any()),
+			clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
+			mockMetrics.EXPECT().SetCompletedStage(moduleName, namespace, kernelVersion, metrics.ModuleLoaderStage, false),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, nodeList.Items, nodeList.Items, dsByKernelVersion).Return(nil),
+		)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+	})
+
+	It("should not create a DaemonSet rejected by the dry-run validation", func() {
+		const (
+			imageName     = "test-image"
+			kernelVersion = "1.2.3"
+		)
+
+		mappings := []kmmv1beta1.KernelMapping{
+			{
+				ContainerImage: imageName,
+				Literal:        kernelVersion,
+			},
+		}
+
+		osConfig := module.NodeOSConfig{}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						KernelMappings: mappings,
+					},
+				},
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		nodeList := v1.NodeList{
+			Items: []v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node1",
+						Labels: map[string]string{"key": "value"},
+					},
+					Status: v1.NodeStatus{
+						NodeInfo: v1.NodeSystemInfo{KernelVersion: kernelVersion},
+					},
+				},
+			},
+		}
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      daemonset.DaemonSetName(moduleName, kernelVersion, ""),
+				Namespace: namespace,
+			},
+		}
+
+		validationErr := &daemonset.ValidationError{Name: moduleName + "-", Err: errors.New("violates PodSecurity")}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(0),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = nodeList.Items
+					return nil
+				},
+			),
+			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[0]).Return(&osConfig),
+			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
+			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, ""),
+			mockDC.EXPECT().ValidateDaemonSet(ctx, gomock.Any()).Return(validationErr),
+		)
+
+		_, err := mr.Reconcile(context.Background(), req)
+		Expect(err).To(HaveOccurred())
+
+		var target *daemonset.ValidationError
+		Expect(errors.As(err, &target)).To(BeTrue())
+	})
+
+	It("should create one DaemonSet per architecture of the nodes running the kernel", func() {
+		const (
+			imageName     = "test-image"
+			kernelVersion = "1.2.3"
+		)
+
+		mappings := []kmmv1beta1.KernelMapping{
+			{
+				ContainerImage: imageName,
+				Literal:        kernelVersion,
+			},
+		}
+
+		osConfig := module.NodeOSConfig{}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						KernelMappings: mappings,
+					},
+				},
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		nodeList := v1.NodeList{
+			Items: []v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node1",
+						Labels: map[string]string{"key": "value"},
+					},
+					Status: v1.NodeStatus{
+						NodeInfo: v1.NodeSystemInfo{Architecture: "arm64", KernelVersion: kernelVersion},
+					},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node2",
+						Labels: map[string]string{"key": "value"},
+					},
+					Status: v1.NodeStatus{
+						NodeInfo: v1.NodeSystemInfo{Architecture: "amd64", KernelVersion: kernelVersion},
+					},
+				},
+			},
+		}
+
+		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		dsARM64 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      daemonset.DaemonSetName(moduleName, kernelVersion, "arm64"),
+				Namespace: namespace,
+			},
+		}
+
+		dsAMD64 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      daemonset.DaemonSetName(moduleName, kernelVersion, "amd64"),
+				Namespace: namespace,
+			},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(0),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = nodeList.Items
+					return nil
+				},
+			),
+			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[0]).Return(&osConfig),
+			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
+			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
+			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[1]).Return(&osConfig),
+			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
+			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+		)
+
+		// the DaemonSets are reconciled in map order
+		clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")).Times(2)
+		mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &dsARM64, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, "arm64")
+		mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &dsAMD64, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, "amd64")
+		mockDC.EXPECT().ValidateDaemonSet(ctx, gomock.Any()).Times(2)
+		clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil).Times(2)
+		mockMetrics.EXPECT().SetCompletedStage(moduleName, namespace, kernelVersion, metrics.ModuleLoaderStage, false).Times(2)
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.
+				EXPECT().
+				GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion+"/arm64", kernelVersion+"/amd64"), false).
+				Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, nodeList.Items, nodeList.Items, dsByKernelVersion).Return(nil),
+		)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+	})
+
+	It("should patch the DaemonSet when it already exists", func() {
+		const (
+			imageName     = "test-image"
+			kernelVersion = "1.2.3"
+		)
+
+		osConfig := module.NodeOSConfig{}
+
+		mappings := []kmmv1beta1.KernelMapping{
+			{
+				ContainerImage: imageName,
+				Literal:        kernelVersion,
+			},
+		}
+
+		nodeLabels := map[string]string{"key": "value"}
 
 		mod := kmmv1beta1.Module{
 			ObjectMeta: metav1.ObjectMeta{
@@ -137,14 +802,38 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 				Namespace: namespace,
 			},
 			Spec: kmmv1beta1.ModuleSpec{
-				Selector: map[string]string{"key": "value"},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						KernelMappings: mappings,
+					},
+				},
+				Selector: nodeLabels,
+			},
+		}
+
+		nodeList := v1.NodeList{
+			Items: []v1.Node{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:   "node1",
+						Labels: nodeLabels,
+					},
+					Status: v1.NodeStatus{
+						NodeInfo: v1.NodeSystemInfo{KernelVersion: kernelVersion},
+					},
+				},
 			},
 		}
 
+		const (
+			dsName      = "some-daemonset"
+			dsNamespace = "test-namespace"
+		)
+
 		ds := appsv1.DaemonSet{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      "some-daemonset",
-				Namespace: namespace,
+				Name:      dsName,
+				Namespace: dsNamespace,
 			},
 		}
 
@@ -165,10 +854,12 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 			mockMetrics.EXPECT().SetExistingKMMOModules(1),
 			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
 				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
-					list.Items = []v1.Node{}
+					list.Items = nodeList.Items
 					return nil
 				},
 			),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()),
+			clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()),
 		)
 
 		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
@@ -176,9 +867,19 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		dsByKernelVersion := map[string]*appsv1.DaemonSet{kernelVersion: &ds}
 
 		gomock.InOrder(
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil),
-			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString()),
-			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, dsByKernelVersion).Return(nil),
+			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[0]).Return(&osConfig),
+			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
+			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, "").Do(
+				func(ctx context.Context, d *appsv1.DaemonSet, _ string, _ kmmv1beta1.Module, _, _ string) {
+					d.SetLabels(map[string]string{"test": "test"})
+				}),
+			mockDC.EXPECT().ValidateDaemonSet(ctx, gomock.Any()),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, nodeList.Items, nodeList.Items, dsByKernelVersion).Return(nil),
 		)
 
 		res, err := mr.Reconcile(context.Background(), req)
@@ -186,12 +887,14 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(res).To(Equal(reconcile.Result{}))
 	})
 
-	It("should create a DaemonSet when a node matches the selector", func() {
+	It("should keep using a DaemonSet created before the architecture was part of its key", func() {
 		const (
 			imageName     = "test-image"
 			kernelVersion = "1.2.3"
 		)
 
+		osConfig := module.NodeOSConfig{}
+
 		mappings := []kmmv1beta1.KernelMapping{
 			{
 				ContainerImage: imageName,
@@ -199,7 +902,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 			},
 		}
 
-		osConfig := module.NodeOSConfig{}
+		nodeLabels := map[string]string{"key": "value"}
 
 		mod := kmmv1beta1.Module{
 			ObjectMeta: metav1.ObjectMeta{
@@ -212,7 +915,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 						KernelMappings: mappings,
 					},
 				},
-				Selector: map[string]string{"key": "value"},
+				Selector: nodeLabels,
 			},
 		}
 
@@ -221,23 +924,20 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 				{
 					ObjectMeta: metav1.ObjectMeta{
 						Name:   "node1",
-						Labels: map[string]string{"key": "value"},
+						Labels: nodeLabels,
 					},
 					Status: v1.NodeStatus{
-						NodeInfo: v1.NodeSystemInfo{KernelVersion: kernelVersion},
+						NodeInfo: v1.NodeSystemInfo{KernelVersion: kernelVersion, Architecture: "amd64"},
 					},
 				},
 			},
 		}
 
-		dsByKernelVersion := make(map[string]*appsv1.DaemonSet)
-
-		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
-
+		// no architecture label nor node selector
 		ds := appsv1.DaemonSet{
 			ObjectMeta: metav1.ObjectMeta{
-				GenerateName: moduleName + "-",
-				Namespace:    namespace,
+				Name:      moduleName + "-" + kernelVersion,
+				Namespace: namespace,
 			},
 		}
 
@@ -251,25 +951,39 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 			),
 			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
 				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					list.Items = []kmmv1beta1.Module{mod}
 					return nil
 				},
 			),
-			mockMetrics.EXPECT().SetExistingKMMOModules(0),
+			mockMetrics.EXPECT().SetExistingKMMOModules(1),
 			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
 				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
 					list.Items = nodeList.Items
 					return nil
 				},
 			),
+			// the existing DaemonSet is patched: neither Create nor Delete is expected
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()),
+			clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()),
+		)
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		dsByKernelVersion := map[string]*appsv1.DaemonSet{kernelVersion: &ds}
+
+		gomock.InOrder(
 			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[0]).Return(&osConfig),
 			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
 			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, "").Do(
+				func(ctx context.Context, d *appsv1.DaemonSet, _ string, _ kmmv1beta1.Module, _, _ string) {
+					d.SetLabels(map[string]string{"test": "test"})
+				}),
+			mockDC.EXPECT().ValidateDaemonSet(ctx, gomock.Any()),
 			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
-			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion),
-			clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
-			mockMetrics.EXPECT().SetCompletedStage(moduleName, namespace, kernelVersion, metrics.ModuleLoaderStage, false),
-			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion)),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
 			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, nodeList.Items, nodeList.Items, dsByKernelVersion).Return(nil),
 		)
 
@@ -278,7 +992,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(res).To(Equal(reconcile.Result{}))
 	})
 
-	It("should patch the DaemonSet when it already exists", func() {
+	It("should only patch the DaemonSet template when the OnDelete update strategy is used", func() {
 		const (
 			imageName     = "test-image"
 			kernelVersion = "1.2.3"
@@ -305,6 +1019,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 					Container: kmmv1beta1.ModuleLoaderContainerSpec{
 						KernelMappings: mappings,
 					},
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType},
 				},
 				Selector: nodeLabels,
 			},
@@ -358,6 +1073,7 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 				},
 			),
 			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()),
+			// no pod is deleted: the pods are only replaced once deleted by the user
 			clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()),
 		)
 
@@ -369,12 +1085,16 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 			mockKM.EXPECT().GetNodeOSConfig(&nodeList.Items[0]).Return(&osConfig),
 			mockKM.EXPECT().FindMappingForKernel(mappings, kernelVersion).Return(&mappings[0], nil),
 			mockKM.EXPECT().PrepareKernelMapping(&mappings[0], &osConfig).Return(&mappings[0], nil),
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil),
-			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion).Do(
-				func(ctx context.Context, d *appsv1.DaemonSet, _ string, _ kmmv1beta1.Module, _ string) {
-					d.SetLabels(map[string]string{"test": "test"})
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(dsByKernelVersion, nil, nil),
+			mockDC.EXPECT().SetDriverContainerAsDesired(context.Background(), &ds, imageName, gomock.AssignableToTypeOf(mod), kernelVersion, "").Do(
+				func(ctx context.Context, d *appsv1.DaemonSet, _ string, m kmmv1beta1.Module, _, _ string) {
+					d.Spec.Template.Spec.Containers = []v1.Container{{Image: "test-image-v2"}}
+					d.Spec.UpdateStrategy = m.Spec.ModuleLoader.UpdateStrategy
 				}),
-			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion)),
+			mockDC.EXPECT().ValidateDaemonSet(ctx, gomock.Any()),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, dsByKernelVersion, sets.NewString(kernelVersion), false).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
 			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, nodeList.Items, nodeList.Items, dsByKernelVersion).Return(nil),
 		)
 
@@ -441,13 +1161,103 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 					return nil
 				},
 			),
-			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(nil, nil),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(nil, nil, nil),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().SetDevicePluginAsDesired(context.Background(), &ds, gomock.AssignableToTypeOf(&mod)),
+			clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
+			mockMetrics.EXPECT().SetCompletedStage(moduleName, namespace, "", metrics.DevicePluginStage, false),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().GarbageCollect(ctx, nil, sets.NewString(), true).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, nil).Return(nil),
+		)
+
+		res, err := mr.Reconcile(context.Background(), req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res).To(Equal(reconcile.Result{}))
+	})
+
+	It("should create a PodDisruptionBudget for the Device plugin if configured", func() {
+		const (
+			imageName     = "test-image"
+			kernelVersion = "1.2.3"
+		)
+
+		mappings := []kmmv1beta1.KernelMapping{
+			{
+				ContainerImage: imageName,
+				Literal:        kernelVersion,
+			},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					PodDisruptionBudget: &kmmv1beta1.DevicePluginPodDisruptionBudget{
+						MaxUnavailable: intstr.FromInt(1),
+					},
+				},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						KernelMappings: mappings,
+					},
+				},
+				Selector: map[string]string{"key": "value"},
+			},
+		}
+
+		mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName + "-device-plugin",
+				Namespace: namespace,
+			},
+		}
+
+		pdb := policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName + "-device-plugin",
+				Namespace: namespace,
+			},
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+					m.ObjectMeta = mod.ObjectMeta
+					m.Spec = mod.Spec
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+					return nil
+				},
+			),
+			mockMetrics.EXPECT().SetExistingKMMOModules(0),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{}
+					return nil
+				},
+			),
+			mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(nil, nil, nil),
 			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
 			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
 			mockDC.EXPECT().SetDevicePluginAsDesired(context.Background(), &ds, gomock.AssignableToTypeOf(&mod)),
 			clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
 			mockMetrics.EXPECT().SetCompletedStage(moduleName, namespace, "", metrics.DevicePluginStage, false),
-			mockDC.EXPECT().GarbageCollect(ctx, nil, sets.NewString()),
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")),
+			mockDC.EXPECT().SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), &pdb, gomock.AssignableToTypeOf(&mod)),
+			clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
+			mockDC.EXPECT().GarbageCollect(ctx, nil, sets.NewString(), true).Return(&daemonset.GCResult{}, nil),
+			mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
 			mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, nil).Return(nil),
 		)
 
@@ -455,6 +1265,81 @@ var _ = Describe("ModuleReconciler_Reconcile", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(res).To(Equal(reconcile.Result{}))
 	})
+
+	DescribeTable("should delete the device plugin PodDisruptionBudget when it is not configured anymore",
+		func(devicePlugin *kmmv1beta1.DevicePluginSpec) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: devicePlugin,
+					Selector:     map[string]string{"key": "value"},
+				},
+			}
+
+			mr := NewModuleReconciler(clnt, mockBM, mockDC, mockKM, mockMetrics, nil, mockRegistry, mockSU)
+
+			dpName := types.NamespacedName{Name: moduleName + "-device-plugin", Namespace: namespace}
+
+			gomock.InOrder(
+				clnt.EXPECT().Get(ctx, req.NamespacedName, gomock.Any()).DoAndReturn(
+					func(_ interface{}, _ interface{}, m *kmmv1beta1.Module) error {
+						m.ObjectMeta = mod.ObjectMeta
+						m.Spec = mod.Spec
+						return nil
+					},
+				),
+				clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, list *kmmv1beta1.ModuleList, _ ...interface{}) error {
+						return nil
+					},
+				),
+				mockMetrics.EXPECT().SetExistingKMMOModules(0),
+				clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+						list.Items = []v1.Node{}
+						return nil
+					},
+				),
+				mockDC.EXPECT().ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace).Return(nil, nil, nil),
+			)
+
+			if devicePlugin != nil {
+				gomock.InOrder(
+					clnt.EXPECT().Get(ctx, dpName, gomock.AssignableToTypeOf(&appsv1.DaemonSet{})).
+						Return(apierrors.NewNotFound(schema.GroupResource{}, "whatever")).
+						Times(2),
+					mockDC.EXPECT().SetDevicePluginAsDesired(context.Background(), gomock.Any(), gomock.AssignableToTypeOf(&mod)),
+					clnt.EXPECT().Create(ctx, gomock.Any()).Return(nil),
+					mockMetrics.EXPECT().SetCompletedStage(moduleName, namespace, "", metrics.DevicePluginStage, false),
+				)
+			}
+
+			gomock.InOrder(
+				clnt.EXPECT().Get(ctx, dpName, gomock.AssignableToTypeOf(&policyv1.PodDisruptionBudget{})).DoAndReturn(
+					func(_ interface{}, nsn types.NamespacedName, pdb *policyv1.PodDisruptionBudget) error {
+						pdb.Name = nsn.Name
+						pdb.Namespace = nsn.Namespace
+						return nil
+					},
+				),
+				clnt.EXPECT().Delete(ctx, &policyv1.PodDisruptionBudget{
+					ObjectMeta: metav1.ObjectMeta{Name: dpName.Name, Namespace: dpName.Namespace},
+				}),
+				mockDC.EXPECT().GarbageCollect(ctx, nil, sets.NewString(), devicePlugin != nil).Return(&daemonset.GCResult{}, nil),
+				mockDC.EXPECT().DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "").Return(nil, nil),
+				mockSU.EXPECT().ModuleUpdateStatus(ctx, &mod, []v1.Node{}, []v1.Node{}, nil).Return(nil),
+			)
+
+			res, err := mr.Reconcile(context.Background(), req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res).To(Equal(reconcile.Result{}))
+		},
+		Entry("device plugin removed", nil),
+		Entry("PodDisruptionBudget removed", &kmmv1beta1.DevicePluginSpec{}),
+	)
 })
 
 var _ = Describe("ModuleReconciler_handleBuild", func() {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/filter"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -39,10 +40,14 @@ func (r *NodeKernelReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	kernelVersion := node.Status.NodeInfo.KernelVersion
 
+	// some kernel versions, such as debug kernels suffixed with +debug, are not valid label values
+	labelValue := daemonset.KernelLabelValue(kernelVersion)
+
 	logger.Info(
 		"Patching node label",
 		"old kernel", node.Labels[r.labelName],
-		"new kernel", kernelVersion)
+		"new kernel", kernelVersion,
+		"label value", labelValue)
 
 	p := client.MergeFrom(node.DeepCopy())
 
@@ -50,7 +55,7 @@ func (r *NodeKernelReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		node.Labels = make(map[string]string)
 	}
 
-	node.Labels[r.labelName] = kernelVersion
+	node.Labels[r.labelName] = labelValue
 
 	if err := r.client.Patch(ctx, &node, p); err != nil {
 		return ctrl.Result{}, fmt.Errorf("could not patch the node: %v", err)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/client"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
@@ -104,4 +105,40 @@ var _ = Describe("NodeKernelReconciler_Reconcile", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(res).To(Equal(res))
 	})
+
+	It("should set the sanitized kernel version if it is not a valid label value", func() {
+		const debugKernelVersion = "5.14.0-284.11.1.el9_2.x86_64+debug"
+
+		node := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: nodeName},
+			Status: v1.NodeStatus{
+				NodeInfo: v1.NodeSystemInfo{KernelVersion: debugKernelVersion},
+			},
+		}
+
+		ctx := context.Background()
+		gomock.InOrder(
+			clnt.EXPECT().Get(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, _ interface{}, n *v1.Node) error {
+					n.ObjectMeta = node.ObjectMeta
+					n.Status = node.Status
+					return nil
+				},
+			),
+			clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, n *v1.Node, _ interface{}, _ ...interface{}) error {
+					Expect(n.Labels).To(HaveKeyWithValue(labelName, daemonset.KernelLabelValue(debugKernelVersion)))
+					return nil
+				},
+			),
+		)
+
+		nkr := NewNodeKernelReconciler(clnt, labelName, nil)
+		req := runtimectrl.Request{
+			NamespacedName: types.NamespacedName{Name: nodeName},
+		}
+
+		_, err := nkr.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+	})
 })
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/filter"
 	v1 "k8s.io/api/core/v1"
@@ -46,9 +45,11 @@ func (pnmr *PodNodeModuleReconciler) Reconcile(ctx context.Context, req ctrl.Req
 
 	nodeName := pod.Spec.NodeName
 
-	moduleName, ok := pod.Labels[constants.ModuleNameLabel]
+	moduleNameLabel := pnmr.daemonAPI.GetModuleNameLabel()
+
+	moduleName, ok := pod.Labels[moduleNameLabel]
 	if !ok {
-		return ctrl.Result{}, fmt.Errorf("pod %s has no %q label", podNamespacedName, constants.ModuleNameLabel)
+		return ctrl.Result{}, fmt.Errorf("pod %s has no %q label", podNamespacedName, moduleNameLabel)
 	}
 
 	labelName := pnmr.daemonAPI.GetNodeLabelFromPod(&pod, moduleName)
@@ -95,7 +96,7 @@ func (pnmr *PodNodeModuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 			),
 			filter.DeletingPredicate(),
 		),
-		filter.HasLabel(constants.ModuleNameLabel),
+		filter.HasLabel(pnmr.daemonAPI.GetModuleNameLabel()),
 		filter.PodHasSpecNodeName(),
 	)
 
@@ -128,7 +129,7 @@ func (pnmr *PodNodeModuleReconciler) addLabel(ctx context.Context, nodeName, lab
 func (pnmr *PodNodeModuleReconciler) deleteFinalizer(ctx context.Context, pod *v1.Pod) error {
 	podCopy := pod.DeepCopy()
 
-	controllerutil.RemoveFinalizer(pod, constants.NodeLabelerFinalizer)
+	controllerutil.RemoveFinalizer(pod, pnmr.daemonAPI.GetNodeLabelerFinalizer())
 
 	return pnmr.client.Patch(ctx, pod, client.MergeFrom(podCopy))
 }
@@ -49,6 +49,7 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 		It("should return an error if the pod is not labeled", func() {
 			gomock.InOrder(
 				kubeClient.EXPECT().Get(ctx, nn, gomock.AssignableToTypeOf(&v1.Pod{})),
+				mockDC.EXPECT().GetModuleNameLabel().Return(constants.ModuleNameLabel),
 			)
 
 			_, err := r.Reconcile(ctx, req)
@@ -74,6 +75,7 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 						o.SetLabels(map[string]string{constants.ModuleNameLabel: moduleName})
 						o.(*v1.Pod).Spec.NodeName = nodeName
 					}),
+				mockDC.EXPECT().GetModuleNameLabel().Return(constants.ModuleNameLabel),
 				mockDC.EXPECT().GetNodeLabelFromPod(&podWithModuleName, moduleName).Return(nodeLabel),
 				kubeClient.
 					EXPECT().
@@ -131,6 +133,7 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 							},
 						}
 					}),
+				mockDC.EXPECT().GetModuleNameLabel().Return(constants.ModuleNameLabel),
 				mockDC.EXPECT().GetNodeLabelFromPod(&readyPod, moduleName).Return(nodeLabel),
 				kubeClient.EXPECT().Get(ctx, types.NamespacedName{Name: nodeName}, &node),
 				kubeClient.
@@ -186,6 +189,7 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 						o.SetDeletionTimestamp(&now)
 						o.SetFinalizers([]string{constants.NodeLabelerFinalizer})
 					}),
+				mockDC.EXPECT().GetModuleNameLabel().Return(constants.ModuleNameLabel),
 				mockDC.EXPECT().GetNodeLabelFromPod(&deletedPod, moduleName).Return(nodeLabel),
 				kubeClient.
 					EXPECT().
@@ -204,6 +208,7 @@ var _ = Describe("PodNodeModuleReconciler", func() {
 							),
 						)
 					}),
+				mockDC.EXPECT().GetNodeLabelerFinalizer().Return(constants.NodeLabelerFinalizer),
 				kubeClient.
 					EXPECT().
 					Patch(ctx, &podWithoutFinalizer, gomock.Any()).
@@ -5,4 +5,27 @@ const (
 	NodeLabelerFinalizer = "kmm.node.kubernetes.io/node-labeler"
 	TargetKernelTarget   = "kmm.node.kubernetes.io/target-kernel"
 	DaemonSetRole        = "kmm.node.kubernetes.io/role"
+	ModuleVersionLabel   = "kmm.node.kubernetes.io/version"
+	// ArchLabel is set on the module loader DaemonSets and pods to the architecture they are pinned to, if any.
+	ArchLabel = "kmm.node.kubernetes.io/arch"
+
+	// ModuleLoaderRole and DevicePluginRole are the values of the DaemonSetRole label.
+	ModuleLoaderRole = "module-loader"
+	DevicePluginRole = "device-plugin"
+
+	KernelVersionAnnotation = "kmm.node.kubernetes.io/kernel-version"
+	LastSeenValidAnnotation = "kmm.node.kubernetes.io/last-seen-valid"
+	// PinAnnotation, when set to "true" on a DaemonSet, prevents it from being garbage-collected.
+	PinAnnotation = "kmm.node.kubernetes.io/pin"
+	// OwnerAnnotation is set to <namespace>/<name> of the Module on the device plugin objects created in another
+	// namespace than the Module, which cannot have an owner reference to it.
+	OwnerAnnotation = "kmm.node.kubernetes.io/owner"
+	// ModprobeConfigHashAnnotation is set on the module loader pods to a hash of their modprobe configuration, so
+	// that changing it rolls the pods.
+	ModprobeConfigHashAnnotation = "kmm.node.kubernetes.io/modprobe-config-hash"
+	// StaleFirmwarePathAnnotation is set on the module loader DaemonSets to the host firmware directory that their
+	// pods remove because the Module does not copy firmware to it anymore, until the rollout completes.
+	StaleFirmwarePathAnnotation = "kmm.node.kubernetes.io/stale-firmware-path"
+
+	KernelFullVersionEnv = "KERNEL_FULL_VERSION"
 )
@@ -2,97 +2,514 @@ package daemonset
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
 	kubeletDevicePluginsVolumeName = "kubelet-device-plugins"
-	kubeletDevicePluginsPath       = "/var/lib/kubelet/device-plugins"
 	nodeLibModulesPath             = "/lib/modules"
 	nodeLibModulesVolumeName       = "node-lib-modules"
 	nodeUsrLibModulesPath          = "/usr/lib/modules"
 	nodeUsrLibModulesVolumeName    = "node-usr-lib-modules"
 	nodeVarLibFirmwarePath         = "/var/lib/firmware"
 	nodeVarLibFirmwareVolumeName   = "node-var-lib-firmware"
+	tmpPath                        = "/tmp"
+	tmpVolumeName                  = "tmp"
 	devicePluginKernelVersion      = ""
+	defaultPriorityClassName       = "system-node-critical"
+	extraModulesVolumeNamePrefix   = "extra-modules"
+	firmwareCleanupContainerName   = "firmware-cleanup"
+	firmwareExtractContainerName   = "firmware-extract"
+	modprobeConfigPath             = "/etc/modprobe.d"
+	modprobeConfigVolumeName       = "modprobe-config"
+	hostDevPath                    = "/dev"
+	hostDevVolumeName              = "host-dev"
+	hostPathMountVolumeNamePrefix  = "host-path"
+	moduleLoaderContainerName      = "module-loader"
+	serviceAccountTokenVolumeName  = "service-account-token"
+	staleFirmwareVolumeName        = "stale-firmware"
+	serviceAccountTokenPath        = "token"
+	defaultSELinuxType             = "spc_t"
+	defaultProbePeriodSeconds      = 10
+	defaultProbeTimeoutSeconds     = 1
+	defaultProbeFailureThreshold   = 3
+	kernelLabelValueHashLength     = 8
+	defaultShellPath               = "/bin/sh"
+	defaultModprobePath            = "modprobe"
+	defaultDirName                 = "/opt"
+	defaultReadyPollInterval       = 2 * time.Second
+	dryRunFieldOwner               = "kmm"
+	modulePodsPageSize             = 500
+
+	// defaultTerminationGracePeriodSeconds is the Kubernetes default for the pods' termination grace period.
+	defaultTerminationGracePeriodSeconds int64 = 30
+
+	// lastSeenValidRefreshDivisor bounds how often the last-seen-valid annotation is refreshed: once per retention
+	// window divided by this value.
+	lastSeenValidRefreshDivisor = 10
+
+	DefaultGarbageCollectionConcurrency = 10
+	DefaultKubeletDevicePluginsPath     = "/var/lib/kubelet/device-plugins"
+	DefaultNodeLabelPrefix              = "kmm.node.kubernetes.io"
+)
+
+var (
+	// moduleNameRegexp matches valid kernel module names.
+	moduleNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+	// shellSafeRegexp matches strings that can be passed to the shell without quoting.
+	shellSafeRegexp = regexp.MustCompile(`^[a-zA-Z0-9_./=:,+@%-]+$`)
+
+	// sysctlNameRegexp matches valid sysctl names.
+	sysctlNameRegexp = regexp.MustCompile(`^([a-z0-9]([-_a-z0-9]*[a-z0-9])?\.)*[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`)
+
+	// safeSysctls are the sysctls that Kubernetes allows by default.
+	safeSysctls = sets.NewString(
+		"kernel.shm_rmid_forced",
+		"net.ipv4.ip_local_port_range",
+		"net.ipv4.ip_unprivileged_port_start",
+		"net.ipv4.ping_group_range",
+		"net.ipv4.tcp_syncookies",
+	)
 )
 
 //go:generate mockgen -source=daemonset.go -package=daemonset -destination=mock_daemonset.go
 
+// ErrNoMatchingNodes is returned by CheckSelectorMatchesNodes if a Module's selector does not match any node.
+var ErrNoMatchingNodes = errors.New("the module selector does not match any node")
+
+// ErrGarbageCollectionSafeguard is returned by GarbageCollect if it kept stale module loader DaemonSets because there
+// were fewer valid kernel versions than the minimum set with WithGarbageCollectionMinValidKernels.
+var ErrGarbageCollectionSafeguard = errors.New("too few valid kernel versions to garbage-collect module loader DaemonSets")
+
+// DuplicateDaemonSetError indicates that several module loader DaemonSets target the same kernel version.
+type DuplicateDaemonSetError struct {
+	KernelVersion string
+	Names         []string
+}
+
+func (e *DuplicateDaemonSetError) Error() string {
+	return fmt.Sprintf("multiple DaemonSets found for kernel %q: %s", e.KernelVersion, strings.Join(e.Names, ", "))
+}
+
+// RawArgsConflictError indicates that a ModprobeSpec sets raw load or unload arguments together with structured
+// fields that would be ignored.
+type RawArgsConflictError struct {
+	// Phase is either "load" or "unload".
+	Phase  string
+	Fields []string
+}
+
+func (e *RawArgsConflictError) Error() string {
+	return fmt.Sprintf("rawArgs.%s cannot be used with %s", e.Phase, strings.Join(e.Fields, ", "))
+}
+
+// ValidationError indicates that the API server, or one of its admission controllers, rejected a DaemonSet during
+// a dry run.
+type ValidationError struct {
+	Name string
+	Err  error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("DaemonSet %s was rejected by the API server: %v", e.Name, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
 type DaemonSetCreator interface {
-	GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKernels sets.String) ([]string, error)
-	ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, error)
-	SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, image string, mod kmmv1beta1.Module, kernelVersion string) error
+	AllModuleDaemonSets(ctx context.Context) (map[types.NamespacedName][]appsv1.DaemonSet, error)
+	CheckSelectorMatchesNodes(ctx context.Context, mod *kmmv1beta1.Module, kernelVersions sets.String) error
+	DeleteCrossNamespaceDevicePlugins(ctx context.Context, name, namespace, keepNamespace string) ([]string, error)
+	GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) (*GCResult, error)
+	GarbageCollectPlan(existingDS map[string]*appsv1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) []string
+	ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, []*appsv1.DaemonSet, error)
+	ModulePods(ctx context.Context, name, namespace string) ([]v1.Pod, error)
+	SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, image string, mod kmmv1beta1.Module, kernelVersion, arch string) error
 	SetDevicePluginAsDesired(ctx context.Context, ds *appsv1.DaemonSet, mod *kmmv1beta1.Module) error
+	SetDevicePluginPodDisruptionBudgetAsDesired(ctx context.Context, pdb *policyv1.PodDisruptionBudget, mod *kmmv1beta1.Module) error
 	GetNodeLabelFromPod(pod *v1.Pod, moduleName string) string
+	GetModuleNameLabel() string
+	GetNodeLabelerFinalizer() string
+	KernelLabel() string
+	KernelVersion(ds *appsv1.DaemonSet) string
+	NodeModuleStatus(ctx context.Context, mod *kmmv1beta1.Module) (int, error)
+	RemoveModuleNodeLabels(ctx context.Context, moduleName string) error
+	ValidateDaemonSet(ctx context.Context, ds *appsv1.DaemonSet) error
+	WaitForModuleDaemonSetsReady(ctx context.Context, name, namespace string, timeout time.Duration) error
+}
+
+// GCResult holds the outcome of a garbage collection.
+type GCResult struct {
+	// Deleted contains the names of the DaemonSets that were deleted.
+	Deleted []string
+
+	// Failed maps the names of the DaemonSets that could not be deleted to the corresponding error.
+	Failed map[string]error
+
+	// RetainedUntil is the earliest time at which a DaemonSet kept because of the retention window can be deleted.
+	// It is zero if no DaemonSet was retained.
+	RetainedUntil time.Time
+}
+
+// DSStatus is the number of pods of a module loader DaemonSet in each state, as reported in its status.
+type DSStatus struct {
+	// Desired is the number of nodes that should run a pod.
+	Desired int32
+
+	// Ready is the number of nodes that run a ready pod.
+	Ready int32
+
+	// Available is the number of nodes that run a pod that has been ready for at least the DaemonSet's
+	// minReadySeconds.
+	Available int32
 }
 
 type daemonSetGenerator struct {
-	client      client.Client
-	kernelLabel string
-	scheme      *runtime.Scheme
+	client                    client.Client
+	devicePluginsVolumeSource *v1.VolumeSource
+	dryRunValidation          bool
+	gcConcurrency             int
+	gcMinValidKernels         int
+	gcRetention               time.Duration
+	imageDigestRequired       bool
+	kernelLabel               string
+	kubeletDevicePluginsPath  string
+	moduleNameLabel           string
+	nodeKernelLabel           string
+	nodeLabelPrefix           string
+	nodeLabelerFinalizer      string
+	now                       func() time.Time
+	readyPollInterval         time.Duration
+	scheme                    *runtime.Scheme
 }
 
-func NewCreator(client client.Client, kernelLabel string, scheme *runtime.Scheme) DaemonSetCreator {
-	return &daemonSetGenerator{
-		client:      client,
-		kernelLabel: kernelLabel,
-		scheme:      scheme,
+// Option configures optional parameters of the DaemonSetCreator returned by NewCreator.
+type Option func(*daemonSetGenerator)
+
+// WithNodeKernelLabel sets the key of the node label holding the kernel version, used to schedule the module loader
+// pods, for clusters on which it differs from the kernel label of the DaemonSets, for instance
+// feature.node.kubernetes.io/kernel-version.full when it is published by Node Feature Discovery.
+// Defaults to the kernel label passed to NewCreator.
+func WithNodeKernelLabel(key string) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.nodeKernelLabel = key
 	}
 }
 
-func (dc *daemonSetGenerator) GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKernels sets.String) ([]string, error) {
-	deleted := make([]string, 0)
+// WithNodeLabelPrefix sets the domain used to build the node labels that indicate that a module or its device plugin
+// is ready. Defaults to DefaultNodeLabelPrefix.
+func WithNodeLabelPrefix(prefix string) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.nodeLabelPrefix = prefix
+	}
+}
 
-	for kernelVersion, ds := range existingDS {
-		if !dc.isDevicePluginDaemonSet(ds) && !validKernels.Has(kernelVersion) {
-			if err := dc.client.Delete(ctx, ds); err != nil {
-				return nil, fmt.Errorf("could not delete DaemonSet %s: %v", ds.Name, err)
-			}
+// WithNodeLabelerFinalizer sets the finalizer added to module loader and device plugin pods, so that the node labels
+// are removed before the pods are deleted. Defaults to <node label prefix>/node-labeler, which is
+// constants.NodeLabelerFinalizer with the default node label prefix.
+func WithNodeLabelerFinalizer(finalizer string) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.nodeLabelerFinalizer = finalizer
+	}
+}
+
+// WithGarbageCollectionConcurrency sets the maximum number of DaemonSets that GarbageCollect deletes in parallel, so
+// that Modules targeting many kernel versions are cleaned up quickly without flooding the API server.
+// Defaults to DefaultGarbageCollectionConcurrency; 1 deletes the DaemonSets one after the other.
+func WithGarbageCollectionConcurrency(n int) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.gcConcurrency = n
+	}
+}
+
+// WithGarbageCollectionMinValidKernels makes the garbage collection keep all module loader DaemonSets when there are
+// fewer than n valid kernel versions, for instance because the kernel versions of the nodes are not known yet after
+// a restart, instead of deleting all of them. GarbageCollect then returns ErrGarbageCollectionSafeguard.
+// Defaults to 0, which disables the safeguard.
+func WithGarbageCollectionMinValidKernels(n int) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.gcMinValidKernels = n
+	}
+}
+
+// WithGarbageCollectionRetention makes the garbage collection keep module loader DaemonSets for at least d after their
+// kernel version stopped being valid, so that a kernel rollback does not require recreating them.
+// The time at which a kernel version was last valid is stored in the constants.LastSeenValidAnnotation annotation of
+// the DaemonSet. It is only refreshed once per tenth of d, so that the DaemonSets are not patched on every
+// reconciliation; they may therefore be kept up to a tenth of d longer. Defaults to 0, which deletes the DaemonSets
+// immediately.
+func WithGarbageCollectionRetention(d time.Duration) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.gcRetention = d
+	}
+}
+
+// WithDryRunValidation makes ValidateDaemonSet submit the DaemonSets to the API server in dry-run mode, so that
+// admission failures such as Pod Security or quota violations are reported before the DaemonSets are persisted.
+// Defaults to false, in which case ValidateDaemonSet does nothing.
+func WithDryRunValidation(enabled bool) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.dryRunValidation = enabled
+	}
+}
+
+// WithImageDigestRequired makes SetDriverContainerAsDesired reject module loader images that are not referenced by
+// digest, so that a mutable tag cannot change the code loaded in the kernel. Defaults to false.
+func WithImageDigestRequired(required bool) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.imageDigestRequired = required
+	}
+}
+
+// WithKubeletDevicePluginsPath sets the directory in which the kubelet expects device plugins to create their
+// socket, for distributions that do not use the default, such as k3s.
+// Defaults to DefaultKubeletDevicePluginsPath.
+func WithKubeletDevicePluginsPath(path string) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.kubeletDevicePluginsPath = path
+	}
+}
+
+// WithDevicePluginsVolumeSource replaces the hostPath volume that exposes the kubelet device plugins directory to the
+// device plugin pods with src, for instance an emptyDir in test clusters that cannot mount host paths.
+// The volume is still mounted at the kubelet device plugins path. Defaults to a hostPath volume.
+func WithDevicePluginsVolumeSource(src v1.VolumeSource) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.devicePluginsVolumeSource = &src
+	}
+}
+
+// WithModuleNameLabel sets the key of the label holding the name of the Module on the DaemonSets and their pods, so
+// that several KMM deployments can coexist in the same cluster. Defaults to constants.ModuleNameLabel.
+func WithModuleNameLabel(key string) Option {
+	return func(dc *daemonSetGenerator) {
+		dc.moduleNameLabel = key
+	}
+}
+
+func NewCreator(client client.Client, kernelLabel string, scheme *runtime.Scheme, opts ...Option) DaemonSetCreator {
+	dc := &daemonSetGenerator{
+		client:                   client,
+		kernelLabel:              kernelLabel,
+		kubeletDevicePluginsPath: DefaultKubeletDevicePluginsPath,
+		moduleNameLabel:          constants.ModuleNameLabel,
+		nodeLabelPrefix:          DefaultNodeLabelPrefix,
+		now:                      time.Now,
+		readyPollInterval:        defaultReadyPollInterval,
+		scheme:                   scheme,
+	}
 
-			deleted = append(deleted, ds.Name)
+	for _, opt := range opts {
+		opt(dc)
+	}
+
+	if dc.nodeLabelerFinalizer == "" {
+		dc.nodeLabelerFinalizer = dc.nodeLabelPrefix + "/node-labeler"
+	}
+
+	if dc.nodeKernelLabel == "" {
+		dc.nodeKernelLabel = dc.kernelLabel
+	}
+
+	if dc.gcConcurrency < 1 {
+		dc.gcConcurrency = DefaultGarbageCollectionConcurrency
+	}
+
+	return dc
+}
+
+// CheckSelectorMatchesNodes returns ErrNoMatchingNodes if no node matches the node selector of mod's module loader
+// DaemonSets, that is mod's selector and one of kernelVersions. If kernelVersions is empty, any kernel version matches.
+func (dc *daemonSetGenerator) CheckSelectorMatchesNodes(ctx context.Context, mod *kmmv1beta1.Module, kernelVersions sets.String) error {
+	nodes := v1.NodeList{}
+
+	if err := dc.client.List(ctx, &nodes, client.MatchingLabels(mod.Spec.Selector)); err != nil {
+		return fmt.Errorf("could not list nodes: %v", err)
+	}
+
+	// nodes are labeled with the sanitized kernel version
+	labelValues := sets.NewString()
+
+	for kv := range kernelVersions {
+		labelValues.Insert(KernelLabelValue(kv))
+	}
+
+	for _, node := range nodes.Items {
+		if labelValues.Len() == 0 || labelValues.Has(node.Labels[dc.nodeKernelLabel]) {
+			return nil
+		}
+	}
+
+	return ErrNoMatchingNodes
+}
+
+func (dc *daemonSetGenerator) GarbageCollect(ctx context.Context, existingDS map[string]*appsv1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) (*GCResult, error) {
+	res := GCResult{
+		Deleted: make([]string, 0),
+		Failed:  make(map[string]error),
+	}
+
+	errs := make([]error, 0)
+
+	toDelete, retainedUntil, safeguarded := dc.daemonSetsToDelete(existingDS, validKernels, devicePluginEnabled)
+
+	res.RetainedUntil = retainedUntil
+
+	if safeguarded {
+		errs = append(errs, ErrGarbageCollectionSafeguard)
+	}
+
+	logger := log.FromContext(ctx)
+
+	deleteErrs := dc.deleteDaemonSets(ctx, toDelete)
+
+	for i, ds := range toDelete {
+		kernelVersion := dc.KernelVersion(ds)
+
+		reason := "kernel version no longer targeted"
+		if dc.isDevicePluginDaemonSet(ds) {
+			reason = "device plugin disabled"
+		}
+
+		if err := deleteErrs[i]; err != nil {
+			res.Failed[ds.Name] = err
+			errs = append(errs, fmt.Errorf("could not delete DaemonSet %s: %v", ds.Name, err))
+			continue
 		}
+
+		logger.Info("Garbage-collected DaemonSet", "name", ds.Name, "kernel version", kernelVersion, "reason", reason)
+
+		res.Deleted = append(res.Deleted, ds.Name)
+		garbageCollectedDaemonSets.WithLabelValues(ds.Labels[dc.moduleNameLabel], ds.Namespace).Inc()
+	}
+
+	return &res, utilerrors.NewAggregate(errs)
+}
+
+// deleteDaemonSets deletes daemonSets with at most dc.gcConcurrency requests in flight, and returns the error of each
+// deletion at the index of the corresponding DaemonSet.
+func (dc *daemonSetGenerator) deleteDaemonSets(ctx context.Context, daemonSets []*appsv1.DaemonSet) []error {
+	errs := make([]error, len(daemonSets))
+	sem := make(chan struct{}, dc.gcConcurrency)
+
+	var wg sync.WaitGroup
+
+	for i, ds := range daemonSets {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, ds *appsv1.DaemonSet) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			errs[i] = dc.client.Delete(ctx, ds)
+		}(i, ds)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// GarbageCollectPlan returns the sorted names of the DaemonSets that GarbageCollect would delete, without deleting
+// them.
+func (dc *daemonSetGenerator) GarbageCollectPlan(existingDS map[string]*appsv1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) []string {
+	names := make([]string, 0)
+
+	toDelete, _, _ := dc.daemonSetsToDelete(existingDS, validKernels, devicePluginEnabled)
+
+	for _, ds := range toDelete {
+		names = append(names, ds.Name)
 	}
 
-	return deleted, nil
+	sort.Strings(names)
+
+	return names
 }
 
-func (dc *daemonSetGenerator) ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, error) {
+// ModuleDaemonSetsByKernelVersion returns the DaemonSets of a Module indexed by DaemonSetKey of their kernel version and
+// architecture. The device plugin DaemonSet is indexed by the device plugin kernel version.
+// If several DaemonSets exist for the same key, the newest one is kept in the map and the others are returned in a
+// separate slice so that the caller can delete them.
+func (dc *daemonSetGenerator) ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*appsv1.DaemonSet, []*appsv1.DaemonSet, error) {
 	dsList, err := dc.moduleDaemonSets(ctx, name, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("could not get all DaemonSets: %w", err)
+		return nil, nil, fmt.Errorf("could not get all DaemonSets: %w", err)
 	}
 
 	dsByKernelVersion := make(map[string]*appsv1.DaemonSet, len(dsList))
+	duplicates := make([]*appsv1.DaemonSet, 0)
 
 	for i := 0; i < len(dsList); i++ {
-		ds := dsList[i]
+		ds := &dsList[i]
+
+		key := dc.KernelVersion(ds)
+
+		if !dc.isDevicePluginDaemonSet(ds) {
+			key = DaemonSetKey(key, Arch(ds))
+		}
+
+		if existing := dsByKernelVersion[key]; existing != nil {
+			if isNewer(existing, ds) {
+				duplicates = append(duplicates, ds)
+				continue
+			}
 
-		kernelVersion := ds.Labels[dc.kernelLabel]
-		if dsByKernelVersion[kernelVersion] != nil {
-			return nil, fmt.Errorf("multiple DaemonSets found for kernel %q", kernelVersion)
+			duplicates = append(duplicates, existing)
 		}
 
-		dsByKernelVersion[kernelVersion] = &ds
+		dsByKernelVersion[key] = ds
 	}
 
-	return dsByKernelVersion, nil
+	kernelVersions := len(dsByKernelVersion)
+	if _, ok := dsByKernelVersion[devicePluginKernelVersion]; ok {
+		kernelVersions--
+	}
+
+	moduleDaemonSets.WithLabelValues(name, namespace).Set(float64(len(dsList)))
+	moduleKernelVersions.WithLabelValues(name, namespace).Set(float64(kernelVersions))
+
+	return dsByKernelVersion, duplicates, nil
 }
 
-func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, image string, mod kmmv1beta1.Module, kernelVersion string) error {
+func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, ds *appsv1.DaemonSet, image string, mod kmmv1beta1.Module, kernelVersion, arch string) error {
 	if ds == nil {
 		return errors.New("ds cannot be nil")
 	}
@@ -101,40 +518,182 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, d
 		return errors.New("image cannot be empty")
 	}
 
+	if dc.imageDigestRequired {
+		if _, err := name.NewDigest(image); err != nil {
+			return fmt.Errorf("image %q is not referenced by digest: %v", image, err)
+		}
+	}
+
 	if kernelVersion == "" {
 		return errors.New("kernelVersion cannot be empty")
 	}
 
+	if err := ValidateModprobeSpec(mod.Spec.ModuleLoader.Container.Modprobe); err != nil {
+		return fmt.Errorf("invalid modprobe spec: %v", err)
+	}
+
+	for _, p := range mod.Spec.ModuleLoader.ExtraModulesHostPaths {
+		if !path.IsAbs(p) {
+			return fmt.Errorf("extra modules host path %q is not absolute", p)
+		}
+	}
+
+	if err := validateHostDevPaths(mod.Spec.ModuleLoader.Container); err != nil {
+		return fmt.Errorf("invalid host /dev paths: %v", err)
+	}
+
+	if err := validateProbes(mod.Spec.ModuleLoader.Container); err != nil {
+		return fmt.Errorf("invalid probes: %v", err)
+	}
+
+	for _, dep := range mod.Spec.ModuleLoader.DependsOn {
+		if dep == mod.Name {
+			return fmt.Errorf("module %s cannot depend on itself", mod.Name)
+		}
+	}
+
+	if version := mod.Spec.ModuleLoader.Container.Version; version != "" {
+		if errs := validation.IsValidLabelValue(version); len(errs) > 0 {
+			return fmt.Errorf("invalid module loader version %q: %s", version, strings.Join(errs, "; "))
+		}
+	}
+
+	resources := mod.Spec.ModuleLoader.Container.Resources
+
+	if err := validateResources(resources); err != nil {
+		return fmt.Errorf("invalid module loader resources: %v", err)
+	}
+
+	if err := validateUpdateStrategy(mod.Spec.ModuleLoader.UpdateStrategy); err != nil {
+		return fmt.Errorf("invalid module loader update strategy: %v", err)
+	}
+
+	if err := validateSysctls(mod.Spec.ModuleLoader); err != nil {
+		return fmt.Errorf("invalid module loader sysctls: %v", err)
+	}
+
+	if mod.Spec.ModuleLoader.DNSPolicy == v1.DNSNone && mod.Spec.ModuleLoader.DNSConfig == nil {
+		return errors.New("dnsConfig must be set if dnsPolicy is None")
+	}
+
+	if err := validateModuleLoaderSecurityContext(mod.Spec.ModuleLoader.SecurityContext); err != nil {
+		return fmt.Errorf("invalid module loader security context: %v", err)
+	}
+
+	containerName := ModuleLoaderContainerName(&mod)
+
+	if errs := validation.IsDNS1123Label(containerName); len(errs) > 0 {
+		return fmt.Errorf("invalid module loader container name %q: %s", containerName, strings.Join(errs, "; "))
+	}
+
+	for _, c := range mod.Spec.ModuleLoader.InitContainers {
+		if c.Name == containerName {
+			return fmt.Errorf("init container %q has the same name as the module loader container", c.Name)
+		}
+
+		if c.Name == firmwareExtractContainerName && isFirmwareTarball(mod.Spec.ModuleLoader.Container.Modprobe) {
+			return fmt.Errorf("init container name %q is reserved for the firmware extraction", c.Name)
+		}
+	}
+
+	staleFirmwarePath := staleFirmwareHostPath(ds, mod.Spec.ModuleLoader.Container.Modprobe, mod.Name)
+
+	if staleFirmwarePath != "" {
+		for _, c := range mod.Spec.ModuleLoader.InitContainers {
+			if c.Name == firmwareCleanupContainerName {
+				return fmt.Errorf("init container name %q is reserved for the firmware cleanup", c.Name)
+			}
+		}
+	}
+
+	kernelLabelValue := KernelLabelValue(kernelVersion)
+
 	standardLabels := map[string]string{
-		constants.ModuleNameLabel: mod.Name,
-		dc.kernelLabel:            kernelVersion,
-		constants.DaemonSetRole:   "module-loader",
+		dc.moduleNameLabel:      mod.Name,
+		dc.kernelLabel:          kernelLabelValue,
+		constants.DaemonSetRole: constants.ModuleLoaderRole,
+	}
+
+	// distinguishes the DaemonSets of nodes running the same kernel on different architectures
+	if arch != "" {
+		standardLabels[constants.ArchLabel] = arch
 	}
 
 	ds.SetLabels(
-		OverrideLabels(ds.GetLabels(), standardLabels),
+		OverrideLabels(
+			OverrideLabels(ds.GetLabels(), mod.Spec.ModuleLoader.DaemonSetLabels),
+			standardLabels,
+		),
 	)
 
+	// the label only holds a sanitized value; keep the real kernel version so that it can be recovered
+	if kernelLabelValue != kernelVersion {
+		ds.SetAnnotations(
+			OverrideLabels(ds.GetAnnotations(), map[string]string{constants.KernelVersionAnnotation: kernelVersion}),
+		)
+	}
+
+	if dc.gcRetention > 0 && dc.lastSeenValidOutdated(ds) {
+		ds.SetAnnotations(
+			OverrideLabels(ds.GetAnnotations(), map[string]string{
+				constants.LastSeenValidAnnotation: dc.now().UTC().Format(time.RFC3339),
+			}),
+		)
+	}
+
 	nodeSelector := CopyMapStringString(mod.Spec.Selector)
-	nodeSelector[dc.kernelLabel] = kernelVersion
+	nodeSelector[dc.nodeKernelLabel] = kernelLabelValue
+
+	nodeRequirements := []v1.NodeSelectorRequirement{
+		{
+			Key:      dc.nodeKernelLabel,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{kernelLabelValue},
+		},
+	}
+
+	// pin the module loader to the architecture its image was built for
+	if arch != "" {
+		nodeSelector[v1.LabelArchStable] = arch
+
+		nodeRequirements = append(nodeRequirements, v1.NodeSelectorRequirement{
+			Key:      v1.LabelArchStable,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{arch},
+		})
+	}
+
+	// only schedule the module loader on nodes where the modules it depends on are ready
+	for _, dep := range mod.Spec.ModuleLoader.DependsOn {
+		nodeRequirements = append(nodeRequirements, v1.NodeSelectorRequirement{
+			Key:      getDriverContainerNodeLabel(dc.nodeLabelPrefix, dep),
+			Operator: v1.NodeSelectorOpExists,
+		})
+	}
 
 	hostPathDirectory := v1.HostPathDirectory
 	hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
 
+	kernelModuleNames := getModulesLoadingOrder(mod.Spec.ModuleLoader.Container.Modprobe)
+
 	container := v1.Container{
 		Command:         []string{"sleep", "infinity"},
-		Name:            "module-loader",
+		Name:            containerName,
 		Image:           image,
 		ImagePullPolicy: mod.Spec.ModuleLoader.Container.ImagePullPolicy,
+		Resources:       resources,
+		LivenessProbe:   makeLivenessProbe(mod.Spec.ModuleLoader.Container.LivenessProbe, kernelModuleNames),
+		ReadinessProbe:  makeReadinessProbe(mod.Spec.ModuleLoader.Container.ReadinessProbe, kernelModuleNames),
+		StartupProbe:    makeStartupProbe(mod.Spec.ModuleLoader.Container.StartupProbe, kernelModuleNames),
 		Lifecycle: &v1.Lifecycle{
 			PostStart: &v1.LifecycleHandler{
 				Exec: &v1.ExecAction{
-					Command: MakeLoadCommand(mod.Spec.ModuleLoader.Container.Modprobe, mod.Name),
+					Command: MakeLoadCommand(mod.Spec.ModuleLoader.Container.Modprobe, mod.Name, kernelVersion),
 				},
 			},
 			PreStop: &v1.LifecycleHandler{
 				Exec: &v1.ExecAction{
-					Command: MakeUnloadCommand(mod.Spec.ModuleLoader.Container.Modprobe, mod.Name),
+					Command: MakeUnloadCommand(mod.Spec.ModuleLoader.Container.Modprobe, mod.Name, kernelVersion),
 				},
 			},
 		},
@@ -143,10 +702,8 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, d
 			Capabilities: &v1.Capabilities{
 				Add: []v1.Capability{"SYS_MODULE"},
 			},
-			RunAsUser: pointer.Int64(0),
-			SELinuxOptions: &v1.SELinuxOptions{
-				Type: "spc_t",
-			},
+			RunAsUser:      pointer.Int64(0),
+			SELinuxOptions: makeSELinuxOptions(mod.Spec.ModuleLoader.Container.SELinuxType),
 		},
 		VolumeMounts: []v1.VolumeMount{
 			{
@@ -162,6 +719,26 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, d
 		},
 	}
 
+	if c := mod.Spec.ModuleLoader.Container; len(c.Command) > 0 || len(c.Args) > 0 {
+		container.Command = c.Command
+		container.Args = c.Args
+	}
+
+	if c := mod.Spec.ModuleLoader.Container; c.DisableLifecycleHooks {
+		container.Lifecycle = nil
+
+		// the image's entrypoint loads the module
+		if len(c.Command) == 0 && len(c.Args) == 0 {
+			container.Command = nil
+		}
+	}
+
+	if !mod.Spec.ModuleLoader.Container.DisableKernelVersionEnv {
+		container.Env = []v1.EnvVar{
+			{Name: constants.KernelFullVersionEnv, Value: kernelVersion},
+		}
+	}
+
 	volumes := []v1.Volume{
 		{
 			Name: nodeLibModulesVolumeName,
@@ -183,8 +760,74 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, d
 		},
 	}
 
+	for i, p := range mod.Spec.ModuleLoader.ExtraModulesHostPaths {
+		volumeName := fmt.Sprintf("%s-%d", extraModulesVolumeNamePrefix, i)
+
+		volumes = append(volumes, v1.Volume{
+			Name: volumeName,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{
+					Path: p,
+					Type: &hostPathDirectory,
+				},
+			},
+		})
+
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      volumeName,
+			ReadOnly:  true,
+			MountPath: p,
+		})
+	}
+
+	if cm := mod.Spec.ModuleLoader.ModprobeConfigMap; cm != nil {
+		if cm.Name == "" {
+			return errors.New("modprobe ConfigMap name cannot be empty")
+		}
+
+		volumes = append(volumes, v1.Volume{
+			Name: modprobeConfigVolumeName,
+			VolumeSource: v1.VolumeSource{
+				ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: *cm},
+			},
+		})
+
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      modprobeConfigVolumeName,
+			ReadOnly:  true,
+			MountPath: modprobeConfigPath,
+		})
+	}
+
+	if c := mod.Spec.ModuleLoader.Container; c.MountHostDev {
+		hostVolumes, mounts := makeHostDevMounts(c.HostDevPaths)
+
+		volumes = append(volumes, hostVolumes...)
+		container.VolumeMounts = append(container.VolumeMounts, mounts...)
+	}
+
+	var initContainers []v1.Container
+
+	if modprobe := mod.Spec.ModuleLoader.Container.Modprobe; isFirmwareTarball(modprobe) {
+		initContainers = append(initContainers, v1.Container{
+			Name:            firmwareExtractContainerName,
+			Image:           image,
+			ImagePullPolicy: mod.Spec.ModuleLoader.Container.ImagePullPolicy,
+			Command:         makeFirmwareExtractCommand(modprobe, mod.Name, kernelVersion),
+			SecurityContext: &v1.SecurityContext{
+				AllowPrivilegeEscalation: pointer.Bool(false),
+				RunAsUser:                pointer.Int64(0),
+				SELinuxOptions:           makeSELinuxOptions(mod.Spec.ModuleLoader.Container.SELinuxType),
+			},
+		})
+	}
+
+	for _, c := range mod.Spec.ModuleLoader.InitContainers {
+		initContainers = append(initContainers, *c.DeepCopy())
+	}
+
 	if fw := mod.Spec.ModuleLoader.Container.Modprobe.FirmwarePath; fw != "" {
-		moduleFirmwarePath := fmt.Sprintf("%s/%s", nodeVarLibFirmwarePath, mod.Name)
+		moduleFirmwarePath := getModuleFirmwareHostPath(mod.Spec.ModuleLoader.Container.Modprobe, mod.Name)
 
 		firmwareVolume := v1.Volume{
 			Name: nodeVarLibFirmwareVolumeName,
@@ -203,24 +846,137 @@ func (dc *daemonSetGenerator) SetDriverContainerAsDesired(ctx context.Context, d
 		}
 
 		container.VolumeMounts = append(container.VolumeMounts, firmwareVolumeMount)
+
+		for i := range initContainers {
+			initContainers[i].VolumeMounts = append(initContainers[i].VolumeMounts, firmwareVolumeMount)
+		}
+	}
+
+	if staleFirmwarePath != "" {
+		// the directory itself cannot be removed if it is a mount point: mount its parent instead
+		parent := path.Dir(staleFirmwarePath)
+
+		volumes = append(volumes, v1.Volume{
+			Name: staleFirmwareVolumeName,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{Path: parent},
+			},
+		})
+
+		cleanupContainer := v1.Container{
+			Name:            firmwareCleanupContainerName,
+			Image:           image,
+			ImagePullPolicy: mod.Spec.ModuleLoader.Container.ImagePullPolicy,
+			Command:         makeFirmwareCleanupCommand(staleFirmwarePath),
+			SecurityContext: &v1.SecurityContext{
+				AllowPrivilegeEscalation: pointer.Bool(false),
+				RunAsUser:                pointer.Int64(0),
+				SELinuxOptions:           makeSELinuxOptions(mod.Spec.ModuleLoader.Container.SELinuxType),
+			},
+			VolumeMounts: []v1.VolumeMount{
+				{
+					Name:      staleFirmwareVolumeName,
+					MountPath: parent,
+				},
+			},
+		}
+
+		initContainers = append([]v1.Container{cleanupContainer}, initContainers...)
+	}
+
+	if sc := mod.Spec.ModuleLoader.SecurityContext; sc != nil {
+		if sc.RunAsUser != nil {
+			container.SecurityContext.RunAsUser = pointer.Int64(*sc.RunAsUser)
+		}
+
+		if sc.RunAsGroup != nil {
+			container.SecurityContext.RunAsGroup = pointer.Int64(*sc.RunAsGroup)
+		}
+	}
+
+	if mod.Spec.ModuleLoader.Container.ReadOnlyRootFilesystem {
+		container.SecurityContext.ReadOnlyRootFilesystem = pointer.Bool(true)
+
+		volumes = append(volumes, v1.Volume{
+			Name:         tmpVolumeName,
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		})
+
+		container.VolumeMounts = append(container.VolumeMounts, v1.VolumeMount{
+			Name:      tmpVolumeName,
+			MountPath: tmpPath,
+		})
+	}
+
+	podLabels := CopyMapStringString(standardLabels)
+
+	// the version is only set on the pods, as the DaemonSet selector cannot be changed
+	if version := mod.Spec.ModuleLoader.Container.Version; version != "" {
+		podLabels[constants.ModuleVersionLabel] = version
 	}
 
+	configHash, err := modprobeConfigHash(mod.Spec.ModuleLoader.Container.Modprobe, mod.Name, kernelVersion)
+	if err != nil {
+		return fmt.Errorf("could not hash the modprobe configuration: %v", err)
+	}
+
+	podAnnotations := dc.makePodAnnotations(mod.Spec.ModuleLoader.PodAnnotations)
+	if podAnnotations == nil {
+		podAnnotations = make(map[string]string, 1)
+	}
+
+	// the load command only runs when the pod starts: changing the hash rolls the pods so that it runs again
+	podAnnotations[constants.ModprobeConfigHashAnnotation] = configHash
+
+	live := ds.Spec
+
 	ds.Spec = appsv1.DaemonSetSpec{
 		Template: v1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:     standardLabels,
-				Finalizers: []string{constants.NodeLabelerFinalizer},
+				Annotations: podAnnotations,
+				Labels:      podLabels,
+				Finalizers:  []string{dc.nodeLabelerFinalizer},
 			},
 			Spec: v1.PodSpec{
-				Containers:         []v1.Container{container},
-				ImagePullSecrets:   GetPodPullSecrets(mod.Spec.ImageRepoSecret),
-				NodeSelector:       nodeSelector,
-				PriorityClassName:  "system-node-critical",
-				ServiceAccountName: mod.Spec.ModuleLoader.ServiceAccountName,
-				Volumes:            volumes,
+				Affinity:                      makeAffinity(mod.Spec.ModuleLoader.Affinity, nodeRequirements...),
+				Containers:                    []v1.Container{container},
+				DNSConfig:                     mod.Spec.ModuleLoader.DNSConfig,
+				DNSPolicy:                     mod.Spec.ModuleLoader.DNSPolicy,
+				HostIPC:                       mod.Spec.ModuleLoader.HostIPC,
+				HostNetwork:                   mod.Spec.ModuleLoader.HostNetwork,
+				HostPID:                       mod.Spec.ModuleLoader.HostPID,
+				ImagePullSecrets:              GetPodPullSecrets(mod.Spec.ImageRepoSecret, mod.Spec.ImageRepoSecrets...),
+				InitContainers:                initContainers,
+				NodeSelector:                  nodeSelector,
+				PriorityClassName:             getPriorityClassName(mod.Spec.ModuleLoader.PriorityClassName),
+				SecurityContext:               makeModuleLoaderPodSecurityContext(mod.Spec.ModuleLoader),
+				ServiceAccountName:            mod.Spec.ModuleLoader.ServiceAccountName,
+				TerminationGracePeriodSeconds: getTerminationGracePeriodSeconds(mod.Spec.ModuleLoader.Container.Modprobe),
+				Tolerations:                   makeModuleLoaderTolerations(mod.Spec.ModuleLoader),
+				Volumes:                       volumes,
 			},
 		},
-		Selector: &metav1.LabelSelector{MatchLabels: standardLabels},
+		Selector:       &metav1.LabelSelector{MatchLabels: CopyMapStringString(standardLabels)},
+		UpdateStrategy: mod.Spec.ModuleLoader.UpdateStrategy,
+	}
+
+	preserveDefaultedFields(&live, &ds.Spec)
+
+	// the selector is immutable: DaemonSets created before the architecture was part of it keep theirs
+	if sel := live.Selector; sel != nil && sel.MatchLabels[constants.ArchLabel] == "" {
+		ds.Spec.Selector = sel
+	}
+
+	// remember the stale directory so that pods scheduled later during the rollout, for instance on new nodes, also
+	// remove it
+	if staleFirmwarePath != "" {
+		if ds.Annotations == nil {
+			ds.Annotations = make(map[string]string, 1)
+		}
+
+		ds.Annotations[constants.StaleFirmwarePathAnnotation] = staleFirmwarePath
+	} else {
+		delete(ds.Annotations, constants.StaleFirmwarePathAnnotation)
 	}
 
 	return controllerutil.SetControllerReference(&mod, ds, dc.scheme)
@@ -235,11 +991,30 @@ func (dc *daemonSetGenerator) SetDevicePluginAsDesired(ctx context.Context, ds *
 		return errors.New("device plugin in module should not be nil")
 	}
 
-	containerVolumeMounts := []v1.VolumeMount{
-		{
-			Name:      kubeletDevicePluginsVolumeName,
-			MountPath: kubeletDevicePluginsPath,
-		},
+	hostPathVolumes, hostPathVolumeMounts, err := makeHostPathMounts(mod.Spec.DevicePlugin.HostPathMounts)
+	if err != nil {
+		return fmt.Errorf("invalid host path mounts: %v", err)
+	}
+
+	if err = validateUpdateStrategy(mod.Spec.DevicePlugin.UpdateStrategy); err != nil {
+		return fmt.Errorf("invalid device plugin update strategy: %v", err)
+	}
+
+	containerVolumeMounts := make([]v1.VolumeMount, 0)
+	containerVolumeMounts = append(containerVolumeMounts, mod.Spec.DevicePlugin.Container.VolumeMounts...)
+	containerVolumeMounts = append(containerVolumeMounts, v1.VolumeMount{
+		Name:      kubeletDevicePluginsVolumeName,
+		MountPath: dc.kubeletDevicePluginsPath,
+	})
+	containerVolumeMounts = append(containerVolumeMounts, hostPathVolumeMounts...)
+
+	tokenVolume, tokenVolumeMount, err := makeProjectedServiceAccountToken(mod.Spec.DevicePlugin.ProjectedServiceAccountToken)
+	if err != nil {
+		return fmt.Errorf("invalid projected ServiceAccount token: %v", err)
+	}
+
+	if tokenVolumeMount != nil {
+		containerVolumeMounts = append(containerVolumeMounts, *tokenVolumeMount)
 	}
 
 	hostPathDirectory := v1.HostPathDirectory
@@ -248,29 +1023,97 @@ func (dc *daemonSetGenerator) SetDevicePluginAsDesired(ctx context.Context, ds *
 		Name: kubeletDevicePluginsVolumeName,
 		VolumeSource: v1.VolumeSource{
 			HostPath: &v1.HostPathVolumeSource{
-				Path: kubeletDevicePluginsPath,
+				Path: dc.kubeletDevicePluginsPath,
 				Type: &hostPathDirectory,
 			},
 		},
 	}
 
-	standardLabels := map[string]string{
-		constants.ModuleNameLabel: mod.Name,
-		constants.DaemonSetRole:   "device-plugin",
+	if dc.devicePluginsVolumeSource != nil {
+		devicePluginVolume.VolumeSource = *dc.devicePluginsVolumeSource.DeepCopy()
+	}
+
+	volumes := []v1.Volume{devicePluginVolume}
+	volumes = append(volumes, mod.Spec.DevicePlugin.Volumes...)
+	volumes = append(volumes, hostPathVolumes...)
+
+	if tokenVolume != nil {
+		volumes = append(volumes, *tokenVolume)
+	}
+
+	if mod.Spec.DevicePlugin.MountModuleFirmware {
+		modprobe := mod.Spec.ModuleLoader.Container.Modprobe
+
+		if modprobe.FirmwarePath == "" {
+			return errors.New("cannot mount the module firmware: the module loader does not copy any firmware")
+		}
+
+		moduleFirmwarePath := getModuleFirmwareHostPath(modprobe, mod.Name)
+		hostPathDirectoryOrCreate := v1.HostPathDirectoryOrCreate
+
+		volumes = append(volumes, v1.Volume{
+			Name: nodeVarLibFirmwareVolumeName,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{
+					Path: moduleFirmwarePath,
+					Type: &hostPathDirectoryOrCreate,
+				},
+			},
+		})
+
+		containerVolumeMounts = append(containerVolumeMounts, v1.VolumeMount{
+			Name:      nodeVarLibFirmwareVolumeName,
+			MountPath: moduleFirmwarePath,
+			ReadOnly:  true,
+		})
+	}
+
+	standardLabels := dc.devicePluginLabels(mod)
+
+	if err = validateTopologySpreadConstraints(mod.Spec.DevicePlugin.TopologySpreadConstraints, standardLabels); err != nil {
+		return fmt.Errorf("invalid device plugin topology spread constraints: %v", err)
 	}
 
 	ds.SetLabels(
-		OverrideLabels(ds.GetLabels(), standardLabels),
+		OverrideLabels(
+			OverrideLabels(ds.GetLabels(), mod.Spec.DevicePlugin.DaemonSetLabels),
+			standardLabels,
+		),
 	)
 
+	// only run the device plugin on the nodes selected by the Module on which the kernel module is loaded
+	nodeSelector := CopyMapStringString(mod.Spec.Selector)
+
+	for k, v := range mod.Spec.DevicePlugin.NodeSelector {
+		if mv, ok := nodeSelector[k]; ok && mv != v {
+			return fmt.Errorf("device plugin node selector %s=%s conflicts with the Module selector %s=%s", k, v, k, mv)
+		}
+
+		nodeSelector[k] = v
+	}
+
+	readyLabel := getDriverContainerNodeLabel(dc.nodeLabelPrefix, mod.Name)
+
+	if _, ok := mod.Spec.DevicePlugin.NodeSelector[readyLabel]; ok {
+		return fmt.Errorf("device plugin node selector cannot contain the reserved label %s", readyLabel)
+	}
+
+	nodeSelector[readyLabel] = ""
+
+	live := ds.Spec
+
 	ds.Spec = appsv1.DaemonSetSpec{
-		Selector: &metav1.LabelSelector{MatchLabels: standardLabels},
+		Selector:       &metav1.LabelSelector{MatchLabels: CopyMapStringString(standardLabels)},
+		UpdateStrategy: mod.Spec.DevicePlugin.UpdateStrategy,
 		Template: v1.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:     standardLabels,
-				Finalizers: []string{constants.NodeLabelerFinalizer},
+				Annotations: dc.makePodAnnotations(mod.Spec.DevicePlugin.PodAnnotations),
+				Labels:      CopyMapStringString(standardLabels),
+				Finalizers:  []string{dc.nodeLabelerFinalizer},
 			},
 			Spec: v1.PodSpec{
+				Affinity:                     mod.Spec.DevicePlugin.Affinity.DeepCopy(),
+				AutomountServiceAccountToken: mod.Spec.DevicePlugin.AutomountServiceAccountToken,
 				Containers: []v1.Container{
 					{
 						Args:            mod.Spec.DevicePlugin.Container.Args,
@@ -280,157 +1123,2100 @@ func (dc *daemonSetGenerator) SetDevicePluginAsDesired(ctx context.Context, ds *
 						Image:           mod.Spec.DevicePlugin.Container.Image,
 						ImagePullPolicy: mod.Spec.DevicePlugin.Container.ImagePullPolicy,
 						Resources:       mod.Spec.DevicePlugin.Container.Resources,
-						SecurityContext: &v1.SecurityContext{Privileged: pointer.Bool(true)},
-						VolumeMounts:    append(mod.Spec.DevicePlugin.Container.VolumeMounts, containerVolumeMounts...),
+						SecurityContext: makeDevicePluginSecurityContext(mod.Spec.DevicePlugin.Container.SecurityContext),
+						VolumeMounts:    containerVolumeMounts,
 					},
 				},
-				PriorityClassName:  "system-node-critical",
-				ImagePullSecrets:   GetPodPullSecrets(mod.Spec.ImageRepoSecret),
-				NodeSelector:       map[string]string{getDriverContainerNodeLabel(mod.Name): ""},
-				ServiceAccountName: mod.Spec.DevicePlugin.ServiceAccountName,
-				Volumes:            append([]v1.Volume{devicePluginVolume}, mod.Spec.DevicePlugin.Volumes...),
+				PriorityClassName:         getPriorityClassName(mod.Spec.DevicePlugin.PriorityClassName),
+				ImagePullSecrets:          GetPodPullSecrets(mod.Spec.ImageRepoSecret, mod.Spec.ImageRepoSecrets...),
+				NodeSelector:              nodeSelector,
+				ServiceAccountName:        mod.Spec.DevicePlugin.ServiceAccountName,
+				Tolerations:               mod.Spec.DevicePlugin.Tolerations,
+				TopologySpreadConstraints: mod.Spec.DevicePlugin.TopologySpreadConstraints,
+				Volumes:                   volumes,
 			},
 		},
 	}
 
-	return controllerutil.SetControllerReference(mod, ds, dc.scheme)
+	preserveDefaultedFields(&live, &ds.Spec)
+
+	return dc.setDevicePluginOwner(ds, mod)
 }
 
-func (dc *daemonSetGenerator) GetNodeLabelFromPod(pod *v1.Pod, moduleName string) string {
-	kernelVersion := pod.Labels[dc.kernelLabel]
-	if kernelVersion == devicePluginKernelVersion {
-		return getDevicePluginNodeLabel(moduleName)
+// SetDevicePluginPodDisruptionBudgetAsDesired sets the desired state of the PodDisruptionBudget protecting the pods
+// of mod's device plugin DaemonSet.
+func (dc *daemonSetGenerator) SetDevicePluginPodDisruptionBudgetAsDesired(ctx context.Context, pdb *policyv1.PodDisruptionBudget, mod *kmmv1beta1.Module) error {
+	if pdb == nil {
+		return errors.New("pdb cannot be nil")
+	}
+
+	if mod.Spec.DevicePlugin == nil || mod.Spec.DevicePlugin.PodDisruptionBudget == nil {
+		return errors.New("device plugin PodDisruptionBudget in module should not be nil")
 	}
-	return getDriverContainerNodeLabel(moduleName)
+
+	maxUnavailable := mod.Spec.DevicePlugin.PodDisruptionBudget.MaxUnavailable
+
+	if err := validateMaxUnavailable(maxUnavailable); err != nil {
+		return fmt.Errorf("invalid device plugin PodDisruptionBudget: %v", err)
+	}
+
+	standardLabels := dc.devicePluginLabels(mod)
+
+	pdb.SetLabels(OverrideLabels(pdb.GetLabels(), standardLabels))
+
+	pdb.Spec = policyv1.PodDisruptionBudgetSpec{
+		MaxUnavailable: &maxUnavailable,
+		Selector:       &metav1.LabelSelector{MatchLabels: CopyMapStringString(standardLabels)},
+	}
+
+	return dc.setDevicePluginOwner(pdb, mod)
 }
 
-func (dc *daemonSetGenerator) moduleDaemonSets(ctx context.Context, name, namespace string) ([]appsv1.DaemonSet, error) {
-	dsList := appsv1.DaemonSetList{}
+// DevicePluginName returns the name of mod's device plugin DaemonSet and PodDisruptionBudget.
+func DevicePluginName(mod *kmmv1beta1.Module) string {
+	return mod.Name + "-device-plugin"
+}
+
+// DevicePluginNamespace returns the namespace of mod's device plugin objects.
+func DevicePluginNamespace(mod *kmmv1beta1.Module) string {
+	if dp := mod.Spec.DevicePlugin; dp != nil && dp.Namespace != "" {
+		return dp.Namespace
+	}
+
+	return mod.Namespace
+}
+
+// setDevicePluginOwner makes mod the controller of obj if obj belongs to its namespace. Otherwise, as owner references
+// cannot cross namespaces, obj is annotated with the namespaced name of mod so that
+// DeleteCrossNamespaceDevicePlugins can find it.
+func (dc *daemonSetGenerator) setDevicePluginOwner(obj client.Object, mod *kmmv1beta1.Module) error {
+	if DevicePluginNamespace(mod) == mod.Namespace {
+		return controllerutil.SetControllerReference(mod, obj, dc.scheme)
+	}
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+
+	annotations[constants.OwnerAnnotation] = types.NamespacedName{Namespace: mod.Namespace, Name: mod.Name}.String()
+	obj.SetAnnotations(annotations)
+
+	return nil
+}
+
+// DeleteCrossNamespaceDevicePlugins deletes the device plugin DaemonSets and PodDisruptionBudgets that were created
+// for the Module namespace/name in other namespaces, except those in keepNamespace, which can be empty.
+// It returns the namespaced names of the deleted DaemonSets.
+func (dc *daemonSetGenerator) DeleteCrossNamespaceDevicePlugins(ctx context.Context, name, namespace, keepNamespace string) ([]string, error) {
+	owner := types.NamespacedName{Namespace: namespace, Name: name}.String()
+
 	opts := []client.ListOption{
-		client.MatchingLabels(map[string]string{constants.ModuleNameLabel: name}),
-		client.InNamespace(namespace),
+		client.MatchingLabels{
+			dc.moduleNameLabel:      name,
+			constants.DaemonSetRole: constants.DevicePluginRole,
+		},
 	}
+
+	dsList := appsv1.DaemonSetList{}
+
 	if err := dc.client.List(ctx, &dsList, opts...); err != nil {
-		return nil, fmt.Errorf("could not list DaemonSets: %v", err)
+		return nil, fmt.Errorf("could not list device plugin DaemonSets: %v", err)
 	}
-	return dsList.Items, nil
-}
 
-func (dc *daemonSetGenerator) isDevicePluginDaemonSet(ds *appsv1.DaemonSet) bool {
-	return ds.Labels[dc.kernelLabel] == ""
+	pdbList := policyv1.PodDisruptionBudgetList{}
+
+	if err := dc.client.List(ctx, &pdbList, opts...); err != nil {
+		return nil, fmt.Errorf("could not list device plugin PodDisruptionBudgets: %v", err)
+	}
+
+	objs := make([]client.Object, 0, len(dsList.Items)+len(pdbList.Items))
+
+	for i := range dsList.Items {
+		objs = append(objs, &dsList.Items[i])
+	}
+
+	for i := range pdbList.Items {
+		objs = append(objs, &pdbList.Items[i])
+	}
+
+	deleted := make([]string, 0)
+	errs := make([]error, 0)
+
+	for _, obj := range objs {
+		if obj.GetAnnotations()[constants.OwnerAnnotation] != owner || obj.GetNamespace() == keepNamespace {
+			continue
+		}
+
+		nsn := client.ObjectKeyFromObject(obj).String()
+
+		if err := dc.client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("could not delete %s: %v", nsn, err))
+			continue
+		}
+
+		if _, ok := obj.(*appsv1.DaemonSet); ok {
+			deleted = append(deleted, nsn)
+		}
+	}
+
+	return deleted, utilerrors.NewAggregate(errs)
 }
 
-// CopyMapStringString returns a deep copy of m.
-func CopyMapStringString(m map[string]string) map[string]string {
-	n := make(map[string]string, len(m))
+// devicePluginLabels returns the labels managed by KMM on mod's device plugin DaemonSet and pods.
+func (dc *daemonSetGenerator) devicePluginLabels(mod *kmmv1beta1.Module) map[string]string {
+	return map[string]string{
+		dc.moduleNameLabel:      mod.Name,
+		constants.DaemonSetRole: constants.DevicePluginRole,
+	}
+}
 
-	for k, v := range m {
-		n[k] = v
+// GetNodeLabelFromPod returns the node label reflecting the readiness of pod: the device plugin one if the pod runs
+// the device plugin, and the module loader one otherwise.
+// The role label of the pod is used if present; pods without it are classified by their kernel label, which is empty
+// for the device plugin.
+func (dc *daemonSetGenerator) GetNodeLabelFromPod(pod *v1.Pod, moduleName string) string {
+	if isDevicePluginRole(pod.Labels, pod.Labels[dc.kernelLabel]) {
+		return getDevicePluginNodeLabel(dc.nodeLabelPrefix, moduleName)
 	}
+	return getDriverContainerNodeLabel(dc.nodeLabelPrefix, moduleName)
+}
 
-	return n
+func (dc *daemonSetGenerator) GetModuleNameLabel() string {
+	return dc.moduleNameLabel
 }
 
-func getDriverContainerNodeLabel(moduleName string) string {
-	return fmt.Sprintf("kmm.node.kubernetes.io/%s.ready", moduleName)
+// KernelLabel returns the key of the label holding the kernel version on the module loader DaemonSets and their pods,
+// as passed to NewCreator.
+func (dc *daemonSetGenerator) KernelLabel() string {
+	return dc.kernelLabel
 }
 
-func getDevicePluginNodeLabel(moduleName string) string {
-	return fmt.Sprintf("kmm.node.kubernetes.io/%s.device-plugin-ready", moduleName)
+func (dc *daemonSetGenerator) GetNodeLabelerFinalizer() string {
+	return dc.nodeLabelerFinalizer
 }
 
-func IsDevicePluginKernelVersion(kernelVersion string) bool {
-	return kernelVersion == devicePluginKernelVersion
+// KernelVersion returns the kernel version targeted by ds, or an empty string if ds runs the device plugin.
+// The kernel version annotation takes precedence over the kernel label, which may only hold a sanitized value.
+func (dc *daemonSetGenerator) KernelVersion(ds *appsv1.DaemonSet) string {
+	if kernelVersion, ok := ds.Annotations[constants.KernelVersionAnnotation]; ok {
+		return kernelVersion
+	}
+
+	return ds.Labels[dc.kernelLabel]
 }
 
-func GetDevicePluginKernelVersion() string {
-	return devicePluginKernelVersion
+// NodeModuleStatus returns the number of nodes targeted by mod's selector on which the kernel module is loaded.
+func (dc *daemonSetGenerator) NodeModuleStatus(ctx context.Context, mod *kmmv1beta1.Module) (int, error) {
+	nodes := v1.NodeList{}
+
+	if err := dc.client.List(ctx, &nodes, client.MatchingLabels(mod.Spec.Selector)); err != nil {
+		return 0, fmt.Errorf("could not list nodes: %v", err)
+	}
+
+	nodeLabel := getDriverContainerNodeLabel(dc.nodeLabelPrefix, mod.Name)
+	loaded := 0
+
+	for _, node := range nodes.Items {
+		if _, ok := node.Labels[nodeLabel]; ok {
+			loaded++
+		}
+	}
+
+	return loaded, nil
 }
 
-func GetPodPullSecrets(secret *v1.LocalObjectReference) []v1.LocalObjectReference {
-	if secret == nil {
-		return nil
+// RemoveModuleNodeLabels removes the labels indicating that moduleName's kernel module and device plugin are ready
+// from all nodes, so that the workloads depending on them are no longer scheduled there.
+// It is meant to be called when the Module is being deleted, before its DaemonSets are.
+func (dc *daemonSetGenerator) RemoveModuleNodeLabels(ctx context.Context, moduleName string) error {
+	nodeLabels := []string{
+		getDriverContainerNodeLabel(dc.nodeLabelPrefix, moduleName),
+		getDevicePluginNodeLabel(dc.nodeLabelPrefix, moduleName),
+	}
+
+	nodesByName := make(map[string]v1.Node)
+
+	for _, label := range nodeLabels {
+		nodes := v1.NodeList{}
+
+		if err := dc.client.List(ctx, &nodes, client.HasLabels{label}); err != nil {
+			return fmt.Errorf("could not list nodes with label %s: %v", label, err)
+		}
+
+		for _, node := range nodes.Items {
+			nodesByName[node.Name] = node
+		}
+	}
+
+	errs := make([]error, 0)
+
+	for _, node := range nodesByName {
+		nodeCopy := node.DeepCopy()
+		changed := false
+
+		for _, label := range nodeLabels {
+			if _, ok := node.Labels[label]; ok {
+				delete(node.Labels, label)
+				changed = true
+			}
+		}
+
+		if !changed {
+			continue
+		}
+
+		if err := dc.client.Patch(ctx, &node, client.MergeFrom(nodeCopy)); err != nil {
+			errs = append(errs, fmt.Errorf("could not remove the labels of node %s: %v", node.Name, err))
+		}
 	}
 
-	return []v1.LocalObjectReference{*secret}
+	return utilerrors.NewAggregate(errs)
 }
 
-func OverrideLabels(labels, overrides map[string]string) map[string]string {
-	if labels == nil {
-		labels = make(map[string]string, len(overrides))
+// AllModuleDaemonSets returns the DaemonSets of all Modules in all namespaces, indexed by the Module's namespace and
+// name.
+func (dc *daemonSetGenerator) AllModuleDaemonSets(ctx context.Context) (map[types.NamespacedName][]appsv1.DaemonSet, error) {
+	dsList := appsv1.DaemonSetList{}
+
+	if err := dc.client.List(ctx, &dsList, client.HasLabels{dc.moduleNameLabel}); err != nil {
+		return nil, fmt.Errorf("could not list DaemonSets: %v", err)
 	}
 
-	for k, v := range overrides {
-		labels[k] = v
+	dsByModule := make(map[types.NamespacedName][]appsv1.DaemonSet)
+
+	for _, ds := range dsList.Items {
+		modName := ds.Labels[dc.moduleNameLabel]
+		if modName == "" {
+			continue
+		}
+
+		nsn := types.NamespacedName{Namespace: ds.Namespace, Name: modName}
+
+		dsByModule[nsn] = append(dsByModule[nsn], ds)
 	}
 
-	return labels
+	return dsByModule, nil
 }
 
-func MakeLoadCommand(spec kmmv1beta1.ModprobeSpec, modName string) []string {
-	loadCommandShell := []string{
-		"/bin/sh",
-		"-c",
+// WaitForModuleDaemonSetsReady polls the DaemonSets of the Module until all their desired pods are ready.
+// If timeout elapses first, the returned error lists the kernel versions whose DaemonSets are not ready yet.
+func (dc *daemonSetGenerator) WaitForModuleDaemonSetsReady(ctx context.Context, name, namespace string, timeout time.Duration) error {
+	var lagging []string
+
+	err := wait.PollImmediateWithContext(ctx, dc.readyPollInterval, timeout, func(ctx context.Context) (bool, error) {
+		dsByKernelVersion, _, err := dc.ModuleDaemonSetsByKernelVersion(ctx, name, namespace)
+		if err != nil {
+			return false, err
+		}
+
+		lagging = laggingDaemonSets(dsByKernelVersion)
+
+		return len(lagging) == 0, nil
+	})
+
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf(
+			"timed out waiting for the DaemonSets of Module %s/%s to be ready: %s",
+			namespace,
+			name,
+			strings.Join(lagging, ", "),
+		)
 	}
 
-	loadCommand := "modprobe"
+	return err
+}
 
-	if ra := spec.RawArgs; ra != nil && len(ra.Load) > 0 {
-		loadCommand = fmt.Sprintf("%s %s", loadCommand, strings.Join(ra.Load, " "))
-		return append(loadCommandShell, loadCommand)
+// laggingDaemonSets returns a description of the DaemonSets in dsByKernelVersion that do not have all their desired
+// pods ready, sorted by kernel version.
+func laggingDaemonSets(dsByKernelVersion map[string]*appsv1.DaemonSet) []string {
+	lagging := make([]string, 0)
+
+	for kernelVersion, ds := range dsByKernelVersion {
+		if ds.Status.NumberReady == ds.Status.DesiredNumberScheduled {
+			continue
+		}
+
+		if IsDevicePluginKernelVersion(kernelVersion) {
+			kernelVersion = "device plugin"
+		}
+
+		lagging = append(
+			lagging,
+			fmt.Sprintf("%s (%d/%d ready)", kernelVersion, ds.Status.NumberReady, ds.Status.DesiredNumberScheduled),
+		)
 	}
 
-	if fw := spec.FirmwarePath; fw != "" {
-		loadCommand = fmt.Sprintf("cp -r %s %s/%s && %s", fw, nodeVarLibFirmwarePath, modName, loadCommand)
+	sort.Strings(lagging)
+
+	return lagging
+}
+
+// ValidateDaemonSet submits ds to the API server in dry-run mode if dry-run validation is enabled: a DaemonSet that
+// does not exist yet is created, and an existing one is server-side applied.
+// It returns a *ValidationError if the API server or an admission controller rejects ds.
+func (dc *daemonSetGenerator) ValidateDaemonSet(ctx context.Context, ds *appsv1.DaemonSet) error {
+	if !dc.dryRunValidation {
+		return nil
 	}
 
-	if a := spec.Args; a != nil && len(a.Load) > 0 {
-		loadCommand = fmt.Sprintf("%s %s", loadCommand, strings.Join(a.Load, " "))
+	obj := ds.DeepCopy()
+	obj.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("DaemonSet"))
+	obj.ManagedFields = nil
+	obj.ResourceVersion = ""
+	obj.Status = appsv1.DaemonSetStatus{}
+
+	var err error
+
+	if obj.Name == "" {
+		err = dc.client.Create(ctx, obj, client.DryRunAll)
 	} else {
-		loadCommand = fmt.Sprintf("%s -v", loadCommand)
+		err = dc.client.Patch(ctx, obj, client.Apply, client.DryRunAll, client.FieldOwner(dryRunFieldOwner), client.ForceOwnership)
 	}
 
-	if dirName := spec.DirName; dirName != "" {
-		loadCommand = fmt.Sprintf("%s -d %s", loadCommand, dirName)
+	if err == nil {
+		return nil
 	}
 
-	loadCommand = fmt.Sprintf("%s %s", loadCommand, spec.ModuleName)
+	name := ds.Name
+	if name == "" {
+		name = ds.GenerateName
+	}
 
-	if p := spec.Parameters; len(p) > 0 {
-		loadCommand = fmt.Sprintf("%s %s", loadCommand, strings.Join(spec.Parameters, " "))
+	if apierrors.IsInvalid(err) || apierrors.IsForbidden(err) {
+		return &ValidationError{Name: name, Err: err}
 	}
 
-	return append(loadCommandShell, loadCommand)
+	return fmt.Errorf("could not validate DaemonSet %s: %v", name, err)
 }
 
-func MakeUnloadCommand(spec kmmv1beta1.ModprobeSpec, modName string) []string {
-	unloadCommandShell := []string{
-		"/bin/sh",
-		"-c",
+// ModulePods returns the module loader and device plugin pods of a Module.
+// The pods are listed in pages of modulePodsPageSize items to limit the load on the API server in large clusters.
+func (dc *daemonSetGenerator) ModulePods(ctx context.Context, name, namespace string) ([]v1.Pod, error) {
+	pods := make([]v1.Pod, 0)
+
+	opts := []client.ListOption{
+		client.MatchingLabels(map[string]string{dc.moduleNameLabel: name}),
+		client.InNamespace(namespace),
+		client.Limit(modulePodsPageSize),
 	}
 
-	unloadCommand := "modprobe"
+	continueToken := ""
 
-	if ra := spec.RawArgs; ra != nil && len(ra.Unload) > 0 {
-		unloadCommand = fmt.Sprintf("%s %s", unloadCommand, strings.Join(ra.Unload, " "))
-		return append(unloadCommandShell, unloadCommand)
+	for {
+		podList := v1.PodList{}
+
+		if err := dc.client.List(ctx, &podList, append(opts, client.Continue(continueToken))...); err != nil {
+			return nil, fmt.Errorf("could not list pods: %v", err)
+		}
+
+		pods = append(pods, podList.Items...)
+
+		if continueToken = podList.Continue; continueToken == "" {
+			return pods, nil
+		}
 	}
+}
 
-	if a := spec.Args; a != nil && len(a.Unload) > 0 {
-		unloadCommand = fmt.Sprintf("%s %s", unloadCommand, strings.Join(a.Unload, " "))
-	} else {
-		unloadCommand = fmt.Sprintf("%s -rv", unloadCommand)
+func (dc *daemonSetGenerator) moduleDaemonSets(ctx context.Context, name, namespace string) ([]appsv1.DaemonSet, error) {
+	dsList := appsv1.DaemonSetList{}
+	opts := []client.ListOption{
+		client.MatchingLabels(map[string]string{dc.moduleNameLabel: name}),
+		client.InNamespace(namespace),
 	}
+	if err := dc.client.List(ctx, &dsList, opts...); err != nil {
+		return nil, fmt.Errorf("could not list DaemonSets: %v", err)
+	}
+	return dsList.Items, nil
+}
 
-	if dirName := spec.DirName; dirName != "" {
-		unloadCommand = fmt.Sprintf("%s -d %s", unloadCommand, dirName)
+// daemonSetsToDelete returns the DaemonSets that should be garbage-collected, the earliest time at which a
+// DaemonSet kept because of the retention window can be deleted, and whether stale module loader DaemonSets were
+// kept because there are too few valid kernel versions.
+// DaemonSets annotated with kmm.node.kubernetes.io/pin: "true" are never garbage-collected.
+func (dc *daemonSetGenerator) daemonSetsToDelete(existingDS map[string]*appsv1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) ([]*appsv1.DaemonSet, time.Time, bool) {
+	toDelete := make([]*appsv1.DaemonSet, 0)
+
+	var (
+		retainedUntil time.Time
+		safeguarded   bool
+	)
+
+	for kernelVersion, ds := range existingDS {
+		if isPinned(ds) {
+			continue
+		}
+
+		if dc.isDevicePluginDaemonSet(ds) {
+			if devicePluginEnabled {
+				continue
+			}
+		} else if validKernels.Has(kernelVersion) {
+			continue
+		} else if until := dc.retainedUntil(ds); dc.now().Before(until) {
+			if retainedUntil.IsZero() || until.Before(retainedUntil) {
+				retainedUntil = until
+			}
+
+			continue
+		} else if validKernels.Len() < dc.gcMinValidKernels {
+			safeguarded = true
+			continue
+		}
+
+		toDelete = append(toDelete, ds)
 	}
 
-	unloadCommand = fmt.Sprintf("%s %s", unloadCommand, spec.ModuleName)
+	return toDelete, retainedUntil, safeguarded
+}
+
+// isPinned returns true if ds was pinned by a user to protect it from garbage collection.
+func isPinned(ds *appsv1.DaemonSet) bool {
+	return ds.GetAnnotations()[constants.PinAnnotation] == "true"
+}
+
+// retainedUntil returns the time until which ds should be kept after its kernel version stopped being valid.
+// It returns the zero time if the retention window is disabled or if ds has no valid last-seen-valid annotation.
+func (dc *daemonSetGenerator) retainedUntil(ds *appsv1.DaemonSet) time.Time {
+	if dc.gcRetention <= 0 {
+		return time.Time{}
+	}
 
-	if fw := spec.FirmwarePath; fw != "" {
-		unloadCommand = fmt.Sprintf("%s && rm -rf %s/%s", unloadCommand, nodeVarLibFirmwarePath, modName)
+	lastSeenValid, err := time.Parse(time.RFC3339, ds.GetAnnotations()[constants.LastSeenValidAnnotation])
+	if err != nil {
+		return time.Time{}
 	}
 
-	return append(unloadCommandShell, unloadCommand)
+	// the annotation may be up to one refresh interval old when the kernel version stops being valid
+	return lastSeenValid.Add(dc.gcRetention + dc.lastSeenValidRefreshInterval())
+}
+
+// lastSeenValidOutdated returns true if the last-seen-valid annotation of ds is missing, invalid, or older than the
+// refresh interval.
+func (dc *daemonSetGenerator) lastSeenValidOutdated(ds *appsv1.DaemonSet) bool {
+	lastSeenValid, err := time.Parse(time.RFC3339, ds.GetAnnotations()[constants.LastSeenValidAnnotation])
+	if err != nil {
+		return true
+	}
+
+	return dc.now().Sub(lastSeenValid) >= dc.lastSeenValidRefreshInterval()
+}
+
+func (dc *daemonSetGenerator) lastSeenValidRefreshInterval() time.Duration {
+	return dc.gcRetention / lastSeenValidRefreshDivisor
+}
+
+// makePodAnnotations returns a copy of annotations without the keys in the KMM domain, which are reserved.
+// It returns nil if no annotations are left.
+func (dc *daemonSetGenerator) makePodAnnotations(annotations map[string]string) map[string]string {
+	var res map[string]string
+
+	for k, v := range annotations {
+		if dc.isReservedKey(k) {
+			continue
+		}
+
+		if res == nil {
+			res = make(map[string]string, len(annotations))
+		}
+
+		res[k] = v
+	}
+
+	return res
+}
+
+func (dc *daemonSetGenerator) isReservedKey(key string) bool {
+	return strings.HasPrefix(key, DefaultNodeLabelPrefix+"/") || strings.HasPrefix(key, dc.nodeLabelPrefix+"/")
+}
+
+func (dc *daemonSetGenerator) isDevicePluginDaemonSet(ds *appsv1.DaemonSet) bool {
+	return isDevicePluginRole(ds.GetLabels(), dc.KernelVersion(ds))
+}
+
+// isDevicePluginRole returns true if labels, which belong to a DaemonSet or a pod targeting kernelVersion, identify it
+// as running the device plugin.
+// The role label is authoritative; objects created before it was introduced are classified by their kernel version,
+// which is empty for the device plugin.
+func isDevicePluginRole(labels map[string]string, kernelVersion string) bool {
+	switch labels[constants.DaemonSetRole] {
+	case constants.DevicePluginRole:
+		return true
+	case constants.ModuleLoaderRole:
+		return false
+	default:
+		return IsDevicePluginKernelVersion(kernelVersion)
+	}
+}
+
+// isNewer returns true if a was created after b.
+// If both DaemonSets were created at the same time, the one with the lowest UID is considered newer so that the
+// result is deterministic.
+func isNewer(a, b *appsv1.DaemonSet) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return b.CreationTimestamp.Before(&a.CreationTimestamp)
+	}
+
+	return a.UID < b.UID
+}
+
+// CopyMapStringString returns a deep copy of m.
+func CopyMapStringString(m map[string]string) map[string]string {
+	n := make(map[string]string, len(m))
+
+	for k, v := range m {
+		n[k] = v
+	}
+
+	return n
+}
+
+// makeHostPathMounts returns the volumes and volume mounts corresponding to mounts.
+func makeHostPathMounts(mounts []kmmv1beta1.HostPathMount) ([]v1.Volume, []v1.VolumeMount, error) {
+	volumes := make([]v1.Volume, 0, len(mounts))
+	volumeMounts := make([]v1.VolumeMount, 0, len(mounts))
+
+	for i, m := range mounts {
+		if !path.IsAbs(m.HostPath) {
+			return nil, nil, fmt.Errorf("host path %q is not absolute", m.HostPath)
+		}
+
+		if !path.IsAbs(m.MountPath) {
+			return nil, nil, fmt.Errorf("mount path %q is not absolute", m.MountPath)
+		}
+
+		name := fmt.Sprintf("%s-%d", hostPathMountVolumeNamePrefix, i)
+
+		volumes = append(volumes, v1.Volume{
+			Name: name,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{Path: m.HostPath},
+			},
+		})
+
+		volumeMounts = append(volumeMounts, v1.VolumeMount{
+			Name:      name,
+			MountPath: m.MountPath,
+			ReadOnly:  m.ReadOnly,
+		})
+	}
+
+	return volumes, volumeMounts, nil
+}
+
+// makeProjectedServiceAccountToken returns the volume and volume mount projecting the ServiceAccount token described
+// by token, or nil if token is nil.
+func makeProjectedServiceAccountToken(token *kmmv1beta1.ProjectedServiceAccountToken) (*v1.Volume, *v1.VolumeMount, error) {
+	if token == nil {
+		return nil, nil, nil
+	}
+
+	if !path.IsAbs(token.MountPath) {
+		return nil, nil, fmt.Errorf("mount path %q is not absolute", token.MountPath)
+	}
+
+	volume := v1.Volume{
+		Name: serviceAccountTokenVolumeName,
+		VolumeSource: v1.VolumeSource{
+			Projected: &v1.ProjectedVolumeSource{
+				Sources: []v1.VolumeProjection{
+					{
+						ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+							Audience:          token.Audience,
+							ExpirationSeconds: token.ExpirationSeconds,
+							Path:              serviceAccountTokenPath,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	volumeMount := v1.VolumeMount{
+		Name:      serviceAccountTokenVolumeName,
+		MountPath: token.MountPath,
+		ReadOnly:  true,
+	}
+
+	return &volume, &volumeMount, nil
+}
+
+// ModuleLoaderContainerName returns the name of the module loader container of mod: the one set in its spec, or
+// module-loader by default.
+func ModuleLoaderContainerName(mod *kmmv1beta1.Module) string {
+	if name := mod.Spec.ModuleLoader.Container.Name; name != "" {
+		return name
+	}
+
+	return moduleLoaderContainerName
+}
+
+// StaleImageDaemonSets returns the names of the module loader DaemonSets in existing, indexed by DaemonSetKey as
+// returned by ModuleDaemonSetsByKernelVersion, whose module loader container, named containerName, does not run their
+// desired image.
+// desiredImages is indexed by DaemonSetKey too; images that do not depend on the architecture can be indexed by
+// kernel version only.
+// Device plugin DaemonSets and DaemonSets without a desired image are ignored.
+func StaleImageDaemonSets(existing map[string]*appsv1.DaemonSet, desiredImages map[string]string, containerName string) []string {
+	stale := make([]string, 0)
+
+	for key, ds := range existing {
+		if IsDevicePluginKernelVersion(key) {
+			continue
+		}
+
+		desiredImage, ok := desiredImages[key]
+		if !ok {
+			if desiredImage, ok = desiredImages[KernelVersionFromKey(key)]; !ok {
+				continue
+			}
+		}
+
+		var image string
+
+		for _, c := range ds.Spec.Template.Spec.Containers {
+			if c.Name == containerName {
+				image = c.Image
+				break
+			}
+		}
+
+		if image != desiredImage {
+			stale = append(stale, ds.Name)
+		}
+	}
+
+	sort.Strings(stale)
+
+	return stale
+}
+
+// DaemonSetNeedsUpdate returns true if live differs from desired in any of the fields managed by KMM: the labels,
+// the update strategy and the whole pod template.
+// Labels and pod template annotations that are present on live but not on desired are ignored, as they were added by
+// someone else. The update strategy is only compared if desired sets one, and the fields of the pod template that
+// desired leaves empty are compared after the API server defaults, as live holds them.
+// Updating a DaemonSet that uses the OnDelete strategy only changes its template: the existing pods keep running until
+// they are deleted.
+func DaemonSetNeedsUpdate(live, desired *appsv1.DaemonSet) bool {
+	for k, v := range desired.Labels {
+		if lv, ok := live.Labels[k]; !ok || lv != v {
+			return true
+		}
+	}
+
+	if desired.Spec.UpdateStrategy.Type != "" &&
+		!equality.Semantic.DeepEqual(live.Spec.UpdateStrategy, desired.Spec.UpdateStrategy) {
+		return true
+	}
+
+	for k, v := range desired.Spec.Template.Annotations {
+		if lv, ok := live.Spec.Template.Annotations[k]; !ok || lv != v {
+			return true
+		}
+	}
+
+	if !equality.Semantic.DeepEqual(live.Spec.Template.Labels, desired.Spec.Template.Labels) ||
+		!equality.Semantic.DeepEqual(live.Spec.Template.Finalizers, desired.Spec.Template.Finalizers) {
+		return true
+	}
+
+	defaulted := desired.Spec.DeepCopy()
+	preserveDefaultedFields(&live.Spec, defaulted)
+
+	return !equality.Semantic.DeepEqual(live.Spec.Template.Spec, defaulted.Template.Spec)
+}
+
+// preserveDefaultedFields copies to desired the fields of live that desired leaves empty and that were defaulted by
+// the API server, so that setting the desired state of an existing DaemonSet does not reset them on every
+// reconciliation. Fields that KMM may set are only copied if live holds the server default: a value that was set by
+// KMM and is no longer desired is still reset.
+func preserveDefaultedFields(live, desired *appsv1.DaemonSetSpec) {
+	// fields never set by KMM
+	if desired.RevisionHistoryLimit == nil {
+		desired.RevisionHistoryLimit = live.RevisionHistoryLimit
+	}
+
+	lp := &live.Template.Spec
+	dp := &desired.Template.Spec
+
+	if dp.RestartPolicy == "" {
+		dp.RestartPolicy = lp.RestartPolicy
+	}
+
+	if dp.SchedulerName == "" {
+		dp.SchedulerName = lp.SchedulerName
+	}
+
+	// fields that KMM sets from the Module
+	defaultUpdateStrategy := appsv1.DaemonSetUpdateStrategy{
+		Type: appsv1.RollingUpdateDaemonSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+			MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+			MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+		},
+	}
+
+	if desired.UpdateStrategy.Type == "" && equality.Semantic.DeepEqual(live.UpdateStrategy, defaultUpdateStrategy) {
+		desired.UpdateStrategy = live.UpdateStrategy
+	}
+
+	if dp.DNSPolicy == "" && lp.DNSPolicy == v1.DNSClusterFirst {
+		dp.DNSPolicy = lp.DNSPolicy
+	}
+
+	if dp.TerminationGracePeriodSeconds == nil &&
+		lp.TerminationGracePeriodSeconds != nil &&
+		*lp.TerminationGracePeriodSeconds == defaultTerminationGracePeriodSeconds {
+		dp.TerminationGracePeriodSeconds = lp.TerminationGracePeriodSeconds
+	}
+
+	if dp.SecurityContext == nil && equality.Semantic.DeepEqual(lp.SecurityContext, &v1.PodSecurityContext{}) {
+		dp.SecurityContext = lp.SecurityContext
+	}
+
+	preserveDefaultedVolumeFields(lp.Volumes, dp.Volumes)
+	preserveDefaultedContainerFields(lp.InitContainers, dp.InitContainers)
+	preserveDefaultedContainerFields(lp.Containers, dp.Containers)
+}
+
+// preserveDefaultedVolumeFields copies to each volume of desired the defaulted fields of the volume of live with the
+// same name.
+func preserveDefaultedVolumeFields(live, desired []v1.Volume) {
+	liveByName := make(map[string]*v1.Volume, len(live))
+
+	for i := range live {
+		liveByName[live[i].Name] = &live[i]
+	}
+
+	for i := range desired {
+		dv := &desired[i]
+
+		lv := liveByName[dv.Name]
+		if lv == nil {
+			continue
+		}
+
+		if dh, lh := dv.HostPath, lv.HostPath; dh != nil && lh != nil && dh.Type == nil &&
+			lh.Type != nil && *lh.Type == v1.HostPathUnset {
+			dh.Type = lh.Type
+		}
+
+		if dc, lc := dv.ConfigMap, lv.ConfigMap; dc != nil && lc != nil && dc.DefaultMode == nil &&
+			lc.DefaultMode != nil && *lc.DefaultMode == v1.ConfigMapVolumeSourceDefaultMode {
+			dc.DefaultMode = lc.DefaultMode
+		}
+
+		if ds, ls := dv.Secret, lv.Secret; ds != nil && ls != nil && ds.DefaultMode == nil &&
+			ls.DefaultMode != nil && *ls.DefaultMode == v1.SecretVolumeSourceDefaultMode {
+			ds.DefaultMode = ls.DefaultMode
+		}
+
+		if dp, lp := dv.Projected, lv.Projected; dp != nil && lp != nil && dp.DefaultMode == nil &&
+			lp.DefaultMode != nil && *lp.DefaultMode == v1.ProjectedVolumeSourceDefaultMode {
+			dp.DefaultMode = lp.DefaultMode
+		}
+	}
+}
+
+// preserveDefaultedContainerFields copies to each container of desired the defaulted fields of the container of live
+// with the same name.
+func preserveDefaultedContainerFields(live, desired []v1.Container) {
+	liveByName := make(map[string]*v1.Container, len(live))
+
+	for i := range live {
+		liveByName[live[i].Name] = &live[i]
+	}
+
+	for i := range desired {
+		dc := &desired[i]
+
+		lc := liveByName[dc.Name]
+		if lc == nil {
+			continue
+		}
+
+		if dc.TerminationMessagePath == "" {
+			dc.TerminationMessagePath = lc.TerminationMessagePath
+		}
+
+		if dc.TerminationMessagePolicy == "" {
+			dc.TerminationMessagePolicy = lc.TerminationMessagePolicy
+		}
+
+		if dc.ImagePullPolicy == "" && lc.ImagePullPolicy == defaultImagePullPolicy(dc.Image) {
+			dc.ImagePullPolicy = lc.ImagePullPolicy
+		}
+
+		preserveDefaultedProbeFields(lc.LivenessProbe, dc.LivenessProbe)
+		preserveDefaultedProbeFields(lc.ReadinessProbe, dc.ReadinessProbe)
+		preserveDefaultedProbeFields(lc.StartupProbe, dc.StartupProbe)
+	}
+}
+
+// preserveDefaultedProbeFields copies to desired the defaulted fields of live, if both are set.
+func preserveDefaultedProbeFields(live, desired *v1.Probe) {
+	if live == nil || desired == nil {
+		return
+	}
+
+	if desired.SuccessThreshold == 0 && live.SuccessThreshold == 1 {
+		desired.SuccessThreshold = live.SuccessThreshold
+	}
+}
+
+// defaultImagePullPolicy returns the pull policy that the API server sets on containers running image when they do
+// not set any: Always for the latest tag, which is implied if image has neither a tag nor a digest, and IfNotPresent
+// otherwise.
+func defaultImagePullPolicy(image string) v1.PullPolicy {
+	if tag, err := name.NewTag(image); err == nil && tag.TagStr() == "latest" {
+		return v1.PullAlways
+	}
+
+	return v1.PullIfNotPresent
+}
+
+// makeModuleLoaderTolerations returns the tolerations of spec, followed by a toleration matching all taints if
+// TolerateAllTaints is set.
+func makeModuleLoaderTolerations(spec kmmv1beta1.ModuleLoaderSpec) []v1.Toleration {
+	if !spec.TolerateAllTaints {
+		return spec.Tolerations
+	}
+
+	tolerations := make([]v1.Toleration, 0, len(spec.Tolerations)+1)
+	tolerations = append(tolerations, spec.Tolerations...)
+
+	return append(tolerations, v1.Toleration{Operator: v1.TolerationOpExists})
+}
+
+// makeAffinity returns a copy of affinity in which every required node selector term also contains requirements.
+// Terms are ORed by the scheduler, so the requirements need to be added to each of them.
+func makeAffinity(affinity *v1.Affinity, requirements ...v1.NodeSelectorRequirement) *v1.Affinity {
+	var a *v1.Affinity
+
+	if affinity != nil {
+		a = affinity.DeepCopy()
+	} else {
+		a = &v1.Affinity{}
+	}
+
+	if a.NodeAffinity == nil {
+		a.NodeAffinity = &v1.NodeAffinity{}
+	}
+
+	if a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &v1.NodeSelector{}
+	}
+
+	ns := a.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+
+	if len(ns.NodeSelectorTerms) == 0 {
+		ns.NodeSelectorTerms = []v1.NodeSelectorTerm{{}}
+	}
+
+	for i := range ns.NodeSelectorTerms {
+		ns.NodeSelectorTerms[i].MatchExpressions = append(ns.NodeSelectorTerms[i].MatchExpressions, requirements...)
+	}
+
+	return a
+}
+
+// validateSysctls returns an error if one of spec's sysctls cannot be set in the module loader pods: it must be
+// namespaced, not in a namespace shared with the host, and considered safe by Kubernetes unless unsafe sysctls are
+// allowed.
+func validateSysctls(spec kmmv1beta1.ModuleLoaderSpec) error {
+	for _, s := range spec.Sysctls {
+		if !sysctlNameRegexp.MatchString(s.Name) {
+			return fmt.Errorf("invalid sysctl name %q", s.Name)
+		}
+
+		switch {
+		case strings.HasPrefix(s.Name, "net."):
+			if spec.HostNetwork {
+				return fmt.Errorf("sysctl %s cannot be set with the host network namespace", s.Name)
+			}
+		case isIPCSysctl(s.Name):
+			if spec.HostIPC {
+				return fmt.Errorf("sysctl %s cannot be set with the host IPC namespace", s.Name)
+			}
+		default:
+			return fmt.Errorf("sysctl %s is not namespaced", s.Name)
+		}
+
+		if !spec.AllowUnsafeSysctls && !safeSysctls.Has(s.Name) {
+			return fmt.Errorf("sysctl %s is unsafe and unsafe sysctls are not allowed", s.Name)
+		}
+	}
+
+	return nil
+}
+
+// isIPCSysctl returns true if name belongs to the IPC namespace.
+func isIPCSysctl(name string) bool {
+	return name == "kernel.sem" ||
+		strings.HasPrefix(name, "kernel.shm") ||
+		strings.HasPrefix(name, "kernel.msg") ||
+		strings.HasPrefix(name, "fs.mqueue.")
+}
+
+// makeModuleLoaderPodSecurityContext returns the security context of the module loader pods, or nil if neither a
+// sysctl nor an FSGroup needs to be set.
+func makeModuleLoaderPodSecurityContext(spec kmmv1beta1.ModuleLoaderSpec) *v1.PodSecurityContext {
+	var fsGroup *int64
+
+	if spec.SecurityContext != nil && spec.SecurityContext.FSGroup != nil {
+		fsGroup = pointer.Int64(*spec.SecurityContext.FSGroup)
+	}
+
+	if len(spec.Sysctls) == 0 && fsGroup == nil {
+		return nil
+	}
+
+	return &v1.PodSecurityContext{
+		FSGroup: fsGroup,
+		Sysctls: spec.Sysctls,
+	}
+}
+
+// validateModuleLoaderSecurityContext returns an error if sc sets a negative user or group ID.
+func validateModuleLoaderSecurityContext(sc *kmmv1beta1.ModuleLoaderSecurityContext) error {
+	if sc == nil {
+		return nil
+	}
+
+	ids := []struct {
+		name string
+		id   *int64
+	}{
+		{name: "runAsUser", id: sc.RunAsUser},
+		{name: "runAsGroup", id: sc.RunAsGroup},
+		{name: "fsGroup", id: sc.FSGroup},
+	}
+
+	for _, i := range ids {
+		if i.id != nil && *i.id < 0 {
+			return fmt.Errorf("%s cannot be negative: %d", i.name, *i.id)
+		}
+	}
+
+	return nil
+}
+
+// validateResources returns an error if any limit in r is lower than the corresponding request.
+func validateResources(r v1.ResourceRequirements) error {
+	for name, request := range r.Requests {
+		limit, ok := r.Limits[name]
+		if !ok {
+			continue
+		}
+
+		if limit.Cmp(request) < 0 {
+			return fmt.Errorf("limit %s for %s is lower than request %s", limit.String(), name, request.String())
+		}
+	}
+
+	return nil
+}
+
+// validateUpdateStrategy returns an error if s cannot be used as the update strategy of a DaemonSet.
+func validateUpdateStrategy(s appsv1.DaemonSetUpdateStrategy) error {
+	switch s.Type {
+	case "", appsv1.RollingUpdateDaemonSetStrategyType:
+	case appsv1.OnDeleteDaemonSetStrategyType:
+		if s.RollingUpdate != nil {
+			return errors.New("rollingUpdate cannot be set with the OnDelete strategy")
+		}
+	default:
+		return fmt.Errorf("unknown strategy type %q", s.Type)
+	}
+
+	if s.RollingUpdate == nil || s.RollingUpdate.MaxUnavailable == nil {
+		return nil
+	}
+
+	return validateMaxUnavailable(*s.RollingUpdate.MaxUnavailable)
+}
+
+// validateTopologySpreadConstraints returns an error if the label selector of one of constraints is missing, invalid
+// or does not match podLabels, in which case the constraint would not apply to the pods it is set on.
+func validateTopologySpreadConstraints(constraints []v1.TopologySpreadConstraint, podLabels map[string]string) error {
+	for i, c := range constraints {
+		if c.LabelSelector == nil {
+			return fmt.Errorf("constraint %d: labelSelector cannot be empty", i)
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(c.LabelSelector)
+		if err != nil {
+			return fmt.Errorf("constraint %d: invalid labelSelector: %v", i, err)
+		}
+
+		if !selector.Matches(labels.Set(podLabels)) {
+			return fmt.Errorf("constraint %d: labelSelector %q does not match the pod labels", i, selector.String())
+		}
+	}
+
+	return nil
+}
+
+// validateMaxUnavailable returns an error if maxUnavailable is not a non-negative integer or a percentage between 0%
+// and 100%.
+func validateMaxUnavailable(maxUnavailable intstr.IntOrString) error {
+	if maxUnavailable.Type == intstr.String && !strings.HasSuffix(maxUnavailable.StrVal, "%") {
+		return fmt.Errorf("maxUnavailable %q is not a percentage", maxUnavailable.StrVal)
+	}
+
+	v, err := intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, 100, true)
+	if err != nil {
+		return fmt.Errorf("invalid maxUnavailable: %v", err)
+	}
+
+	if v < 0 {
+		return fmt.Errorf("maxUnavailable %s cannot be negative", maxUnavailable.String())
+	}
+
+	if maxUnavailable.Type == intstr.String && v > 100 {
+		return fmt.Errorf("maxUnavailable %s cannot exceed 100%%", maxUnavailable.String())
+	}
+
+	return nil
+}
+
+func getDriverContainerNodeLabel(prefix, moduleName string) string {
+	return fmt.Sprintf("%s/%s.ready", prefix, moduleName)
+}
+
+func getDevicePluginNodeLabel(prefix, moduleName string) string {
+	return fmt.Sprintf("%s/%s.device-plugin-ready", prefix, moduleName)
+}
+
+func IsDevicePluginKernelVersion(kernelVersion string) bool {
+	return kernelVersion == devicePluginKernelVersion
+}
+
+// MissingKernelDaemonSets returns the kernel versions in observed for which there is no module loader DaemonSet, for any
+// architecture, in existing, which is typically the output of ModuleDaemonSetsByKernelVersion.
+func MissingKernelDaemonSets(existing map[string]*appsv1.DaemonSet, observed sets.String) sets.String {
+	existingKernels := sets.NewString()
+
+	for key := range existing {
+		existingKernels.Insert(KernelVersionFromKey(key))
+	}
+
+	missing := sets.NewString()
+
+	for kernelVersion := range observed {
+		if IsDevicePluginKernelVersion(kernelVersion) {
+			continue
+		}
+
+		if !existingKernels.Has(kernelVersion) {
+			missing.Insert(kernelVersion)
+		}
+	}
+
+	return missing
+}
+
+// DaemonSetStatusByKernel returns the status of the module loader DaemonSets in existing, which is typically the output
+// of ModuleDaemonSetsByKernelVersion, indexed by kernel version. The statuses of the DaemonSets running the same kernel
+// version on different architectures are summed. The device plugin DaemonSet is ignored.
+func DaemonSetStatusByKernel(existing map[string]*appsv1.DaemonSet) map[string]DSStatus {
+	statuses := make(map[string]DSStatus, len(existing))
+
+	for key, ds := range existing {
+		if ds == nil || IsDevicePluginKernelVersion(key) {
+			continue
+		}
+
+		kernelVersion := KernelVersionFromKey(key)
+
+		st := statuses[kernelVersion]
+		st.Desired += ds.Status.DesiredNumberScheduled
+		st.Ready += ds.Status.NumberReady
+		st.Available += ds.Status.NumberAvailable
+		statuses[kernelVersion] = st
+	}
+
+	return statuses
+}
+
+func GetDevicePluginKernelVersion() string {
+	return devicePluginKernelVersion
+}
+
+var invalidLabelValueChars = regexp.MustCompile(`[^-_.A-Za-z0-9]`)
+
+// KernelLabelValue returns a representation of kernelVersion that can be used as a label value.
+// Valid label values are returned unchanged. Otherwise, invalid characters are replaced with underscores and the
+// result is truncated and suffixed with a hash of kernelVersion, so that distinct kernel versions get distinct values.
+func KernelLabelValue(kernelVersion string) string {
+	if len(validation.IsValidLabelValue(kernelVersion)) == 0 {
+		return kernelVersion
+	}
+
+	sum := sha256.Sum256([]byte(kernelVersion))
+	suffix := hex.EncodeToString(sum[:])[:kernelLabelValueHashLength]
+
+	value := invalidLabelValueChars.ReplaceAllString(kernelVersion, "_")
+
+	if maxLen := validation.LabelValueMaxLength - len(suffix) - 1; len(value) > maxLen {
+		value = value[:maxLen]
+	}
+
+	// label values must begin and end with an alphanumeric character
+	value = strings.Trim(value, "-_.")
+	if value == "" {
+		return suffix
+	}
+
+	return value + "-" + suffix
+}
+
+var invalidDNSLabelChars = regexp.MustCompile(`[^-a-z0-9]`)
+
+// DaemonSetKey returns the key of the module loader DaemonSet for kernelVersion and arch in the map returned by
+// ModuleDaemonSetsByKernelVersion: kernelVersion if arch is empty, and kernelVersion/arch otherwise.
+func DaemonSetKey(kernelVersion, arch string) string {
+	if arch == "" {
+		return kernelVersion
+	}
+
+	return kernelVersion + "/" + arch
+}
+
+// KernelVersionFromKey returns the kernel version part of key, a key returned by DaemonSetKey.
+func KernelVersionFromKey(key string) string {
+	if i := strings.LastIndex(key, "/"); i >= 0 {
+		return key[:i]
+	}
+
+	return key
+}
+
+// Arch returns the architecture that the module loader DaemonSet ds is pinned to, or an empty string.
+func Arch(ds *appsv1.DaemonSet) string {
+	if arch, ok := ds.Labels[constants.ArchLabel]; ok {
+		return arch
+	}
+
+	// DaemonSets created before the label was introduced are only pinned by their node selector
+	return ds.Spec.Template.Spec.NodeSelector[v1.LabelArchStable]
+}
+
+// DaemonSetName returns the name of the module loader DaemonSet of moduleName for kernelVersion and arch, which may be
+// empty.
+// The result is a valid DNS-1123 label that only depends on its arguments: they are lowercased, invalid characters are
+// replaced with dashes, and the result is truncated and suffixed with a hash of the arguments, so that distinct
+// (moduleName, kernelVersion, arch) tuples get distinct names.
+func DaemonSetName(moduleName, kernelVersion, arch string) string {
+	key := DaemonSetKey(kernelVersion, arch)
+
+	sum := sha256.Sum256([]byte(moduleName + "/" + key))
+	suffix := hex.EncodeToString(sum[:])[:kernelLabelValueHashLength]
+
+	name := invalidDNSLabelChars.ReplaceAllString(strings.ToLower(moduleName+"-"+key), "-")
+
+	if maxLen := validation.DNS1123LabelMaxLength - len(suffix) - 1; len(name) > maxLen {
+		name = name[:maxLen]
+	}
+
+	// DNS labels must begin and end with an alphanumeric character
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return suffix
+	}
+
+	return name + "-" + suffix
+}
+
+// makeReadinessProbe returns a probe that succeeds once all kernelModuleNames are loaded, or nil if spec is nil.
+func makeReadinessProbe(spec *kmmv1beta1.ReadinessProbeSpec, kernelModuleNames []string) *v1.Probe {
+	if spec == nil {
+		return nil
+	}
+
+	return makeModuleLoadedProbe(spec.PeriodSeconds, spec.TimeoutSeconds, spec.FailureThreshold, kernelModuleNames)
+}
+
+// makeLivenessProbe returns a probe that fails if any of kernelModuleNames is not loaded, or nil if spec is nil.
+func makeLivenessProbe(spec *kmmv1beta1.LivenessProbeSpec, kernelModuleNames []string) *v1.Probe {
+	if spec == nil {
+		return nil
+	}
+
+	return makeModuleLoadedProbe(spec.PeriodSeconds, spec.TimeoutSeconds, spec.FailureThreshold, kernelModuleNames)
+}
+
+// makeStartupProbe returns a probe that holds off the other probes until all kernelModuleNames are loaded, or nil if
+// spec is nil. A non-empty spec.Command replaces the default module check.
+func makeStartupProbe(spec *kmmv1beta1.StartupProbeSpec, kernelModuleNames []string) *v1.Probe {
+	if spec == nil {
+		return nil
+	}
+
+	probe := makeModuleLoadedProbe(spec.PeriodSeconds, spec.TimeoutSeconds, spec.FailureThreshold, kernelModuleNames)
+	probe.InitialDelaySeconds = spec.InitialDelaySeconds
+
+	if len(spec.Command) > 0 {
+		probe.Exec.Command = spec.Command
+	}
+
+	return probe
+}
+
+// makeModuleLoadedProbe returns a probe checking that all kernelModuleNames are loaded.
+// Zero values are replaced with the defaults.
+func makeModuleLoadedProbe(periodSeconds, timeoutSeconds, failureThreshold int32, kernelModuleNames []string) *v1.Probe {
+	command := []string{"test"}
+
+	for i, n := range kernelModuleNames {
+		if i > 0 {
+			command = append(command, "-a")
+		}
+
+		// the kernel replaces dashes with underscores in the names of loaded modules
+		command = append(command, "-d", "/sys/module/"+strings.ReplaceAll(n, "-", "_"))
+	}
+
+	probe := v1.Probe{
+		ProbeHandler: v1.ProbeHandler{
+			Exec: &v1.ExecAction{
+				Command: command,
+			},
+		},
+		PeriodSeconds:    periodSeconds,
+		TimeoutSeconds:   timeoutSeconds,
+		FailureThreshold: failureThreshold,
+	}
+
+	if probe.PeriodSeconds == 0 {
+		probe.PeriodSeconds = defaultProbePeriodSeconds
+	}
+
+	if probe.TimeoutSeconds == 0 {
+		probe.TimeoutSeconds = defaultProbeTimeoutSeconds
+	}
+
+	if probe.FailureThreshold == 0 {
+		probe.FailureThreshold = defaultProbeFailureThreshold
+	}
+
+	return &probe
+}
+
+// makeDevicePluginSecurityContext returns a copy of sc.
+// The container is privileged unless sc explicitly sets Privileged or Capabilities.
+func makeDevicePluginSecurityContext(sc *v1.SecurityContext) *v1.SecurityContext {
+	if sc == nil {
+		return &v1.SecurityContext{Privileged: pointer.Bool(true)}
+	}
+
+	res := sc.DeepCopy()
+
+	if res.Privileged == nil && res.Capabilities == nil {
+		res.Privileged = pointer.Bool(true)
+	}
+
+	return res
+}
+
+func makeSELinuxOptions(seLinuxType *string) *v1.SELinuxOptions {
+	if seLinuxType == nil {
+		return &v1.SELinuxOptions{Type: defaultSELinuxType}
+	}
+
+	if *seLinuxType == "" {
+		return nil
+	}
+
+	return &v1.SELinuxOptions{Type: *seLinuxType}
+}
+
+func getPriorityClassName(priorityClassName string) string {
+	if priorityClassName == "" {
+		return defaultPriorityClassName
+	}
+
+	return priorityClassName
+}
+
+// GetPodPullSecrets returns secret followed by secrets, without duplicates.
+// It returns nil if no secret is provided.
+func GetPodPullSecrets(secret *v1.LocalObjectReference, secrets ...v1.LocalObjectReference) []v1.LocalObjectReference {
+	all := secrets
+
+	if secret != nil {
+		all = append([]v1.LocalObjectReference{*secret}, secrets...)
+	}
+
+	if len(all) == 0 {
+		return nil
+	}
+
+	names := sets.NewString()
+	res := make([]v1.LocalObjectReference, 0, len(all))
+
+	for _, s := range all {
+		if names.Has(s.Name) {
+			continue
+		}
+
+		names.Insert(s.Name)
+		res = append(res, s)
+	}
+
+	return res
+}
+
+// OverrideLabels returns a new map containing labels, with the values in overrides taking precedence.
+// Neither labels nor overrides are modified.
+func OverrideLabels(labels, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(overrides))
+
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	for k, v := range overrides {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+// ValidateModprobeSpec returns an error if spec would result in an invalid load or unload command.
+func ValidateModprobeSpec(spec kmmv1beta1.ModprobeSpec) error {
+	if sh := spec.ShellPath; sh != "" && !path.IsAbs(sh) {
+		return fmt.Errorf("shell path %q is not absolute", sh)
+	}
+
+	if err := validateRawArgs(spec); err != nil {
+		return err
+	}
+
+	if ra := spec.RawArgs; ra != nil && len(ra.Load) > 0 {
+		return nil
+	}
+
+	if spec.UseInsmod && (spec.ForceVermagic || spec.ForceModversion) {
+		return errors.New("forceVermagic and forceModversion cannot be used with useInsmod")
+	}
+
+	if spec.ModuleName == "" {
+		return errors.New("moduleName cannot be empty")
+	}
+
+	names := append([]string{spec.ModuleName}, spec.ModulesLoadingOrder...)
+
+	if spec.InTreeModuleToRemove != "" {
+		names = append(names, spec.InTreeModuleToRemove)
+	}
+
+	for _, n := range names {
+		if !moduleNameRegexp.MatchString(n) {
+			return fmt.Errorf("invalid module name %q: only alphanumeric characters, '-' and '_' are allowed", n)
+		}
+	}
+
+	if lo := spec.ModulesLoadingOrder; len(lo) > 0 && !sets.NewString(lo...).Has(spec.ModuleName) {
+		return fmt.Errorf("moduleName %q must be part of modulesLoadingOrder", spec.ModuleName)
+	}
+
+	if spec.UseInsmod && spec.ModulePath == "" {
+		return errors.New("modulePath cannot be empty when useInsmod is set")
+	}
+
+	// insmod only loads the single file at ModulePath
+	if spec.UseInsmod && len(spec.ModulesLoadingOrder) > 0 {
+		return errors.New("modulesLoadingOrder cannot be used with useInsmod")
+	}
+
+	for _, c := range spec.PreLoadCommand {
+		if strings.TrimSpace(c) == "" {
+			return errors.New("preLoadCommand cannot contain empty commands")
+		}
+	}
+
+	for _, c := range spec.PostLoadVerify {
+		if strings.TrimSpace(c) == "" {
+			return errors.New("postLoadVerify cannot contain empty commands")
+		}
+	}
+
+	for _, c := range spec.PostUnloadCommand {
+		if strings.TrimSpace(c) == "" {
+			return errors.New("postUnloadCommand cannot contain empty commands")
+		}
+	}
+
+	for _, c := range spec.PreUnloadCommand {
+		if strings.TrimSpace(c) == "" {
+			return errors.New("preUnloadCommand cannot contain empty commands")
+		}
+	}
+
+	if fw := spec.FirmwarePath; fw != "" && !path.IsAbs(fw) {
+		return fmt.Errorf("firmware path %q is not absolute", fw)
+	}
+
+	switch spec.FirmwareSource {
+	case "", kmmv1beta1.FirmwareSourceDirectory:
+	case kmmv1beta1.FirmwareSourceTarball:
+		if spec.FirmwarePath == "" {
+			return errors.New("firmwarePath cannot be empty when firmwareSource is Tarball")
+		}
+	default:
+		return fmt.Errorf("invalid firmware source %q", spec.FirmwareSource)
+	}
+
+	if fw := spec.FirmwareHostPath; fw != "" && !path.IsAbs(fw) {
+		return fmt.Errorf("firmware host path %q is not absolute", fw)
+	}
+
+	if d := spec.FirmwareSubDir; d != "" {
+		if path.IsAbs(d) {
+			return fmt.Errorf("firmware subdirectory %q is not relative", d)
+		}
+
+		if c := path.Clean(d); c == "." || c == ".." || strings.HasPrefix(c, "../") {
+			return fmt.Errorf("firmware subdirectory %q must be a subdirectory of the firmware host path", d)
+		}
+	}
+
+	return nil
+}
+
+func MakeLoadCommand(spec kmmv1beta1.ModprobeSpec, modName, kernelVersion string) []string {
+	if spec.ExecForm && !loadNeedsShell(spec) {
+		return makeExecLoadCommand(spec)
+	}
+
+	loadCommandShell := []string{
+		getShellPath(spec),
+		"-c",
+	}
+
+	if ra := spec.RawArgs; ra != nil && len(ra.Load) > 0 {
+		loadCommand := fmt.Sprintf("%s%s %s", getLoadTimeoutPrefix(spec), getModprobePath(spec), shellQuoteAll(ra.Load))
+		return append(loadCommandShell, loadCommand)
+	}
+
+	var loadCommand string
+
+	if spec.UseInsmod {
+		loadCommand = makeInsmodLoadCommand(spec)
+	} else {
+		loadCommand = makeModprobeLoadCommand(spec, kernelVersion)
+	}
+
+	if verify := spec.PostLoadVerify; len(verify) > 0 {
+		loadCommand = fmt.Sprintf("%s && %s", loadCommand, strings.Join(verify, " && "))
+	}
+
+	if pre := spec.PreLoadCommand; len(pre) > 0 {
+		loadCommand = fmt.Sprintf("%s && %s", strings.Join(pre, " && "), loadCommand)
+	}
+
+	// tarballs are extracted by an init container
+	if fw := spec.FirmwarePath; fw != "" && !isFirmwareTarball(spec) {
+		hostDir := getFirmwareDir(spec, modName, kernelVersion)
+		copyCommand := fmt.Sprintf("cp -r %s %s", shellQuote(fw), shellQuote(hostDir))
+
+		// create the kernel version directory upfront, so that cp copies the firmware(s) into it on every start
+		// instead of only on the first one
+		if spec.FirmwareVersionScoped {
+			copyCommand = fmt.Sprintf("mkdir -p %s && %s", shellQuote(hostDir), copyCommand)
+		}
+
+		if spec.FirmwareDecompress {
+			copyCommand = fmt.Sprintf("%s && %s", copyCommand, makeFirmwareDecompressCommand(hostDir))
+		}
+
+		loadCommand = fmt.Sprintf("%s && %s", copyCommand, loadCommand)
+	}
+
+	if inTree := spec.InTreeModuleToRemove; inTree != "" {
+		loadCommand = fmt.Sprintf("(%s -rv %s || true) && %s", getModprobePath(spec), shellQuote(inTree), loadCommand)
+	}
+
+	return append(loadCommandShell, loadCommand)
+}
+
+func MakeUnloadCommand(spec kmmv1beta1.ModprobeSpec, modName, kernelVersion string) []string {
+	unloadCommandShell := []string{
+		getShellPath(spec),
+		"-c",
+	}
+
+	var preUnloadCommands []string
+
+	if gp := spec.UnloadGracePeriodSeconds; gp != nil && *gp > 0 {
+		preUnloadCommands = append(preUnloadCommands, fmt.Sprintf("sleep %d", *gp))
+	}
+
+	preUnloadCommands = append(preUnloadCommands, spec.PreUnloadCommand...)
+
+	if ra := spec.RawArgs; ra != nil && len(ra.Unload) > 0 {
+		unloadCommand := fmt.Sprintf("%s %s", getModprobePath(spec), shellQuoteAll(ra.Unload))
+		return append(unloadCommandShell, strings.Join(append(preUnloadCommands, unloadCommand), " && "))
+	}
+
+	var unloadCommand string
+
+	if spec.UseInsmod {
+		unloadCommand = fmt.Sprintf("rmmod %s", shellQuote(spec.ModuleName))
+	} else {
+		unloadCommand = makeModprobeUnloadCommand(spec)
+	}
+
+	unloadCommand = strings.Join(append(preUnloadCommands, unloadCommand), " && ")
+
+	if post := spec.PostUnloadCommand; len(post) > 0 {
+		unloadCommand = fmt.Sprintf("%s && %s", unloadCommand, strings.Join(post, " && "))
+	}
+
+	if inTree := spec.InTreeModuleToRemove; inTree != "" {
+		unloadCommand = fmt.Sprintf("%s && (%s -v %s || true)", unloadCommand, getModprobePath(spec), shellQuote(inTree))
+	}
+
+	// the pod is going away, for instance because the Module was deleted: remove the firmware even if the module
+	// could not be unloaded, but keep reporting the failure
+	if fw := spec.FirmwarePath; fw != "" && !spec.RetainFirmwareOnUnload {
+		firmwareDir := getFirmwareDir(spec, modName, kernelVersion)
+		unloadCommand = fmt.Sprintf("%s; ret=$?; rm -rf %s", unloadCommand, shellQuote(firmwareDir))
+
+		// only remove the module's directory once no kernel version uses it anymore
+		if spec.FirmwareVersionScoped {
+			unloadCommand = fmt.Sprintf("%s; rmdir %s 2>/dev/null", unloadCommand, shellQuote(path.Dir(firmwareDir)))
+		}
+
+		unloadCommand += "; exit $ret"
+	}
+
+	return append(unloadCommandShell, unloadCommand)
+}
+
+// validateRawArgs returns a *RawArgsConflictError if spec sets raw load or unload arguments together with the
+// structured fields that MakeLoadCommand or MakeUnloadCommand would then ignore.
+func validateRawArgs(spec kmmv1beta1.ModprobeSpec) error {
+	ra := spec.RawArgs
+	if ra == nil {
+		return nil
+	}
+
+	// DirName is defaulted by the API server
+	customDirName := spec.DirName != "" && spec.DirName != defaultDirName
+
+	if len(ra.Load) > 0 {
+		conflicts := []optionalField{
+			{"args.load", spec.Args != nil && len(spec.Args.Load) > 0},
+			{"dirName", customDirName},
+			{"parameters", len(spec.Parameters) > 0},
+			{"modulesLoadingOrder", len(spec.ModulesLoadingOrder) > 0},
+			{"firmwarePath", spec.FirmwarePath != ""},
+			{"runDepmod", spec.RunDepmod},
+			{"force", spec.Force},
+			{"forceVermagic", spec.ForceVermagic},
+			{"forceModversion", spec.ForceModversion},
+			{"useInsmod", spec.UseInsmod},
+			{"modulePath", spec.ModulePath != ""},
+			{"inTreeModuleToRemove", spec.InTreeModuleToRemove != ""},
+			{"preLoadCommand", len(spec.PreLoadCommand) > 0},
+			{"postLoadVerify", len(spec.PostLoadVerify) > 0},
+		}
+
+		if fields := setFields(conflicts); len(fields) > 0 {
+			return &RawArgsConflictError{Phase: "load", Fields: fields}
+		}
+	}
+
+	if len(ra.Unload) > 0 {
+		conflicts := []optionalField{
+			{"args.unload", spec.Args != nil && len(spec.Args.Unload) > 0},
+			{"dirName", customDirName},
+			{"unloadParameters", len(spec.UnloadParameters) > 0},
+			{"modulesLoadingOrder", len(spec.ModulesLoadingOrder) > 0},
+			{"firmwarePath", spec.FirmwarePath != ""},
+			{"useInsmod", spec.UseInsmod},
+			{"inTreeModuleToRemove", spec.InTreeModuleToRemove != ""},
+			{"postUnloadCommand", len(spec.PostUnloadCommand) > 0},
+		}
+
+		if fields := setFields(conflicts); len(fields) > 0 {
+			return &RawArgsConflictError{Phase: "unload", Fields: fields}
+		}
+	}
+
+	return nil
+}
+
+// optionalField is a ModprobeSpec field and whether it is set.
+type optionalField struct {
+	field string
+	set   bool
+}
+
+// setFields returns the names of the fields in fields that are set.
+func setFields(fields []optionalField) []string {
+	names := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		if f.set {
+			names = append(names, f.field)
+		}
+	}
+
+	return names
+}
+
+// loadNeedsShell returns true if the load command of spec chains several commands, and must therefore be run by a
+// shell.
+func loadNeedsShell(spec kmmv1beta1.ModprobeSpec) bool {
+	if ra := spec.RawArgs; ra != nil && len(ra.Load) > 0 {
+		return false
+	}
+
+	return (spec.FirmwarePath != "" && !isFirmwareTarball(spec)) ||
+		len(spec.PreLoadCommand) > 0 ||
+		len(spec.PostLoadVerify) > 0 ||
+		spec.InTreeModuleToRemove != "" ||
+		(spec.RunDepmod && !spec.UseInsmod) ||
+		(!spec.UseInsmod && len(getModulesLoadingOrder(spec)) > 1)
+}
+
+func isFirmwareTarball(spec kmmv1beta1.ModprobeSpec) bool {
+	return spec.FirmwareSource == kmmv1beta1.FirmwareSourceTarball
+}
+
+// makeFirmwareDecompressCommand returns the shell command decompressing the .zst and .xz files under dir.
+// The decompressed files are overwritten, as they are left over from a previous pod when the compressed files are
+// copied again. find only reports the failures of the decompression with -exec ... +.
+func makeFirmwareDecompressCommand(dir string) string {
+	quoted := shellQuote(dir)
+
+	return fmt.Sprintf(
+		"find %s -name '*.zst' -exec zstd -d -q -f --rm {} + && find %s -name '*.xz' -exec unxz -f {} +",
+		quoted,
+		quoted,
+	)
+}
+
+// staleFirmwareHostPath returns the host firmware directory that live, the module loader DaemonSet of modName as it
+// exists in the cluster, mounted or was already cleaning up, if spec does not copy firmware to that directory anymore.
+// It returns an empty string if live never used firmware, still uses the same directory, or already removed the
+// directory on all its nodes.
+// The directory is otherwise only removed by the unload command of the pods, which keeps it when
+// RetainFirmwareOnUnload is set.
+func staleFirmwareHostPath(live *appsv1.DaemonSet, spec kmmv1beta1.ModprobeSpec, modName string) string {
+	desired := ""
+
+	if spec.FirmwarePath != "" {
+		desired = getModuleFirmwareHostPath(spec, modName)
+	}
+
+	for _, vol := range live.Spec.Template.Spec.Volumes {
+		if vol.Name == nodeVarLibFirmwareVolumeName && vol.HostPath != nil {
+			if vol.HostPath.Path == desired {
+				return ""
+			}
+
+			return vol.HostPath.Path
+		}
+	}
+
+	stale := live.Annotations[constants.StaleFirmwarePathAnnotation]
+
+	// Once all pods ran the cleanup init container, the directory is gone from all nodes and the annotation can be
+	// dropped. This triggers one last rollout that removes the init container.
+	if stale == desired || rolloutComplete(live) {
+		return ""
+	}
+
+	return stale
+}
+
+// rolloutComplete returns true if all pods of ds run its current template and are available.
+func rolloutComplete(ds *appsv1.DaemonSet) bool {
+	st := ds.Status
+
+	return st.ObservedGeneration >= ds.Generation &&
+		st.UpdatedNumberScheduled == st.DesiredNumberScheduled &&
+		st.NumberAvailable == st.DesiredNumberScheduled
+}
+
+// makeFirmwareCleanupCommand returns the command of the init container that removes the stale firmware directory
+// dir from the host.
+func makeFirmwareCleanupCommand(dir string) []string {
+	return []string{"rm", "-rf", dir}
+}
+
+// makeFirmwareExtractCommand returns the command of the init container that extracts the firmware tarball of spec to
+// the host firmware directory of modName for kernelVersion.
+func makeFirmwareExtractCommand(spec kmmv1beta1.ModprobeSpec, modName, kernelVersion string) []string {
+	hostDir := getFirmwareDir(spec, modName, kernelVersion)
+
+	quotedDir := shellQuote(hostDir)
+	extractCommand := fmt.Sprintf("mkdir -p %s && tar -xf %s -C %s", quotedDir, shellQuote(spec.FirmwarePath), quotedDir)
+
+	if spec.FirmwareDecompress {
+		extractCommand = fmt.Sprintf("%s && %s", extractCommand, makeFirmwareDecompressCommand(hostDir))
+	}
+
+	return []string{getShellPath(spec), "-c", extractCommand}
+}
+
+// validateProbes returns an error if spec enables a probe checking that the kernel module is loaded without setting
+// Modprobe.ModuleName, which raw load arguments do not require.
+// A startup probe with its own command does not check the kernel module.
+func validateProbes(spec kmmv1beta1.ModuleLoaderContainerSpec) error {
+	if spec.Modprobe.ModuleName != "" {
+		return nil
+	}
+
+	if spec.ReadinessProbe != nil {
+		return errors.New("readinessProbe requires modprobe.moduleName")
+	}
+
+	if spec.LivenessProbe != nil {
+		return errors.New("livenessProbe requires modprobe.moduleName")
+	}
+
+	if sp := spec.StartupProbe; sp != nil && len(sp.Command) == 0 {
+		return errors.New("startupProbe requires modprobe.moduleName or a command")
+	}
+
+	return nil
+}
+
+// validateHostDevPaths returns an error if the host /dev paths of spec are set without MountHostDev, or if they are
+// not clean absolute paths under /dev.
+func validateHostDevPaths(spec kmmv1beta1.ModuleLoaderContainerSpec) error {
+	if len(spec.HostDevPaths) > 0 && !spec.MountHostDev {
+		return errors.New("hostDevPaths requires mountHostDev")
+	}
+
+	for _, p := range spec.HostDevPaths {
+		if path.Clean(p) != p {
+			return fmt.Errorf("%q is not a clean path", p)
+		}
+
+		if !strings.HasPrefix(p, hostDevPath+"/") {
+			return fmt.Errorf("%q is not under %s", p, hostDevPath)
+		}
+	}
+
+	return nil
+}
+
+// makeHostDevMounts returns the volumes and volume mounts exposing the host /dev, or only paths if it is not empty,
+// at the same path in the module loader container.
+func makeHostDevMounts(paths []string) ([]v1.Volume, []v1.VolumeMount) {
+	if len(paths) == 0 {
+		hostPathDirectory := v1.HostPathDirectory
+
+		volume := v1.Volume{
+			Name: hostDevVolumeName,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{
+					Path: hostDevPath,
+					Type: &hostPathDirectory,
+				},
+			},
+		}
+
+		return []v1.Volume{volume}, []v1.VolumeMount{{Name: hostDevVolumeName, MountPath: hostDevPath}}
+	}
+
+	volumes := make([]v1.Volume, 0, len(paths))
+	mounts := make([]v1.VolumeMount, 0, len(paths))
+
+	for i, p := range paths {
+		volumeName := fmt.Sprintf("%s-%d", hostDevVolumeName, i)
+
+		// the paths can be device nodes as well as directories
+		volumes = append(volumes, v1.Volume{
+			Name: volumeName,
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{Path: p},
+			},
+		})
+
+		mounts = append(mounts, v1.VolumeMount{Name: volumeName, MountPath: p})
+	}
+
+	return volumes, mounts
+}
+
+// modprobeConfigHash returns a hash of spec and of the load command it produces for modName and kernelVersion.
+// It changes whenever the parameters of the module change, even if the load hook is disabled.
+func modprobeConfigHash(spec kmmv1beta1.ModprobeSpec, modName, kernelVersion string) (string, error) {
+	b, err := json.Marshal(struct {
+		Spec        kmmv1beta1.ModprobeSpec `json:"spec"`
+		LoadCommand []string                `json:"loadCommand"`
+	}{
+		Spec:        spec,
+		LoadCommand: MakeLoadCommand(spec, modName, kernelVersion),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// makeExecLoadCommand returns the argv of the load command of spec, which must not need a shell.
+func makeExecLoadCommand(spec kmmv1beta1.ModprobeSpec) []string {
+	argv := make([]string, 0)
+
+	if t := spec.LoadTimeoutSeconds; t != nil && *t > 0 {
+		argv = append(argv, "timeout", fmt.Sprintf("%ds", *t))
+	}
+
+	modprobePath := spec.ModprobePath
+	if modprobePath == "" {
+		modprobePath = defaultModprobePath
+	}
+
+	if ra := spec.RawArgs; ra != nil && len(ra.Load) > 0 {
+		return append(append(argv, modprobePath), ra.Load...)
+	}
+
+	if spec.UseInsmod {
+		argv = append(argv, "insmod")
+
+		if spec.Force {
+			argv = append(argv, "-f")
+		}
+
+		return append(append(argv, spec.ModulePath), spec.Parameters...)
+	}
+
+	argv = append(argv, modprobePath)
+
+	if a := spec.Args; a != nil && len(a.Load) > 0 {
+		argv = append(argv, a.Load...)
+	} else {
+		argv = append(argv, "-v")
+	}
+
+	argv = append(argv, getForceFlags(spec)...)
+
+	if dirName := spec.DirName; dirName != "" {
+		argv = append(argv, "-d", dirName)
+	}
+
+	// loadNeedsShell guarantees that there is a single module to load
+	m := getModulesLoadingOrder(spec)[0]
+	argv = append(argv, m)
+
+	if m == spec.ModuleName {
+		argv = append(argv, spec.Parameters...)
+	}
+
+	return argv
+}
+
+func makeInsmodLoadCommand(spec kmmv1beta1.ModprobeSpec) string {
+	loadCommand := getLoadTimeoutPrefix(spec) + "insmod"
+
+	if spec.Force {
+		loadCommand = fmt.Sprintf("%s -f", loadCommand)
+	}
+
+	loadCommand = fmt.Sprintf("%s %s", loadCommand, shellQuote(spec.ModulePath))
+
+	if p := spec.Parameters; len(p) > 0 {
+		loadCommand = fmt.Sprintf("%s %s", loadCommand, shellQuoteAll(spec.Parameters))
+	}
+
+	return loadCommand
+}
+
+func makeModprobeLoadCommand(spec kmmv1beta1.ModprobeSpec, kernelVersion string) string {
+	loadCommand := getLoadTimeoutPrefix(spec) + getModprobePath(spec)
+
+	if a := spec.Args; a != nil && len(a.Load) > 0 {
+		loadCommand = fmt.Sprintf("%s %s", loadCommand, shellQuoteAll(a.Load))
+	} else {
+		loadCommand = fmt.Sprintf("%s -v", loadCommand)
+	}
+
+	if flags := getForceFlags(spec); len(flags) > 0 {
+		loadCommand = fmt.Sprintf("%s %s", loadCommand, strings.Join(flags, " "))
+	}
+
+	if dirName := spec.DirName; dirName != "" {
+		loadCommand = fmt.Sprintf("%s -d %s", loadCommand, shellQuote(dirName))
+	}
+
+	modules := getModulesLoadingOrder(spec)
+	commands := make([]string, 0, len(modules))
+
+	for _, m := range modules {
+		command := fmt.Sprintf("%s %s", loadCommand, shellQuote(m))
+
+		if p := spec.Parameters; len(p) > 0 && m == spec.ModuleName {
+			command = fmt.Sprintf("%s %s", command, shellQuoteAll(spec.Parameters))
+		}
+
+		commands = append(commands, command)
+	}
+
+	loadCommand = strings.Join(commands, " && ")
+
+	if spec.RunDepmod {
+		depmodCommand := "depmod"
+
+		if dirName := spec.DirName; dirName != "" {
+			depmodCommand = fmt.Sprintf("%s -b %s", depmodCommand, shellQuote(dirName))
+		}
+
+		loadCommand = fmt.Sprintf("%s %s && %s", depmodCommand, shellQuote(kernelVersion), loadCommand)
+	}
+
+	return loadCommand
+}
+
+func makeModprobeUnloadCommand(spec kmmv1beta1.ModprobeSpec) string {
+	unloadCommand := getModprobePath(spec)
+
+	if a := spec.Args; a != nil && len(a.Unload) > 0 {
+		unloadCommand = fmt.Sprintf("%s %s", unloadCommand, shellQuoteAll(a.Unload))
+	} else {
+		unloadCommand = fmt.Sprintf("%s -rv", unloadCommand)
+	}
+
+	if dirName := spec.DirName; dirName != "" {
+		unloadCommand = fmt.Sprintf("%s -d %s", unloadCommand, shellQuote(dirName))
+	}
+
+	modules := getModulesLoadingOrder(spec)
+	commands := make([]string, 0, len(modules))
+
+	// unload the modules in the reverse loading order
+	for i := len(modules) - 1; i >= 0; i-- {
+		command := fmt.Sprintf("%s %s", unloadCommand, shellQuote(modules[i]))
+
+		if p := spec.UnloadParameters; len(p) > 0 && modules[i] == spec.ModuleName {
+			command = fmt.Sprintf("%s %s", command, shellQuoteAll(spec.UnloadParameters))
+		}
+
+		commands = append(commands, command)
+	}
+
+	return strings.Join(commands, " && ")
+}
+
+// shellQuote returns s quoted so that the shell interprets it as a single word, without any expansion.
+func shellQuote(s string) string {
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func shellQuoteAll(s []string) string {
+	quoted := make([]string, 0, len(s))
+
+	for _, e := range s {
+		quoted = append(quoted, shellQuote(e))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// getTerminationGracePeriodSeconds extends the default termination grace period by the unload grace period.
+func getTerminationGracePeriodSeconds(spec kmmv1beta1.ModprobeSpec) *int64 {
+	if gp := spec.UnloadGracePeriodSeconds; gp != nil && *gp > 0 {
+		return pointer.Int64(defaultTerminationGracePeriodSeconds + *gp)
+	}
+
+	return nil
+}
+
+// getForceFlags returns the modprobe flags corresponding to the force options of spec.
+// --force implies both --force-vermagic and --force-modversion.
+func getForceFlags(spec kmmv1beta1.ModprobeSpec) []string {
+	if spec.Force {
+		return []string{"--force"}
+	}
+
+	flags := make([]string, 0, 2)
+
+	if spec.ForceVermagic {
+		flags = append(flags, "--force-vermagic")
+	}
+
+	if spec.ForceModversion {
+		flags = append(flags, "--force-modversion")
+	}
+
+	return flags
+}
+
+// getLoadTimeoutPrefix returns the timeout invocation that should prefix the load commands, if any.
+func getLoadTimeoutPrefix(spec kmmv1beta1.ModprobeSpec) string {
+	if t := spec.LoadTimeoutSeconds; t != nil && *t > 0 {
+		return fmt.Sprintf("timeout %ds ", *t)
+	}
+
+	return ""
+}
+
+func getShellPath(spec kmmv1beta1.ModprobeSpec) string {
+	if spec.ShellPath == "" {
+		return defaultShellPath
+	}
+
+	return spec.ShellPath
+}
+
+func getModprobePath(spec kmmv1beta1.ModprobeSpec) string {
+	if spec.ModprobePath == "" {
+		return defaultModprobePath
+	}
+
+	return shellQuote(spec.ModprobePath)
+}
+
+// getModuleFirmwareHostPath returns the directory on the host under which the firmware(s) of modName are copied,
+// for all kernel versions: FirmwareSubDir, or modName by default, under the firmware host path.
+func getModuleFirmwareHostPath(spec kmmv1beta1.ModprobeSpec, modName string) string {
+	subDir := modName
+
+	if spec.FirmwareSubDir != "" {
+		subDir = path.Clean(spec.FirmwareSubDir)
+	}
+
+	return fmt.Sprintf("%s/%s", getFirmwareHostPath(spec), subDir)
+}
+
+// getFirmwareDir returns the directory on the host to which the firmware(s) of modName are copied.
+func getFirmwareDir(spec kmmv1beta1.ModprobeSpec, modName, kernelVersion string) string {
+	dir := getModuleFirmwareHostPath(spec, modName)
+
+	if spec.FirmwareVersionScoped {
+		dir = fmt.Sprintf("%s/%s", dir, kernelVersion)
+	}
+
+	return dir
+}
+
+func getFirmwareHostPath(spec kmmv1beta1.ModprobeSpec) string {
+	if spec.FirmwareHostPath == "" {
+		return nodeVarLibFirmwarePath
+	}
+
+	return spec.FirmwareHostPath
+}
+
+func getModulesLoadingOrder(spec kmmv1beta1.ModprobeSpec) []string {
+	if len(spec.ModulesLoadingOrder) == 0 {
+		return []string{spec.ModuleName}
+	}
+
+	return spec.ModulesLoadingOrder
 }
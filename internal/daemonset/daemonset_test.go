@@ -4,7 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr/funcr"
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
@@ -12,12 +19,23 @@ import (
 	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/utils/pointer"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
@@ -43,7 +61,7 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 
 	It("should return an error if the DaemonSet is nil", func() {
 		Expect(
-			dg.SetDriverContainerAsDesired(context.Background(), nil, "", kmmv1beta1.Module{}, ""),
+			dg.SetDriverContainerAsDesired(context.Background(), nil, "", kmmv1beta1.Module{}, "", ""),
 		).To(
 			HaveOccurred(),
 		)
@@ -51,7 +69,7 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 
 	It("should return an error if the image is empty", func() {
 		Expect(
-			dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "", kmmv1beta1.Module{}, ""),
+			dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "", kmmv1beta1.Module{}, "", ""),
 		).To(
 			HaveOccurred(),
 		)
@@ -59,7 +77,7 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 
 	It("should return an error if the kernel version is empty", func() {
 		Expect(
-			dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "", kmmv1beta1.Module{}, ""),
+			dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "", kmmv1beta1.Module{}, "", ""),
 		).To(
 			HaveOccurred(),
 		)
@@ -68,13 +86,18 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 	It("should not add a device-plugin container if it is not set in the spec", func() {
 		mod := kmmv1beta1.Module{
 			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
 				Selector: map[string]string{"has-feature-x": "true"},
 			},
 		}
 
 		ds := appsv1.DaemonSet{}
 
-		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion)
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(ds.Spec.Template.Spec.Containers).To(HaveLen(1))
 		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(2))
@@ -106,6 +129,7 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 					Container: kmmv1beta1.ModuleLoaderContainerSpec{
 						Modprobe: kmmv1beta1.ModprobeSpec{
 							FirmwarePath: "/opt/lib/firmware/example",
+							ModuleName:   "some-kmod",
 						},
 					},
 				},
@@ -114,7 +138,7 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 
 		ds := appsv1.DaemonSet{}
 
-		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion)
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
 		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(3))
 		Expect(ds.Spec.Template.Spec.Volumes[2]).To(Equal(vol))
@@ -123,876 +147,7803 @@ var _ = Describe("SetDriverContainerAsDesired", func() {
 
 	})
 
-	It("should work as expected", func() {
-		const (
-			moduleLoaderImage   = "driver-image"
-			dsName              = "ds-name"
-			imageRepoSecretName = "image-repo-secret"
-			serviceAccountName  = "driver-service-account"
-		)
-
+	It("should add read-only volumes and volume mounts for the extra modules host paths", func() {
 		mod := kmmv1beta1.Module{
-			TypeMeta: metav1.TypeMeta{
-				APIVersion: kmmv1beta1.GroupVersion.String(),
-				Kind:       "Module",
-			},
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      moduleName,
-				Namespace: namespace,
+				Name: moduleName,
 			},
 			Spec: kmmv1beta1.ModuleSpec{
 				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
 					Container: kmmv1beta1.ModuleLoaderContainerSpec{
 						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
 					},
-					ServiceAccountName: serviceAccountName,
+					ExtraModulesHostPaths: []string{"/opt/lib/modules", "/var/lib/modules"},
 				},
-				ImageRepoSecret: &v1.LocalObjectReference{Name: imageRepoSecretName},
-				Selector:        map[string]string{"has-feature-x": "true"},
 			},
 		}
 
-		ds := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      dsName,
-				Namespace: namespace,
-			},
-		}
+		ds := appsv1.DaemonSet{}
 
-		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, moduleLoaderImage, mod, kernelVersion)
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
 
-		podLabels := map[string]string{
-			constants.ModuleNameLabel: moduleName,
-			kernelLabel:               kernelVersion,
-			constants.DaemonSetRole:   "module-loader",
-		}
-
 		directory := v1.HostPathDirectory
 
-		expected := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      dsName,
-				Namespace: namespace,
-				Labels:    podLabels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         mod.APIVersion,
-						BlockOwnerDeletion: pointer.Bool(true),
-						Controller:         pointer.Bool(true),
-						Kind:               mod.Kind,
-						Name:               moduleName,
-						UID:                mod.UID,
-					},
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(4))
+		Expect(ds.Spec.Template.Spec.Volumes[0].Name).To(Equal("node-lib-modules"))
+		Expect(ds.Spec.Template.Spec.Volumes[1].Name).To(Equal("node-usr-lib-modules"))
+		Expect(ds.Spec.Template.Spec.Volumes[2:]).To(Equal([]v1.Volume{
+			{
+				Name: "extra-modules-0",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: "/opt/lib/modules", Type: &directory},
 				},
 			},
-			Spec: appsv1.DaemonSetSpec{
-				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
-				Template: v1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Finalizers: []string{constants.NodeLabelerFinalizer},
-						Labels:     podLabels,
-					},
-					Spec: v1.PodSpec{
-						Containers: []v1.Container{
-							{
-								Name:  "module-loader",
-								Image: moduleLoaderImage,
-								Lifecycle: &v1.Lifecycle{
-									PostStart: &v1.LifecycleHandler{
-										Exec: &v1.ExecAction{
-											Command: MakeLoadCommand(mod.Spec.ModuleLoader.Container.Modprobe, moduleName),
-										},
-									},
-									PreStop: &v1.LifecycleHandler{
-										Exec: &v1.ExecAction{
-											Command: MakeUnloadCommand(mod.Spec.ModuleLoader.Container.Modprobe, moduleName),
-										},
-									},
-								},
-								Command: []string{"sleep", "infinity"},
-								VolumeMounts: []v1.VolumeMount{
-									{
-										Name:      "node-lib-modules",
-										ReadOnly:  true,
-										MountPath: "/lib/modules",
-									},
-									{
-										Name:      "node-usr-lib-modules",
-										ReadOnly:  true,
-										MountPath: "/usr/lib/modules",
-									},
-								},
-								SecurityContext: &v1.SecurityContext{
-									AllowPrivilegeEscalation: pointer.Bool(false),
-									Capabilities: &v1.Capabilities{
-										Add: []v1.Capability{"SYS_MODULE"},
-									},
-									RunAsUser: pointer.Int64(0),
-									SELinuxOptions: &v1.SELinuxOptions{
-										Type: "spc_t",
-									},
-								},
-							},
-						},
-						ImagePullSecrets: []v1.LocalObjectReference{
-							{Name: imageRepoSecretName},
-						},
-						NodeSelector: map[string]string{
-							"has-feature-x": "true",
-							kernelLabel:     kernelVersion,
-						},
-						PriorityClassName:  "system-node-critical",
-						ServiceAccountName: serviceAccountName,
-						Volumes: []v1.Volume{
-							{
-								Name: "node-lib-modules",
-								VolumeSource: v1.VolumeSource{
-									HostPath: &v1.HostPathVolumeSource{
-										Path: "/lib/modules",
-										Type: &directory,
-									},
-								},
-							},
-							{
-								Name: "node-usr-lib-modules",
-								VolumeSource: v1.VolumeSource{
-									HostPath: &v1.HostPathVolumeSource{
-										Path: "/usr/lib/modules",
-										Type: &directory,
-									},
-								},
-							},
-						},
-					},
+			{
+				Name: "extra-modules-1",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: "/var/lib/modules", Type: &directory},
 				},
 			},
-		}
-
-		Expect(
-			cmp.Equal(expected, ds),
-		).To(
-			BeTrue(), cmp.Diff(expected, ds),
-		)
+		}))
+
+		volumeMounts := ds.Spec.Template.Spec.Containers[0].VolumeMounts
+		Expect(volumeMounts).To(HaveLen(4))
+		Expect(volumeMounts[2:]).To(Equal([]v1.VolumeMount{
+			{Name: "extra-modules-0", ReadOnly: true, MountPath: "/opt/lib/modules"},
+			{Name: "extra-modules-1", ReadOnly: true, MountPath: "/var/lib/modules"},
+		}))
 	})
 
-	Describe("ModuleDaemonSetsByKernelVersion", func() {
-		It("should return an empty map if no DaemonSets are present", func() {
-			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any())
+	It("should return an error if an extra modules host path is not absolute", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: moduleName,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					ExtraModulesHostPaths: []string{"opt/lib/modules"},
+				},
+			},
+		}
 
-			dc := NewCreator(clnt, kernelLabel, scheme)
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
 
+	DescribeTable("should mount the modprobe ConfigMap only if set",
+		func(cm *v1.LocalObjectReference, expectedVolumes []v1.Volume, expectedMounts []v1.VolumeMount) {
 			mod := kmmv1beta1.Module{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      moduleName,
-					Namespace: namespace,
+					Name: moduleName,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						ModprobeConfigMap: cm,
+					},
 				},
 			}
 
-			m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), mod.Name, mod.Namespace)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(m).To(BeEmpty())
-		})
+			ds := appsv1.DaemonSet{}
 
-		It("should return an error if two DaemonSets are present for the same kernel", func() {
-			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Volumes[2:]).To(Equal(expectedVolumes))
+			Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[2:]).To(Equal(expectedMounts))
+		},
+		Entry("unset", nil, []v1.Volume{}, []v1.VolumeMount{}),
+		Entry(
+			"set",
+			&v1.LocalObjectReference{Name: "some-kmod-options"},
+			[]v1.Volume{
+				{
+					Name: "modprobe-config",
+					VolumeSource: v1.VolumeSource{
+						ConfigMap: &v1.ConfigMapVolumeSource{
+							LocalObjectReference: v1.LocalObjectReference{Name: "some-kmod-options"},
+						},
+					},
+				},
+			},
+			[]v1.VolumeMount{{Name: "modprobe-config", ReadOnly: true, MountPath: "/etc/modprobe.d"}},
+		),
+	)
 
-			dc := NewCreator(clnt, kernelLabel, scheme)
-			mod := kmmv1beta1.Module{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      moduleName,
-					Namespace: namespace,
+	It("should return an error if the modprobe ConfigMap has no name", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: moduleName,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					ModprobeConfigMap: &v1.LocalObjectReference{},
 				},
-			}
+			},
+		}
 
-			_, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), mod.Name, mod.Namespace)
-			Expect(err).To(HaveOccurred())
-		})
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
 
-		It("should return a map if two DaemonSets are present for different kernels", func() {
-			const otherKernelVersion = "4.5.6"
+	hostPathDirectory := v1.HostPathDirectory
 
-			ds1 := appsv1.DaemonSet{
+	DescribeTable("should mount the host /dev only if enabled",
+		func(mountHostDev bool, hostDevPaths []string, expectedVolumes []v1.Volume, expectedMounts []v1.VolumeMount) {
+			mod := kmmv1beta1.Module{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      "ds1",
-					Namespace: namespace,
-					Labels: map[string]string{
-						"kmm.node.kubernetes.io/module.name": moduleName,
-						kernelLabel:                          kernelVersion,
+					Name: moduleName,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							HostDevPaths: hostDevPaths,
+							Modprobe:     kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+							MountHostDev: mountHostDev,
+						},
 					},
 				},
 			}
 
-			ds2 := appsv1.DaemonSet{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "ds2",
-					Namespace: namespace,
-					Labels: map[string]string{
-						"kmm.node.kubernetes.io/module.name": moduleName,
-						kernelLabel:                          otherKernelVersion,
+			ds := appsv1.DaemonSet{}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(2 + len(expectedVolumes)))
+			Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(HaveLen(2 + len(expectedMounts)))
+
+			if len(expectedVolumes) > 0 {
+				Expect(ds.Spec.Template.Spec.Volumes[2:]).To(Equal(expectedVolumes))
+				Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[2:]).To(Equal(expectedMounts))
+			}
+		},
+		Entry("disabled", false, nil, nil, nil),
+		Entry(
+			"whole /dev",
+			true,
+			nil,
+			[]v1.Volume{
+				{
+					Name: "host-dev",
+					VolumeSource: v1.VolumeSource{
+						HostPath: &v1.HostPathVolumeSource{Path: "/dev", Type: &hostPathDirectory},
 					},
 				},
-			}
-
-			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
-				func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
-					list.Items = append(list.Items, ds1)
-					list.Items = append(list.Items, ds2)
-					return nil
+			},
+			[]v1.VolumeMount{{Name: "host-dev", MountPath: "/dev"}},
+		),
+		Entry(
+			"specific device nodes",
+			true,
+			[]string{"/dev/foo0", "/dev/foo"},
+			[]v1.Volume{
+				{
+					Name:         "host-dev-0",
+					VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev/foo0"}},
 				},
-			)
+				{
+					Name:         "host-dev-1",
+					VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev/foo"}},
+				},
+			},
+			[]v1.VolumeMount{
+				{Name: "host-dev-0", MountPath: "/dev/foo0"},
+				{Name: "host-dev-1", MountPath: "/dev/foo"},
+			},
+		),
+	)
 
-			dc := NewCreator(clnt, kernelLabel, scheme)
+	DescribeTable("should return an error if the host /dev paths are invalid",
+		func(mountHostDev bool, hostDevPaths []string) {
 			mod := kmmv1beta1.Module{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      moduleName,
-					Namespace: namespace,
+					Name: moduleName,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							HostDevPaths: hostDevPaths,
+							Modprobe:     kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+							MountHostDev: mountHostDev,
+						},
+					},
 				},
 			}
 
-			m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), mod.Name, mod.Namespace)
-			Expect(err).NotTo(HaveOccurred())
-			Expect(m).To(HaveLen(2))
-			Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
-			Expect(m).To(HaveKeyWithValue(otherKernelVersion, &ds2))
-		})
-	})
-})
-
-var _ = Describe("SetDevicePluginAsDesired", func() {
-	dg := NewCreator(nil, kernelLabel, scheme)
+			ds := appsv1.DaemonSet{}
 
-	It("should return an error if the DaemonSet is nil", func() {
-		Expect(
-			dg.SetDevicePluginAsDesired(context.Background(), nil, &kmmv1beta1.Module{}),
-		).To(
-			HaveOccurred(),
-		)
-	})
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("paths without mountHostDev", false, []string{"/dev/foo0"}),
+		Entry("relative path", true, []string{"dev/foo0"}),
+		Entry("path outside of /dev", true, []string{"/etc/foo0"}),
+		Entry("/dev itself", true, []string{"/dev"}),
+		Entry("path escaping /dev", true, []string{"/dev/../etc"}),
+		Entry("unclean path", true, []string{"/dev//foo0"}),
+	)
 
-	It("should return an error if DevicePlugin not set in the Spec", func() {
-		ds := appsv1.DaemonSet{}
-		Expect(
-			dg.SetDevicePluginAsDesired(context.Background(), &ds, &kmmv1beta1.Module{}),
-		).To(
-			HaveOccurred(),
-		)
-	})
+	It("should use the custom firmware host path if FirmwareHostPath is set", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: moduleName,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							FirmwareHostPath: "/run/firmware",
+							FirmwarePath:     "/opt/lib/firmware/example",
+							ModuleName:       "some-kmod",
+						},
+					},
+				},
+			},
+		}
 
-	It("should add additional volumes if there are any", func() {
-		vol := v1.Volume{Name: "test-volume"}
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(3))
+		Expect(ds.Spec.Template.Spec.Volumes[2].HostPath.Path).To(Equal("/run/firmware/module-name"))
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[2].MountPath).To(Equal("/run/firmware/module-name"))
+
+		lifecycle := ds.Spec.Template.Spec.Containers[0].Lifecycle
+		Expect(lifecycle.PostStart.Exec.Command).To(Equal([]string{
+			"/bin/sh",
+			"-c",
+			"cp -r /opt/lib/firmware/example /run/firmware/module-name && modprobe -v some-kmod",
+		}))
+		Expect(lifecycle.PreStop.Exec.Command).To(Equal([]string{
+			"/bin/sh",
+			"-c",
+			"modprobe -rv some-kmod; ret=$?; rm -rf /run/firmware/module-name; exit $ret",
+		}))
+	})
 
+	It("should copy the firmware to FirmwareSubDir if it is set", func() {
 		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: moduleName,
+			},
 			Spec: kmmv1beta1.ModuleSpec{
-				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
-					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
-					Volumes:   []v1.Volume{vol},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							FirmwarePath:   "/opt/vendor/blobs",
+							FirmwareSubDir: "vendor/acme/",
+							ModuleName:     "some-kmod",
+						},
+					},
 				},
 			},
 		}
 
 		ds := appsv1.DaemonSet{}
 
-		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
-		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(2))
-		Expect(ds.Spec.Template.Spec.Volumes[1]).To(Equal(vol))
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(3))
+		Expect(ds.Spec.Template.Spec.Volumes[2].HostPath.Path).To(Equal("/var/lib/firmware/vendor/acme"))
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts[2].MountPath).To(Equal("/var/lib/firmware/vendor/acme"))
+
+		lifecycle := ds.Spec.Template.Spec.Containers[0].Lifecycle
+		Expect(lifecycle.PostStart.Exec.Command).To(Equal([]string{
+			"/bin/sh",
+			"-c",
+			"cp -r /opt/vendor/blobs /var/lib/firmware/vendor/acme && modprobe -v some-kmod",
+		}))
+		Expect(lifecycle.PreStop.Exec.Command).To(Equal([]string{
+			"/bin/sh",
+			"-c",
+			"modprobe -rv some-kmod; ret=$?; rm -rf /var/lib/firmware/vendor/acme; exit $ret",
+		}))
 	})
 
-	It("should work as expected", func() {
-		const (
-			dsName             = "ds-name"
-			serviceAccountName = "some-service-account"
-		)
+	DescribeTable("should set the tolerations on the pod template",
+		func(tolerations []v1.Toleration) {
+			mod := kmmv1beta1.Module{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: kmmv1beta1.GroupVersion.String(),
+					Kind:       "Module",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						Tolerations: tolerations,
+					},
+				},
+			}
 
-		dpVol := v1.Volume{
-			Name:         "test-volume",
-			VolumeSource: v1.VolumeSource{},
-		}
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
 
-		dpVolMount := v1.VolumeMount{
-			Name:      "some-dp-volume-mount",
-			MountPath: "/some/path",
-		}
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Tolerations).To(Equal(tolerations))
+			Expect(ds.OwnerReferences).To(HaveLen(1))
+			Expect(ds.OwnerReferences[0].Name).To(Equal(moduleName))
+			Expect(*ds.OwnerReferences[0].Controller).To(BeTrue())
+		},
+		Entry("no tolerations", nil),
+		Entry(
+			"one toleration",
+			[]v1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: v1.TolerationOpEqual, Value: "present", Effect: v1.TaintEffectNoSchedule},
+			},
+		),
+		Entry(
+			"multiple tolerations",
+			[]v1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: v1.TolerationOpEqual, Value: "present", Effect: v1.TaintEffectNoSchedule},
+				{Key: "node-role.kubernetes.io/master", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+			},
+		),
+	)
 
-		repoSecret := v1.LocalObjectReference{Name: "pull-secret-name"}
+	DescribeTable("should add a toleration matching all taints if requested",
+		func(tolerations []v1.Toleration) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						TolerateAllTaints: true,
+						Tolerations:       tolerations,
+					},
+				},
+			}
 
-		env := []v1.EnvVar{
-			{
-				Name:  "ENV_KEY",
-				Value: "ENV_VALUE",
-			},
-		}
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
 
-		resources := v1.ResourceRequirements{
-			Limits: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:    resource.MustParse("200m"),
-				v1.ResourceMemory: resource.MustParse("4G"),
-			},
-			Requests: map[v1.ResourceName]resource.Quantity{
-				v1.ResourceCPU:    resource.MustParse("100m"),
-				v1.ResourceMemory: resource.MustParse("2G"),
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(
+				ds.Spec.Template.Spec.Tolerations,
+			).To(
+				Equal(append(tolerations, v1.Toleration{Operator: v1.TolerationOpExists})),
+			)
+
+			// the Module must not be modified
+			Expect(mod.Spec.ModuleLoader.Tolerations).To(HaveLen(len(tolerations)))
+		},
+		Entry("no explicit tolerations", nil),
+		Entry(
+			"explicit tolerations",
+			[]v1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: v1.TolerationOpEqual, Value: "present", Effect: v1.TaintEffectNoSchedule},
 			},
-		}
+		),
+	)
 
-		args := []string{"some", "args"}
-		command := []string{"some", "command"}
+	DescribeTable("should set the image pull secrets on the pod template",
+		func(secrets []v1.LocalObjectReference) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ImageRepoSecrets: secrets,
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+				},
+			}
 
-		const ipp = v1.PullIfNotPresent
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.ImagePullSecrets).To(Equal(secrets))
+		},
+		Entry("no secrets", nil),
+		Entry("one secret", []v1.LocalObjectReference{{Name: "secret1"}}),
+		Entry("multiple secrets", []v1.LocalObjectReference{{Name: "secret1"}, {Name: "secret2"}}),
+	)
+
+	DescribeTable("should set the priority class name on the pod template",
+		func(priorityClassName, expected string) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						PriorityClassName: priorityClassName,
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.PriorityClassName).To(Equal(expected))
+		},
+		Entry("default", "", "system-node-critical"),
+		Entry("overridden", "custom-priority", "custom-priority"),
+	)
 
+	DescribeTable("should set the host namespaces of the pod",
+		func(hostIPC, hostNetwork, hostPID bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						HostIPC:     hostIPC,
+						HostNetwork: hostNetwork,
+						HostPID:     hostPID,
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.HostIPC).To(Equal(hostIPC))
+			Expect(ds.Spec.Template.Spec.HostNetwork).To(Equal(hostNetwork))
+			Expect(ds.Spec.Template.Spec.HostPID).To(Equal(hostPID))
+		},
+		Entry("default", false, false, false),
+		Entry("host IPC only", true, false, false),
+		Entry("host network only", false, true, false),
+		Entry("host PID only", false, false, true),
+		Entry("all", true, true, true),
+	)
+
+	It("should not share the label maps between the DaemonSet, its selector and its pod template", func() {
 		mod := kmmv1beta1.Module{
-			TypeMeta: metav1.TypeMeta{
-				APIVersion: kmmv1beta1.GroupVersion.String(),
-				Kind:       "Module",
-			},
 			ObjectMeta: metav1.ObjectMeta{
 				Name:      moduleName,
 				Namespace: namespace,
 			},
 			Spec: kmmv1beta1.ModuleSpec{
-				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
-					Container: kmmv1beta1.DevicePluginContainerSpec{
-						Args:            args,
-						Command:         command,
-						Env:             env,
-						Image:           devicePluginImage,
-						ImagePullPolicy: ipp,
-						Resources:       resources,
-						VolumeMounts:    []v1.VolumeMount{dpVolMount},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
 					},
-					ServiceAccountName: serviceAccountName,
-					Volumes:            []v1.Volume{dpVol},
 				},
-				ImageRepoSecret: &repoSecret,
-				Selector:        map[string]string{"has-feature-x": "true"},
 			},
 		}
 
 		ds := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      dsName,
-				Namespace: namespace,
-			},
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
 		}
 
-		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
 		Expect(err).NotTo(HaveOccurred())
 
-		podLabels := map[string]string{
-			constants.ModuleNameLabel: moduleName,
-			constants.DaemonSetRole:   "device-plugin",
-		}
+		expected := CopyMapStringString(ds.Spec.Selector.MatchLabels)
 
-		directory := v1.HostPathDirectory
+		ds.Labels["a"] = "b"
+		ds.Spec.Template.Labels["c"] = "d"
 
-		expected := appsv1.DaemonSet{
+		Expect(ds.Spec.Selector.MatchLabels).To(Equal(expected))
+		Expect(ds.Spec.Template.Labels).NotTo(HaveKey("a"))
+		Expect(ds.Labels).NotTo(HaveKey("c"))
+	})
+
+	DescribeTable("should set the kernel version environment variable unless disabled",
+		func(disabled bool, expected []v1.EnvVar) {
+			const fullKernelVersion = "5.14.0-284.11.1.el9_2.x86_64+debug"
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							DisableKernelVersionEnv: disabled,
+							Modprobe:                kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, fullKernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].Env).To(Equal(expected))
+		},
+		Entry("default", false, []v1.EnvVar{{Name: "KERNEL_FULL_VERSION", Value: "5.14.0-284.11.1.el9_2.x86_64+debug"}}),
+		Entry("disabled", true, nil),
+	)
+
+	It("should set the user labels on the DaemonSet without overriding the managed ones", func() {
+		mod := kmmv1beta1.Module{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      dsName,
+				Name:      moduleName,
 				Namespace: namespace,
-				Labels:    podLabels,
-				OwnerReferences: []metav1.OwnerReference{
-					{
-						APIVersion:         mod.APIVersion,
-						BlockOwnerDeletion: pointer.Bool(true),
-						Controller:         pointer.Bool(true),
-						Kind:               mod.Kind,
-						Name:               moduleName,
-						UID:                mod.UID,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					DaemonSetLabels: map[string]string{
+						"cost-center":             "1234",
+						constants.DaemonSetRole:   "other-role",
+						constants.ModuleNameLabel: "other-module",
 					},
 				},
 			},
-			Spec: appsv1.DaemonSetSpec{
-				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
-				Template: v1.PodTemplateSpec{
-					ObjectMeta: metav1.ObjectMeta{
-						Labels:     podLabels,
-						Finalizers: []string{constants.NodeLabelerFinalizer},
-					},
-					Spec: v1.PodSpec{
-						Containers: []v1.Container{
-							{
-								Args:            args,
-								Command:         command,
-								Env:             env,
-								Image:           devicePluginImage,
-								ImagePullPolicy: ipp,
-								Name:            "device-plugin",
-								Resources:       resources,
-								SecurityContext: &v1.SecurityContext{
-									Privileged: pointer.Bool(true),
-								},
-								VolumeMounts: []v1.VolumeMount{
-									dpVolMount,
-									{
-										Name:      "kubelet-device-plugins",
-										MountPath: "/var/lib/kubelet/device-plugins",
-									},
-								},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		expected := map[string]string{
+			"cost-center":             "1234",
+			constants.DaemonSetRole:   "module-loader",
+			constants.ModuleNameLabel: moduleName,
+			kernelLabel:               kernelVersion,
+		}
+
+		for i := 0; i < 2; i++ {
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Labels).To(Equal(expected))
+		}
+	})
+
+	DescribeTable("should set the termination grace period of the pod",
+		func(unloadGracePeriodSeconds, expected *int64) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{
+								ModuleName:               "some-kmod",
+								UnloadGracePeriodSeconds: unloadGracePeriodSeconds,
+							},
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(expected))
+			Expect(ds.Spec.Template.Spec.Containers[0].Lifecycle.PreStop.Exec.Command).To(
+				Equal(MakeUnloadCommand(mod.Spec.ModuleLoader.Container.Modprobe, moduleName, kernelVersion)),
+			)
+		},
+		Entry("default", nil, nil),
+		Entry("zero", pointer.Int64(0), nil),
+		Entry("unload grace period", pointer.Int64(60), pointer.Int64(90)),
+	)
+
+	DescribeTable("should stamp the last-seen-valid annotation only if a retention window is configured",
+		func(retention time.Duration, expectAnnotation bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"a": "b"},
+					Namespace:   namespace,
+				},
+			}
+
+			err := NewCreator(nil, kernelLabel, scheme, WithGarbageCollectionRetention(retention)).
+				SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Annotations).To(HaveKeyWithValue("a", "b"))
+
+			if !expectAnnotation {
+				Expect(ds.Annotations).NotTo(HaveKey(constants.LastSeenValidAnnotation))
+				return
+			}
+
+			lastSeenValid, err := time.Parse(time.RFC3339, ds.Annotations[constants.LastSeenValidAnnotation])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastSeenValid).To(BeTemporally("~", time.Now(), 2*time.Second))
+		},
+		Entry("no retention", time.Duration(0), false),
+		Entry("retention", time.Hour, true),
+	)
+
+	DescribeTable("should only refresh the last-seen-valid annotation once per tenth of the retention window",
+		func(age time.Duration, expectRefresh bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+				},
+			}
+
+			lastSeenValid := time.Now().Add(-age).UTC().Format(time.RFC3339)
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{constants.LastSeenValidAnnotation: lastSeenValid},
+					Namespace:   namespace,
+				},
+			}
+
+			err := NewCreator(nil, kernelLabel, scheme, WithGarbageCollectionRetention(time.Hour)).
+				SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			if !expectRefresh {
+				Expect(ds.Annotations).To(HaveKeyWithValue(constants.LastSeenValidAnnotation, lastSeenValid))
+				return
+			}
+
+			refreshed, err := time.Parse(time.RFC3339, ds.Annotations[constants.LastSeenValidAnnotation])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(refreshed).To(BeTemporally("~", time.Now(), 2*time.Second))
+		},
+		Entry("recent annotation", time.Minute, false),
+		Entry("outdated annotation", 10*time.Minute, true),
+	)
+
+	DescribeTable("should only require images referenced by digest if configured",
+		func(required bool, image string, expectError bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := NewCreator(nil, kernelLabel, scheme, WithImageDigestRequired(required)).
+				SetDriverContainerAsDesired(context.Background(), &ds, image, mod, kernelVersion, "")
+
+			if expectError {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].Image).To(Equal(image))
+		},
+		Entry("tag, not required", false, "quay.io/org/kmod:1.2.3", false),
+		Entry("digest, not required", false, "quay.io/org/kmod@sha256:"+strings.Repeat("a", 64), false),
+		Entry("tag, required", true, "quay.io/org/kmod:1.2.3", true),
+		Entry("no tag, required", true, "quay.io/org/kmod", true),
+		Entry("invalid digest, required", true, "quay.io/org/kmod@sha256:abc", true),
+		Entry("digest, required", true, "quay.io/org/kmod@sha256:"+strings.Repeat("a", 64), false),
+	)
+
+	DescribeTable("should set the update strategy of the DaemonSet",
+		func(strategy appsv1.DaemonSetUpdateStrategy) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						UpdateStrategy: strategy,
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.UpdateStrategy).To(Equal(strategy))
+		},
+		Entry("default", appsv1.DaemonSetUpdateStrategy{}),
+		Entry(
+			"rolling update",
+			appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+					MaxUnavailable: &intstr.IntOrString{Type: intstr.String, StrVal: "25%"},
+				},
+			},
+		),
+		Entry("on delete", appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}),
+	)
+
+	It("should return an error if the update strategy is invalid", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+						RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+							MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: -1},
+						},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		Expect(
+			dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, ""),
+		).To(
+			HaveOccurred(),
+		)
+	})
+
+	DescribeTable("should set the pod annotations without the reserved ones",
+		func(opts []Option, annotations, expected map[string]string) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						PodAnnotations: annotations,
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := NewCreator(nil, kernelLabel, scheme, opts...).
+				SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Annotations).To(HaveKey(constants.ModprobeConfigHashAnnotation))
+
+			delete(ds.Spec.Template.Annotations, constants.ModprobeConfigHashAnnotation)
+			Expect(ds.Spec.Template.Annotations).To(Equal(expected))
+		},
+		Entry("no annotations", nil, nil, map[string]string{}),
+		Entry(
+			"user annotations",
+			nil,
+			map[string]string{"sidecar.istio.io/inject": "false", "a": "b"},
+			map[string]string{"sidecar.istio.io/inject": "false", "a": "b"},
+		),
+		Entry(
+			"reserved annotations",
+			nil,
+			map[string]string{"sidecar.istio.io/inject": "false", "kmm.node.kubernetes.io/role": "x"},
+			map[string]string{"sidecar.istio.io/inject": "false"},
+		),
+		Entry(
+			"reserved annotations with a custom node label prefix",
+			[]Option{WithNodeLabelPrefix("tenant-a.example.com")},
+			map[string]string{"tenant-a.example.com/a": "b", "kmm.node.kubernetes.io/role": "x"},
+			map[string]string{},
+		),
+	)
+
+	It("should copy the container resources to the pod template", func() {
+		resources := v1.ResourceRequirements{
+			Limits: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("500m"),
+				v1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+			Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("100m"),
+				v1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe:  kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						Resources: resources,
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Containers[0].Resources).To(Equal(resources))
+	})
+
+	DescribeTable("should set the container command",
+		func(containerSpec kmmv1beta1.ModuleLoaderContainerSpec, expectedCommand, expectedArgs []string, expectHooks bool) {
+			containerSpec.Modprobe = kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"}
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{Container: containerSpec},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			container := ds.Spec.Template.Spec.Containers[0]
+			Expect(container.Command).To(Equal(expectedCommand))
+			Expect(container.Args).To(Equal(expectedArgs))
+
+			if expectHooks {
+				Expect(container.Lifecycle.PostStart.Exec.Command).To(Equal(MakeLoadCommand(containerSpec.Modprobe, moduleName, kernelVersion)))
+				Expect(container.Lifecycle.PreStop.Exec.Command).To(Equal(MakeUnloadCommand(containerSpec.Modprobe, moduleName, kernelVersion)))
+			} else {
+				Expect(container.Lifecycle).To(BeNil())
+			}
+		},
+		Entry(
+			"default",
+			kmmv1beta1.ModuleLoaderContainerSpec{},
+			[]string{"sleep", "infinity"},
+			nil,
+			true,
+		),
+		Entry(
+			"command and args overridden",
+			kmmv1beta1.ModuleLoaderContainerSpec{Command: []string{"/load.sh"}, Args: []string{"--tail-dmesg"}},
+			[]string{"/load.sh"},
+			[]string{"--tail-dmesg"},
+			true,
+		),
+		Entry(
+			"only args overridden",
+			kmmv1beta1.ModuleLoaderContainerSpec{Args: []string{"--tail-dmesg"}},
+			nil,
+			[]string{"--tail-dmesg"},
+			true,
+		),
+		Entry(
+			"lifecycle hooks disabled",
+			kmmv1beta1.ModuleLoaderContainerSpec{DisableLifecycleHooks: true},
+			nil,
+			nil,
+			false,
+		),
+		Entry(
+			"command overridden and lifecycle hooks disabled",
+			kmmv1beta1.ModuleLoaderContainerSpec{Command: []string{"/load.sh"}, DisableLifecycleHooks: true},
+			[]string{"/load.sh"},
+			nil,
+			false,
+		),
+	)
+
+	DescribeTable("should set the SELinux options of the container",
+		func(seLinuxType *string, expected *v1.SELinuxOptions) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe:    kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+							SELinuxType: seLinuxType,
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].SecurityContext.SELinuxOptions).To(Equal(expected))
+		},
+		Entry("default", nil, &v1.SELinuxOptions{Type: "spc_t"}),
+		Entry("overridden", pointer.String("custom_t"), &v1.SELinuxOptions{Type: "custom_t"}),
+		Entry("omitted", pointer.String(""), nil),
+	)
+
+	It("should not make the root filesystem read-only by default", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Containers[0].SecurityContext.ReadOnlyRootFilesystem).To(BeNil())
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(2))
+	})
+
+	It("should make the root filesystem read-only and mount writable volumes if requested", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							FirmwarePath: "/opt/lib/firmware/example",
+							ModuleName:   "some-kmod",
+						},
+						ReadOnlyRootFilesystem: true,
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		container := ds.Spec.Template.Spec.Containers[0]
+		Expect(container.SecurityContext.ReadOnlyRootFilesystem).To(Equal(pointer.Bool(true)))
+
+		Expect(ds.Spec.Template.Spec.Volumes).To(ContainElement(v1.Volume{
+			Name:         "tmp",
+			VolumeSource: v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}},
+		}))
+
+		Expect(container.VolumeMounts).To(ContainElements(
+			v1.VolumeMount{Name: "tmp", MountPath: "/tmp"},
+			v1.VolumeMount{Name: "node-var-lib-firmware", MountPath: "/var/lib/firmware/module-name"},
+		))
+	})
+
+	It("should not set probes by default", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Containers[0].LivenessProbe).To(BeNil())
+		Expect(ds.Spec.Template.Spec.Containers[0].ReadinessProbe).To(BeNil())
+		Expect(ds.Spec.Template.Spec.Containers[0].StartupProbe).To(BeNil())
+	})
+
+	DescribeTable("should set a readiness probe checking that the kernel module is loaded",
+		func(probeSpec kmmv1beta1.ReadinessProbeSpec, expectedPeriod, expectedTimeout, expectedThreshold int32) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe:       kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+							ReadinessProbe: &probeSpec,
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			expected := &v1.Probe{
+				ProbeHandler: v1.ProbeHandler{
+					Exec: &v1.ExecAction{
+						Command: []string{"test", "-d", "/sys/module/some_kmod"},
+					},
+				},
+				PeriodSeconds:    expectedPeriod,
+				TimeoutSeconds:   expectedTimeout,
+				FailureThreshold: expectedThreshold,
+			}
+
+			Expect(ds.Spec.Template.Spec.Containers[0].ReadinessProbe).To(Equal(expected))
+		},
+		Entry("defaults", kmmv1beta1.ReadinessProbeSpec{}, int32(10), int32(1), int32(3)),
+		Entry(
+			"custom values",
+			kmmv1beta1.ReadinessProbeSpec{PeriodSeconds: 5, TimeoutSeconds: 2, FailureThreshold: 6},
+			int32(5),
+			int32(2),
+			int32(6),
+		),
+	)
+
+	DescribeTable("should set a liveness probe checking that the kernel module is still loaded",
+		func(probeSpec kmmv1beta1.LivenessProbeSpec, expectedPeriod, expectedTimeout, expectedThreshold int32) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							LivenessProbe: &probeSpec,
+							Modprobe:      kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			expected := &v1.Probe{
+				ProbeHandler: v1.ProbeHandler{
+					Exec: &v1.ExecAction{
+						Command: []string{"test", "-d", "/sys/module/some_kmod"},
+					},
+				},
+				PeriodSeconds:    expectedPeriod,
+				TimeoutSeconds:   expectedTimeout,
+				FailureThreshold: expectedThreshold,
+			}
+
+			Expect(ds.Spec.Template.Spec.Containers[0].LivenessProbe).To(Equal(expected))
+			Expect(ds.Spec.Template.Spec.Containers[0].ReadinessProbe).To(BeNil())
+		},
+		Entry("defaults", kmmv1beta1.LivenessProbeSpec{}, int32(10), int32(1), int32(3)),
+		Entry(
+			"custom values",
+			kmmv1beta1.LivenessProbeSpec{PeriodSeconds: 30, TimeoutSeconds: 5, FailureThreshold: 2},
+			int32(30),
+			int32(5),
+			int32(2),
+		),
+	)
+
+	DescribeTable("should set a startup probe if configured",
+		func(probeSpec kmmv1beta1.StartupProbeSpec, expected *v1.Probe) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe:     kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+							StartupProbe: &probeSpec,
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].StartupProbe).To(Equal(expected))
+			Expect(ds.Spec.Template.Spec.Containers[0].LivenessProbe).To(BeNil())
+			Expect(ds.Spec.Template.Spec.Containers[0].ReadinessProbe).To(BeNil())
+		},
+		Entry(
+			"defaults",
+			kmmv1beta1.StartupProbeSpec{},
+			&v1.Probe{
+				ProbeHandler: v1.ProbeHandler{
+					Exec: &v1.ExecAction{
+						Command: []string{"test", "-d", "/sys/module/some_kmod"},
+					},
+				},
+				PeriodSeconds:    10,
+				TimeoutSeconds:   1,
+				FailureThreshold: 3,
+			},
+		),
+		Entry(
+			"custom values",
+			kmmv1beta1.StartupProbeSpec{
+				Command:             []string{"test", "-e", "/sys/class/some-device/ready"},
+				InitialDelaySeconds: 10,
+				PeriodSeconds:       5,
+				TimeoutSeconds:      2,
+				FailureThreshold:    12,
+			},
+			&v1.Probe{
+				ProbeHandler: v1.ProbeHandler{
+					Exec: &v1.ExecAction{
+						Command: []string{"test", "-e", "/sys/class/some-device/ready"},
+					},
+				},
+				InitialDelaySeconds: 10,
+				PeriodSeconds:       5,
+				TimeoutSeconds:      2,
+				FailureThreshold:    12,
+			},
+		),
+	)
+
+	DescribeTable("should check all the modules of the loading order in the probes",
+		func(container kmmv1beta1.ModuleLoaderContainerSpec, getProbe func(c v1.Container) *v1.Probe) {
+			container.Modprobe = kmmv1beta1.ModprobeSpec{
+				ModuleName:          "some-kmod",
+				ModulesLoadingOrder: []string{"some-kmod", "dep-a", "dep-b"},
+			}
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{Container: container},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			probe := getProbe(ds.Spec.Template.Spec.Containers[0])
+			Expect(probe).NotTo(BeNil())
+			Expect(probe.Exec.Command).To(Equal([]string{
+				"test",
+				"-d", "/sys/module/some_kmod",
+				"-a", "-d", "/sys/module/dep_a",
+				"-a", "-d", "/sys/module/dep_b",
+			}))
+		},
+		Entry(
+			"readiness",
+			kmmv1beta1.ModuleLoaderContainerSpec{ReadinessProbe: &kmmv1beta1.ReadinessProbeSpec{}},
+			func(c v1.Container) *v1.Probe { return c.ReadinessProbe },
+		),
+		Entry(
+			"liveness",
+			kmmv1beta1.ModuleLoaderContainerSpec{LivenessProbe: &kmmv1beta1.LivenessProbeSpec{}},
+			func(c v1.Container) *v1.Probe { return c.LivenessProbe },
+		),
+		Entry(
+			"startup",
+			kmmv1beta1.ModuleLoaderContainerSpec{StartupProbe: &kmmv1beta1.StartupProbeSpec{}},
+			func(c v1.Container) *v1.Probe { return c.StartupProbe },
+		),
+	)
+
+	DescribeTable("should return an error if a probe is enabled without a module name",
+		func(container kmmv1beta1.ModuleLoaderContainerSpec, expectErr bool) {
+			container.Modprobe = kmmv1beta1.ModprobeSpec{
+				RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}},
+			}
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{Container: container},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+
+			if expectErr {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		},
+		Entry("no probe", kmmv1beta1.ModuleLoaderContainerSpec{}, false),
+		Entry("readiness", kmmv1beta1.ModuleLoaderContainerSpec{ReadinessProbe: &kmmv1beta1.ReadinessProbeSpec{}}, true),
+		Entry("liveness", kmmv1beta1.ModuleLoaderContainerSpec{LivenessProbe: &kmmv1beta1.LivenessProbeSpec{}}, true),
+		Entry("startup", kmmv1beta1.ModuleLoaderContainerSpec{StartupProbe: &kmmv1beta1.StartupProbeSpec{}}, true),
+		Entry(
+			"startup with a command",
+			kmmv1beta1.ModuleLoaderContainerSpec{
+				StartupProbe: &kmmv1beta1.StartupProbeSpec{Command: []string{"test", "-e", "/dev/some-device"}},
+			},
+			false,
+		),
+	)
+
+	It("should return an error if insmod is used without a module path", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName: "some-kmod",
+							UseInsmod:  true,
+						},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if a limit is lower than its request", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Resources: v1.ResourceRequirements{
+							Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("128Mi")},
+							Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("256Mi")},
+						},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Containers).To(BeEmpty())
+	})
+
+	It("should add the kernel requirement to every required node affinity term", func() {
+		zoneRequirement := v1.NodeSelectorRequirement{
+			Key:      "topology.kubernetes.io/zone",
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{"zone-a", "zone-b"},
+		}
+
+		gpuRequirement := v1.NodeSelectorRequirement{
+			Key:      "gpu-vendor",
+			Operator: v1.NodeSelectorOpNotIn,
+			Values:   []string{"other"},
+		}
+
+		preferred := []v1.PreferredSchedulingTerm{
+			{
+				Weight:     10,
+				Preference: v1.NodeSelectorTerm{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement}},
+			},
+		}
+
+		affinity := &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement}},
+						{MatchExpressions: []v1.NodeSelectorRequirement{gpuRequirement}},
+					},
+				},
+				PreferredDuringSchedulingIgnoredDuringExecution: preferred,
+			},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Affinity: affinity,
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		kernelRequirement := v1.NodeSelectorRequirement{
+			Key:      kernelLabel,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{kernelVersion},
+		}
+
+		nodeAffinity := ds.Spec.Template.Spec.Affinity.NodeAffinity
+		Expect(nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(Equal(preferred))
+		Expect(nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).To(Equal([]v1.NodeSelectorTerm{
+			{MatchExpressions: []v1.NodeSelectorRequirement{zoneRequirement, kernelRequirement}},
+			{MatchExpressions: []v1.NodeSelectorRequirement{gpuRequirement, kernelRequirement}},
+		}))
+
+		// the Module's own affinity must not be modified
+		Expect(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions).To(HaveLen(1))
+		Expect(affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[1].MatchExpressions).To(HaveLen(1))
+	})
+
+	It("should add the kernel requirement when the affinity has no node affinity", func() {
+		podAntiAffinity := &v1.PodAntiAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []v1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "other"}},
+					TopologyKey:   "kubernetes.io/hostname",
+				},
+			},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Affinity: &v1.Affinity{PodAntiAffinity: podAntiAffinity},
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		expected := &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{
+									Key:      kernelLabel,
+									Operator: v1.NodeSelectorOpIn,
+									Values:   []string{kernelVersion},
+								},
+							},
+						},
+					},
+				},
+			},
+			PodAntiAffinity: podAntiAffinity,
+		}
+
+		Expect(ds.Spec.Template.Spec.Affinity).To(Equal(expected))
+	})
+
+	It("should sanitize kernel versions that are not valid label values", func() {
+		const debugKernelVersion = "5.14.0-284.11.1.el9_2.x86_64+debug"
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, debugKernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		labelValue := KernelLabelValue(debugKernelVersion)
+		Expect(labelValue).NotTo(Equal(debugKernelVersion))
+
+		Expect(ds.GetLabels()).To(HaveKeyWithValue(kernelLabel, labelValue))
+		Expect(ds.GetAnnotations()).To(HaveKeyWithValue(constants.KernelVersionAnnotation, debugKernelVersion))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(kernelLabel, labelValue))
+		Expect(ds.Spec.Selector.MatchLabels).To(HaveKeyWithValue(kernelLabel, labelValue))
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(HaveKeyWithValue(kernelLabel, labelValue))
+		Expect(
+			ds.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[0].MatchExpressions[0].Values,
+		).To(Equal([]string{labelValue}))
+		Expect(dg.KernelVersion(&ds)).To(Equal(debugKernelVersion))
+	})
+
+	It("should set the module loader version on the pods only", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						Version:  "4f2a9c1",
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(constants.ModuleVersionLabel, "4f2a9c1"))
+		Expect(ds.Spec.Selector.MatchLabels).NotTo(HaveKey(constants.ModuleVersionLabel))
+		Expect(ds.Labels).NotTo(HaveKey(constants.ModuleVersionLabel))
+	})
+
+	It("should not set the module loader version label if no version is set", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Labels).NotTo(HaveKey(constants.ModuleVersionLabel))
+	})
+
+	It("should return an error if the module loader version is not a valid label value", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						Version:  "v1.0+build",
+					},
+				},
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("should name the module loader container",
+		func(name, expected string) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+							Name:     name,
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers).To(HaveLen(1))
+			Expect(ds.Spec.Template.Spec.Containers[0].Name).To(Equal(expected))
+			Expect(ModuleLoaderContainerName(&mod)).To(Equal(expected))
+		},
+		Entry("default name", "", "module-loader"),
+		Entry("custom name", "kmm-loader", "kmm-loader"),
+	)
+
+	It("should return an error if the module loader container name is invalid", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						Name:     "Module_Loader",
+					},
+				},
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should run the module loader container as root by default", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		sc := ds.Spec.Template.Spec.Containers[0].SecurityContext
+		Expect(sc.RunAsUser).To(Equal(pointer.Int64(0)))
+		Expect(sc.RunAsGroup).To(BeNil())
+		Expect(sc.Capabilities.Add).To(ContainElement(v1.Capability("SYS_MODULE")))
+		Expect(ds.Spec.Template.Spec.SecurityContext).To(BeNil())
+	})
+
+	It("should apply the module loader security context and keep the SYS_MODULE capability", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					SecurityContext: &kmmv1beta1.ModuleLoaderSecurityContext{
+						RunAsUser:  pointer.Int64(1000),
+						RunAsGroup: pointer.Int64(2000),
+						FSGroup:    pointer.Int64(3000),
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		sc := ds.Spec.Template.Spec.Containers[0].SecurityContext
+		Expect(sc.RunAsUser).To(Equal(pointer.Int64(1000)))
+		Expect(sc.RunAsGroup).To(Equal(pointer.Int64(2000)))
+		Expect(sc.Capabilities.Add).To(ContainElement(v1.Capability("SYS_MODULE")))
+		Expect(ds.Spec.Template.Spec.SecurityContext).To(Equal(&v1.PodSecurityContext{FSGroup: pointer.Int64(3000)}))
+	})
+
+	It("should return an error if the module loader security context sets a negative ID", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					SecurityContext: &kmmv1beta1.ModuleLoaderSecurityContext{RunAsUser: pointer.Int64(-1)},
+				},
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should set the DNS policy and configuration of the pods", func() {
+		dnsConfig := &v1.PodDNSConfig{
+			Nameservers: []string{"10.0.0.53"},
+			Searches:    []string{"license.example.com"},
+			Options:     []v1.PodDNSConfigOption{{Name: "ndots", Value: pointer.String("1")}},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					DNSConfig: dnsConfig,
+					DNSPolicy: v1.DNSNone,
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.DNSPolicy).To(Equal(v1.DNSNone))
+		Expect(ds.Spec.Template.Spec.DNSConfig).To(Equal(dnsConfig))
+	})
+
+	It("should not set the DNS policy and configuration of the pods by default", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.DNSPolicy).To(BeEmpty())
+		Expect(ds.Spec.Template.Spec.DNSConfig).To(BeNil())
+	})
+
+	It("should return an error if the DNS policy is None without a DNS configuration", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					DNSPolicy: v1.DNSNone,
+				},
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should run the init containers before the module loader and share the firmware volume with them", func() {
+		verify := v1.Container{
+			Name:         "verify-signature",
+			Image:        "verifier",
+			Command:      []string{"/verify", "/opt/some-kmod.ko"},
+			VolumeMounts: []v1.VolumeMount{{Name: "keys", MountPath: "/keys"}},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ImageRepoSecret: &v1.LocalObjectReference{Name: "pull-secret"},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwarePath: "/firmware"},
+					},
+					InitContainers: []v1.Container{verify},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := ds.Spec.Template.Spec
+		Expect(podSpec.ImagePullSecrets).To(Equal([]v1.LocalObjectReference{{Name: "pull-secret"}}))
+		Expect(podSpec.InitContainers).To(HaveLen(1))
+		Expect(podSpec.InitContainers[0].Name).To(Equal("verify-signature"))
+		Expect(podSpec.InitContainers[0].VolumeMounts).To(Equal([]v1.VolumeMount{
+			{Name: "keys", MountPath: "/keys"},
+			{Name: "node-var-lib-firmware", MountPath: "/var/lib/firmware/" + moduleName},
+		}))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(podSpec.InitContainers[0].VolumeMounts[1]))
+
+		// the Module must not be modified
+		Expect(mod.Spec.ModuleLoader.InitContainers[0].VolumeMounts).To(HaveLen(1))
+	})
+
+	It("should return an error if an init container has the name of the module loader container", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					InitContainers: []v1.Container{{Name: "module-loader", Image: "verifier"}},
+				},
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should extract the firmware tarball in an init container before the other ones", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						ImagePullPolicy: v1.PullAlways,
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName:     "some-kmod",
+							FirmwarePath:   "/firmware.tar.gz",
+							FirmwareSource: kmmv1beta1.FirmwareSourceTarball,
+						},
+					},
+					InitContainers: []v1.Container{{Name: "verify", Image: "verifier"}},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		firmwareMount := v1.VolumeMount{Name: "node-var-lib-firmware", MountPath: "/var/lib/firmware/" + moduleName}
+
+		podSpec := ds.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(HaveLen(2))
+		Expect(podSpec.InitContainers[0]).To(Equal(v1.Container{
+			Name:            "firmware-extract",
+			Image:           "test-image",
+			ImagePullPolicy: v1.PullAlways,
+			Command: []string{
+				"/bin/sh",
+				"-c",
+				"mkdir -p /var/lib/firmware/module-name && tar -xf /firmware.tar.gz -C /var/lib/firmware/module-name",
+			},
+			SecurityContext: &v1.SecurityContext{
+				AllowPrivilegeEscalation: pointer.Bool(false),
+				RunAsUser:                pointer.Int64(0),
+				SELinuxOptions:           &v1.SELinuxOptions{Type: "spc_t"},
+			},
+			VolumeMounts: []v1.VolumeMount{firmwareMount},
+		}))
+		Expect(podSpec.InitContainers[1].Name).To(Equal("verify"))
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(firmwareMount))
+		Expect(podSpec.Containers[0].Lifecycle.PostStart.Exec.Command).To(Equal([]string{"/bin/sh", "-c", "modprobe -v some-kmod"}))
+	})
+
+	It("should not add any init container for a firmware directory", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName:     "some-kmod",
+							FirmwarePath:   "/firmware",
+							FirmwareSource: kmmv1beta1.FirmwareSourceDirectory,
+						},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.InitContainers).To(BeEmpty())
+	})
+
+	It("should return an error if an init container has the name of the firmware extraction container", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName:     "some-kmod",
+							FirmwarePath:   "/firmware.tar",
+							FirmwareSource: kmmv1beta1.FirmwareSourceTarball,
+						},
+					},
+					InitContainers: []v1.Container{{Name: "firmware-extract", Image: "extractor"}},
+				},
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &appsv1.DaemonSet{}, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should remove the firmware directory in an init container once the firmware is not used anymore", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName:   "some-kmod",
+							FirmwarePath: "/firmware",
+						},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Annotations).NotTo(HaveKey(constants.StaleFirmwarePathAnnotation))
+
+		mod.Spec.ModuleLoader.Container.Modprobe.FirmwarePath = ""
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := ds.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(Equal([]v1.Container{
+			{
+				Name:    "firmware-cleanup",
+				Image:   "test-image",
+				Command: []string{"rm", "-rf", "/var/lib/firmware/" + moduleName},
+				SecurityContext: &v1.SecurityContext{
+					AllowPrivilegeEscalation: pointer.Bool(false),
+					RunAsUser:                pointer.Int64(0),
+					SELinuxOptions:           &v1.SELinuxOptions{Type: "spc_t"},
+				},
+				VolumeMounts: []v1.VolumeMount{{Name: "stale-firmware", MountPath: "/var/lib/firmware"}},
+			},
+		}))
+		Expect(podSpec.Volumes).To(ContainElement(v1.Volume{
+			Name: "stale-firmware",
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{Path: "/var/lib/firmware"},
+			},
+		}))
+		Expect(ds.Annotations).To(HaveKeyWithValue(constants.StaleFirmwarePathAnnotation, "/var/lib/firmware/"+moduleName))
+
+		// the firmware volume is gone: the annotation keeps the cleanup in place during the rollout
+		ds.Status = appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 1, NumberAvailable: 1}
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+		Expect(ds.Annotations).To(HaveKeyWithValue(constants.StaleFirmwarePathAnnotation, "/var/lib/firmware/"+moduleName))
+
+		// all nodes removed the directory: the cleanup is dropped
+		ds.Status = appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 2}
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		Expect(ds.Annotations).NotTo(HaveKey(constants.StaleFirmwarePathAnnotation))
+
+		mod.Spec.ModuleLoader.Container.Modprobe.FirmwarePath = "/firmware"
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		Expect(ds.Annotations).NotTo(HaveKey(constants.StaleFirmwarePathAnnotation))
+	})
+
+	It("should return an error if an init container has the name of the firmware cleanup container", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					InitContainers: []v1.Container{{Name: "firmware-cleanup", Image: "cleaner"}},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{constants.StaleFirmwarePathAnnotation: "/var/lib/firmware/" + moduleName},
+			},
+			Status: appsv1.DaemonSetStatus{DesiredNumberScheduled: 1},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should use a node kernel label different from the DaemonSet kernel label", func() {
+		const nodeKernelLabel = "feature.node.kubernetes.io/kernel-version.full"
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+				Selector: map[string]string{"has-feature-x": "true"},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		dc := NewCreator(nil, kernelLabel, scheme, WithNodeKernelLabel(nodeKernelLabel))
+
+		err := dc.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Labels).To(HaveKeyWithValue(kernelLabel, kernelVersion))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(kernelLabel, kernelVersion))
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+			"has-feature-x": "true",
+			nodeKernelLabel: kernelVersion,
+		}))
+
+		terms := ds.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(HaveLen(1))
+		Expect(terms[0].MatchExpressions).To(ContainElement(v1.NodeSelectorRequirement{
+			Key:      nodeKernelLabel,
+			Operator: v1.NodeSelectorOpIn,
+			Values:   []string{kernelVersion},
+		}))
+		Expect(dc.KernelVersion(&ds)).To(Equal(kernelVersion))
+	})
+
+	It("should set the sysctls on the pod security context", func() {
+		sysctls := []v1.Sysctl{
+			{Name: "net.ipv4.ip_local_port_range", Value: "1024 65535"},
+			{Name: "kernel.shm_rmid_forced", Value: "1"},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					Sysctls: sysctls,
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.SecurityContext).To(Equal(&v1.PodSecurityContext{Sysctls: sysctls}))
+	})
+
+	DescribeTable("should validate the sysctls",
+		func(sysctl v1.Sysctl, allowUnsafe, hostNetwork, expectError bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						AllowUnsafeSysctls: allowUnsafe,
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+						HostNetwork: hostNetwork,
+						Sysctls:     []v1.Sysctl{sysctl},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+
+			if expectError {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+		},
+		Entry("safe sysctl", v1.Sysctl{Name: "net.ipv4.tcp_syncookies", Value: "1"}, false, false, false),
+		Entry("unsafe sysctl", v1.Sysctl{Name: "net.core.rmem_max", Value: "26214400"}, false, false, true),
+		Entry("allowed unsafe sysctl", v1.Sysctl{Name: "net.core.rmem_max", Value: "26214400"}, true, false, false),
+		Entry("allowed unsafe IPC sysctl", v1.Sysctl{Name: "kernel.msgmax", Value: "65536"}, true, false, false),
+		Entry("non-namespaced sysctl", v1.Sysctl{Name: "vm.max_map_count", Value: "262144"}, true, false, true),
+		Entry("net sysctl with the host network", v1.Sysctl{Name: "net.ipv4.tcp_syncookies", Value: "1"}, false, true, true),
+		Entry("invalid name", v1.Sysctl{Name: "net..ipv4", Value: "1"}, true, false, true),
+	)
+
+	It("should pin the module loader to the node architecture", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+				Selector: map[string]string{"has-feature-x": "true"},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image-arm64", mod, kernelVersion, "arm64")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.Spec.Template.Spec.Containers[0].Image).To(Equal("test-image-arm64"))
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+			"has-feature-x":    "true",
+			kernelLabel:        kernelVersion,
+			v1.LabelArchStable: "arm64",
+		}))
+
+		terms := ds.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(Equal([]v1.NodeSelectorTerm{
+			{
+				MatchExpressions: []v1.NodeSelectorRequirement{
+					{
+						Key:      kernelLabel,
+						Operator: v1.NodeSelectorOpIn,
+						Values:   []string{kernelVersion},
+					},
+					{
+						Key:      v1.LabelArchStable,
+						Operator: v1.NodeSelectorOpIn,
+						Values:   []string{"arm64"},
+					},
+				},
+			},
+		}))
+
+		Expect(ds.Labels).To(HaveKeyWithValue(constants.ArchLabel, "arm64"))
+		Expect(ds.Spec.Selector.MatchLabels).To(HaveKeyWithValue(constants.ArchLabel, "arm64"))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(constants.ArchLabel, "arm64"))
+	})
+
+	It("should keep the selector of DaemonSets created before the architecture was part of it", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				constants.ModuleNameLabel: moduleName,
+				kernelLabel:               kernelVersion,
+				constants.DaemonSetRole:   constants.ModuleLoaderRole,
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			Spec:       appsv1.DaemonSetSpec{Selector: selector},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image-arm64", mod, kernelVersion, "arm64")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Selector).To(Equal(selector))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(constants.ArchLabel, "arm64"))
+	})
+
+	It("should require the modules it depends on to be ready on the node", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					DependsOn: []string{"module-a", "module-b"},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		terms := ds.Spec.Template.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		Expect(terms).To(Equal([]v1.NodeSelectorTerm{
+			{
+				MatchExpressions: []v1.NodeSelectorRequirement{
+					{Key: kernelLabel, Operator: v1.NodeSelectorOpIn, Values: []string{kernelVersion}},
+					{Key: "kmm.node.kubernetes.io/module-a.ready", Operator: v1.NodeSelectorOpExists},
+					{Key: "kmm.node.kubernetes.io/module-b.ready", Operator: v1.NodeSelectorOpExists},
+				},
+			},
+		}))
+	})
+
+	It("should return an error if the module depends on itself", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					DependsOn: []string{moduleName},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should work as expected", func() {
+		const (
+			moduleLoaderImage   = "driver-image"
+			dsName              = "ds-name"
+			imageRepoSecretName = "image-repo-secret"
+			serviceAccountName  = "driver-service-account"
+		)
+
+		mod := kmmv1beta1.Module{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: kmmv1beta1.GroupVersion.String(),
+				Kind:       "Module",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					ServiceAccountName: serviceAccountName,
+				},
+				ImageRepoSecret: &v1.LocalObjectReference{Name: imageRepoSecretName},
+				Selector:        map[string]string{"has-feature-x": "true"},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      dsName,
+				Namespace: namespace,
+			},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, moduleLoaderImage, mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		configHash, err := modprobeConfigHash(mod.Spec.ModuleLoader.Container.Modprobe, moduleName, kernelVersion)
+		Expect(err).NotTo(HaveOccurred())
+
+		podLabels := map[string]string{
+			constants.ModuleNameLabel: moduleName,
+			kernelLabel:               kernelVersion,
+			constants.DaemonSetRole:   "module-loader",
+		}
+
+		directory := v1.HostPathDirectory
+
+		expected := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      dsName,
+				Namespace: namespace,
+				Labels:    podLabels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         mod.APIVersion,
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Kind:               mod.Kind,
+						Name:               moduleName,
+						UID:                mod.UID,
+					},
+				},
+			},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{constants.ModprobeConfigHashAnnotation: configHash},
+						Finalizers:  []string{constants.NodeLabelerFinalizer},
+						Labels:      podLabels,
+					},
+					Spec: v1.PodSpec{
+						Affinity: &v1.Affinity{
+							NodeAffinity: &v1.NodeAffinity{
+								RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+									NodeSelectorTerms: []v1.NodeSelectorTerm{
+										{
+											MatchExpressions: []v1.NodeSelectorRequirement{
+												{
+													Key:      kernelLabel,
+													Operator: v1.NodeSelectorOpIn,
+													Values:   []string{kernelVersion},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						Containers: []v1.Container{
+							{
+								Name:  "module-loader",
+								Image: moduleLoaderImage,
+								Env: []v1.EnvVar{
+									{Name: "KERNEL_FULL_VERSION", Value: kernelVersion},
+								},
+								Lifecycle: &v1.Lifecycle{
+									PostStart: &v1.LifecycleHandler{
+										Exec: &v1.ExecAction{
+											Command: MakeLoadCommand(mod.Spec.ModuleLoader.Container.Modprobe, moduleName, kernelVersion),
+										},
+									},
+									PreStop: &v1.LifecycleHandler{
+										Exec: &v1.ExecAction{
+											Command: MakeUnloadCommand(mod.Spec.ModuleLoader.Container.Modprobe, moduleName, kernelVersion),
+										},
+									},
+								},
+								Command: []string{"sleep", "infinity"},
+								VolumeMounts: []v1.VolumeMount{
+									{
+										Name:      "node-lib-modules",
+										ReadOnly:  true,
+										MountPath: "/lib/modules",
+									},
+									{
+										Name:      "node-usr-lib-modules",
+										ReadOnly:  true,
+										MountPath: "/usr/lib/modules",
+									},
+								},
+								SecurityContext: &v1.SecurityContext{
+									AllowPrivilegeEscalation: pointer.Bool(false),
+									Capabilities: &v1.Capabilities{
+										Add: []v1.Capability{"SYS_MODULE"},
+									},
+									RunAsUser: pointer.Int64(0),
+									SELinuxOptions: &v1.SELinuxOptions{
+										Type: "spc_t",
+									},
+								},
+							},
+						},
+						ImagePullSecrets: []v1.LocalObjectReference{
+							{Name: imageRepoSecretName},
+						},
+						NodeSelector: map[string]string{
+							"has-feature-x": "true",
+							kernelLabel:     kernelVersion,
+						},
+						PriorityClassName:  "system-node-critical",
+						ServiceAccountName: serviceAccountName,
+						Volumes: []v1.Volume{
+							{
+								Name: "node-lib-modules",
+								VolumeSource: v1.VolumeSource{
+									HostPath: &v1.HostPathVolumeSource{
+										Path: "/lib/modules",
+										Type: &directory,
+									},
+								},
+							},
+							{
+								Name: "node-usr-lib-modules",
+								VolumeSource: v1.VolumeSource{
+									HostPath: &v1.HostPathVolumeSource{
+										Path: "/usr/lib/modules",
+										Type: &directory,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		Expect(
+			cmp.Equal(expected, ds),
+		).To(
+			BeTrue(), cmp.Diff(expected, ds),
+		)
+	})
+
+	Describe("ModuleDaemonSetsByKernelVersion", func() {
+		It("should return an empty map if no DaemonSets are present", func() {
+			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any())
+
+			dc := NewCreator(clnt, kernelLabel, scheme)
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+			}
+
+			m, _, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), mod.Name, mod.Namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(BeEmpty())
+		})
+
+		It("should return an error if two DaemonSets are present for the same kernel", func() {
+			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+			dc := NewCreator(clnt, kernelLabel, scheme)
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+			}
+
+			_, _, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), mod.Name, mod.Namespace)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should return a map if two DaemonSets are present for different kernels", func() {
+			const otherKernelVersion = "4.5.6"
+
+			ds1 := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ds1",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"kmm.node.kubernetes.io/module.name": moduleName,
+						kernelLabel:                          kernelVersion,
+					},
+				},
+			}
+
+			ds2 := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "ds2",
+					Namespace: namespace,
+					Labels: map[string]string{
+						"kmm.node.kubernetes.io/module.name": moduleName,
+						kernelLabel:                          otherKernelVersion,
+					},
+				},
+			}
+
+			clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+					list.Items = append(list.Items, ds1)
+					list.Items = append(list.Items, ds2)
+					return nil
+				},
+			)
+
+			dc := NewCreator(clnt, kernelLabel, scheme)
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+			}
+
+			m, _, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), mod.Name, mod.Namespace)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m).To(HaveLen(2))
+			Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
+			Expect(m).To(HaveKeyWithValue(otherKernelVersion, &ds2))
+		})
+	})
+})
+
+var _ = Describe("SetDevicePluginAsDesired", func() {
+	dg := NewCreator(nil, kernelLabel, scheme)
+
+	It("should return an error if the DaemonSet is nil", func() {
+		Expect(
+			dg.SetDevicePluginAsDesired(context.Background(), nil, &kmmv1beta1.Module{}),
+		).To(
+			HaveOccurred(),
+		)
+	})
+
+	It("should return an error if DevicePlugin not set in the Spec", func() {
+		ds := appsv1.DaemonSet{}
+		Expect(
+			dg.SetDevicePluginAsDesired(context.Background(), &ds, &kmmv1beta1.Module{}),
+		).To(
+			HaveOccurred(),
+		)
+	})
+
+	It("should add additional volumes if there are any", func() {
+		vol := v1.Volume{Name: "test-volume"}
+
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					Volumes:   []v1.Volume{vol},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(2))
+		Expect(ds.Spec.Template.Spec.Volumes[1]).To(Equal(vol))
+	})
+
+	It("should expand the host path mounts into volumes and volume mounts", func() {
+		vol := v1.Volume{Name: "test-volume"}
+		volm := v1.VolumeMount{Name: "test-volume", MountPath: "/test"}
+
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{
+						Image:        devicePluginImage,
+						VolumeMounts: []v1.VolumeMount{volm},
+					},
+					HostPathMounts: []kmmv1beta1.HostPathMount{
+						{HostPath: "/dev/vfio", MountPath: "/dev/vfio"},
+						{HostPath: "/etc/driver", MountPath: "/host/etc/driver", ReadOnly: true},
+					},
+					Volumes: []v1.Volume{vol},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		directory := v1.HostPathDirectory
+
+		Expect(ds.Spec.Template.Spec.Volumes).To(Equal([]v1.Volume{
+			{
+				Name: "kubelet-device-plugins",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: "/var/lib/kubelet/device-plugins", Type: &directory},
+				},
+			},
+			vol,
+			{
+				Name:         "host-path-0",
+				VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev/vfio"}},
+			},
+			{
+				Name:         "host-path-1",
+				VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/etc/driver"}},
+			},
+		}))
+
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(Equal([]v1.VolumeMount{
+			volm,
+			{Name: "kubelet-device-plugins", MountPath: "/var/lib/kubelet/device-plugins"},
+			{Name: "host-path-0", MountPath: "/dev/vfio"},
+			{Name: "host-path-1", MountPath: "/host/etc/driver", ReadOnly: true},
+		}))
+	})
+
+	It("should mount the module firmware read-only at the same path as the module loader", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container:           kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					MountModuleFirmware: true,
+				},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName:       "some-kmod",
+							FirmwarePath:     "/firmware",
+							FirmwareHostPath: "/opt/firmware",
+						},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		directoryOrCreate := v1.HostPathDirectoryOrCreate
+
+		Expect(ds.Spec.Template.Spec.Volumes).To(ContainElement(v1.Volume{
+			Name: "node-var-lib-firmware",
+			VolumeSource: v1.VolumeSource{
+				HostPath: &v1.HostPathVolumeSource{Path: "/opt/firmware/" + moduleName, Type: &directoryOrCreate},
+			},
+		}))
+
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(v1.VolumeMount{
+			Name:      "node-var-lib-firmware",
+			MountPath: "/opt/firmware/" + moduleName,
+			ReadOnly:  true,
+		}))
+
+		driverDS := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &driverDS, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(driverDS.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(v1.VolumeMount{
+			Name:      "node-var-lib-firmware",
+			MountPath: "/opt/firmware/" + moduleName,
+		}))
+	})
+
+	It("should not mount the module firmware by default", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwarePath: "/firmware"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Volumes).To(HaveLen(1))
+	})
+
+	It("should return an error if the module firmware is mounted but the module loader copies none", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container:           kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					MountModuleFirmware: true,
+				},
+			},
+		}
+
+		Expect(
+			dg.SetDevicePluginAsDesired(context.Background(), &appsv1.DaemonSet{}, &mod),
+		).To(
+			HaveOccurred(),
+		)
+	})
+
+	It("should return an error if a host path mount is not absolute", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					HostPathMounts: []kmmv1beta1.HostPathMount{
+						{HostPath: "dev/vfio", MountPath: "/dev/vfio"},
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		Expect(
+			dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod),
+		).To(
+			HaveOccurred(),
+		)
+	})
+
+	DescribeTable("should set the ServiceAccount token automount flag on the pod template",
+		func(automount *bool) {
+			mod := kmmv1beta1.Module{
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						AutomountServiceAccountToken: automount,
+						Container:                    kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{}
+
+			err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.AutomountServiceAccountToken).To(Equal(automount))
+		},
+		Entry("default", nil),
+		Entry("disabled", pointer.Bool(false)),
+		Entry("enabled", pointer.Bool(true)),
+	)
+
+	It("should project the ServiceAccount token if requested", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					ProjectedServiceAccountToken: &kmmv1beta1.ProjectedServiceAccountToken{
+						Audience:          "opa",
+						ExpirationSeconds: pointer.Int64(3600),
+						MountPath:         "/var/run/secrets/opa",
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.Spec.Template.Spec.Volumes).To(ContainElement(v1.Volume{
+			Name: "service-account-token",
+			VolumeSource: v1.VolumeSource{
+				Projected: &v1.ProjectedVolumeSource{
+					Sources: []v1.VolumeProjection{
+						{
+							ServiceAccountToken: &v1.ServiceAccountTokenProjection{
+								Audience:          "opa",
+								ExpirationSeconds: pointer.Int64(3600),
+								Path:              "token",
+							},
+						},
+					},
+				},
+			},
+		}))
+
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(v1.VolumeMount{
+			Name:      "service-account-token",
+			MountPath: "/var/run/secrets/opa",
+			ReadOnly:  true,
+		}))
+	})
+
+	It("should return an error if the projected ServiceAccount token mount path is not absolute", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container:                    kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					ProjectedServiceAccountToken: &kmmv1beta1.ProjectedServiceAccountToken{MountPath: "secrets"},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		Expect(
+			dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod),
+		).To(
+			HaveOccurred(),
+		)
+	})
+
+	It("should use the custom node label prefix in the node selector", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := NewCreator(nil, kernelLabel, scheme, WithNodeLabelPrefix("tenant-a.example.com")).
+			SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(
+			Equal(map[string]string{"tenant-a.example.com/" + moduleName + ".ready": ""}),
+		)
+	})
+
+	It("should merge the Module selector with the readiness label in the node selector", func() {
+		selector := map[string]string{"has-feature-x": "true", "zone": "a"}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+				Selector: selector,
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+			"has-feature-x": "true",
+			"zone":          "a",
+			getDriverContainerNodeLabel(DefaultNodeLabelPrefix, moduleName): "",
+		}))
+
+		// the Module's own selector must not be modified
+		Expect(selector).To(HaveLen(2))
+	})
+
+	It("should set the pod annotations without the reserved ones", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					PodAnnotations: map[string]string{
+						"sidecar.istio.io/inject":     "false",
+						"kmm.node.kubernetes.io/role": "x",
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Annotations).To(Equal(map[string]string{"sidecar.istio.io/inject": "false"}))
+	})
+
+	It("should not share the label maps between the DaemonSet, its selector and its pod template", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		expected := CopyMapStringString(ds.Spec.Selector.MatchLabels)
+
+		ds.Labels["a"] = "b"
+		ds.Spec.Template.Labels["c"] = "d"
+
+		Expect(ds.Spec.Selector.MatchLabels).To(Equal(expected))
+		Expect(ds.Spec.Template.Labels).NotTo(HaveKey("a"))
+		Expect(ds.Labels).NotTo(HaveKey("c"))
+	})
+
+	It("should only schedule the device plugin on nodes with the hardware on which the module is loaded", func() {
+		const hardwareLabel = "feature.node.kubernetes.io/pci-10de.present"
+
+		affinity := &v1.Affinity{
+			NodeAffinity: &v1.NodeAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []v1.PreferredSchedulingTerm{
+					{
+						Weight: 1,
+						Preference: v1.NodeSelectorTerm{
+							MatchExpressions: []v1.NodeSelectorRequirement{
+								{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"a"}},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Affinity:     affinity,
+					Container:    kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					NodeSelector: map[string]string{hardwareLabel: "true"},
+				},
+				Selector: map[string]string{"has-feature-x": "true"},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.Affinity).To(Equal(affinity))
+
+		readyLabel := getDriverContainerNodeLabel(DefaultNodeLabelPrefix, moduleName)
+
+		Expect(ds.Spec.Template.Spec.NodeSelector).To(Equal(map[string]string{
+			"has-feature-x": "true",
+			hardwareLabel:   "true",
+			readyLabel:      "",
+		}))
+
+		selector := labels.SelectorFromSet(ds.Spec.Template.Spec.NodeSelector)
+
+		Expect(selector.Matches(labels.Set{"has-feature-x": "true", hardwareLabel: "true"})).To(BeFalse())
+		Expect(selector.Matches(labels.Set{"has-feature-x": "true", readyLabel: ""})).To(BeFalse())
+		Expect(selector.Matches(labels.Set{"has-feature-x": "true", hardwareLabel: "true", readyLabel: ""})).To(BeTrue())
+	})
+
+	DescribeTable("should reject invalid device plugin node selectors",
+		func(nodeSelector map[string]string) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container:    kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+						NodeSelector: nodeSelector,
+					},
+					Selector: map[string]string{"has-feature-x": "true"},
+				},
+			}
+
+			Expect(
+				dg.SetDevicePluginAsDesired(context.Background(), &appsv1.DaemonSet{}, &mod),
+			).To(HaveOccurred())
+		},
+		Entry("conflict with the Module selector", map[string]string{"has-feature-x": "false"}),
+		Entry("readiness label", map[string]string{"kmm.node.kubernetes.io/" + moduleName + ".ready": "true"}),
+	)
+
+	It("should use the custom kubelet device plugins path", func() {
+		const k3sPath = "/var/lib/rancher/k3s/agent/kubelet/device-plugins"
+
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := NewCreator(nil, kernelLabel, scheme, WithKubeletDevicePluginsPath(k3sPath)).
+			SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		hostPathDirectory := v1.HostPathDirectory
+
+		Expect(ds.Spec.Template.Spec.Volumes).To(Equal([]v1.Volume{
+			{
+				Name: "kubelet-device-plugins",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: k3sPath,
+						Type: &hostPathDirectory,
+					},
+				},
+			},
+		}))
+
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(Equal([]v1.VolumeMount{
+			{
+				Name:      "kubelet-device-plugins",
+				MountPath: k3sPath,
+			},
+		}))
+	})
+
+	It("should use the configured device plugins volume source", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		src := v1.VolumeSource{EmptyDir: &v1.EmptyDirVolumeSource{}}
+
+		err := NewCreator(nil, kernelLabel, scheme, WithDevicePluginsVolumeSource(src)).
+			SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.Spec.Template.Spec.Volumes).To(Equal([]v1.Volume{
+			{
+				Name:         "kubelet-device-plugins",
+				VolumeSource: src,
+			},
+		}))
+
+		Expect(ds.Spec.Template.Spec.Containers[0].VolumeMounts).To(Equal([]v1.VolumeMount{
+			{
+				Name:      "kubelet-device-plugins",
+				MountPath: DefaultKubeletDevicePluginsPath,
+			},
+		}))
+	})
+
+	It("should use the configured module name label", func() {
+		const moduleNameLabel = "fork.example.com/module.name"
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+				},
+			},
+		}
+
+		dc := NewCreator(nil, kernelLabel, scheme, WithModuleNameLabel(moduleNameLabel))
+		Expect(dc.GetModuleNameLabel()).To(Equal(moduleNameLabel))
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dc.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Labels).To(HaveKeyWithValue(moduleNameLabel, moduleName))
+		Expect(ds.Labels).NotTo(HaveKey(constants.ModuleNameLabel))
+		Expect(ds.Spec.Selector.MatchLabels).To(HaveKeyWithValue(moduleNameLabel, moduleName))
+		Expect(ds.Spec.Template.Labels).To(HaveKeyWithValue(moduleNameLabel, moduleName))
+
+		dpDS := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err = dc.SetDevicePluginAsDesired(context.Background(), &dpDS, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dpDS.Labels).To(HaveKeyWithValue(moduleNameLabel, moduleName))
+		Expect(dpDS.Labels).NotTo(HaveKey(constants.ModuleNameLabel))
+		Expect(dpDS.Spec.Template.Labels).To(HaveKeyWithValue(moduleNameLabel, moduleName))
+	})
+
+	It("should set the update strategy of the DaemonSet", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.UpdateStrategy).To(Equal(appsv1.DaemonSetUpdateStrategy{}))
+
+		mod.Spec.DevicePlugin.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+			Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+				MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 50},
+			},
+		}
+
+		err = dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.UpdateStrategy).To(Equal(mod.Spec.DevicePlugin.UpdateStrategy))
+	})
+
+	It("should set the topology spread constraints unchanged", func() {
+		constraints := []v1.TopologySpreadConstraint{
+			{
+				MaxSkew:           1,
+				TopologyKey:       "topology.kubernetes.io/zone",
+				WhenUnsatisfiable: v1.ScheduleAnyway,
+				LabelSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{constants.ModuleNameLabel: moduleName},
+				},
+			},
+		}
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container:                 kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					TopologySpreadConstraints: constraints,
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.Template.Spec.TopologySpreadConstraints).To(Equal(constraints))
+	})
+
+	DescribeTable("should return an error if a topology spread constraint does not select the pods",
+		func(selector *metav1.LabelSelector) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+						TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+							{
+								MaxSkew:           1,
+								TopologyKey:       "topology.kubernetes.io/zone",
+								WhenUnsatisfiable: v1.DoNotSchedule,
+								LabelSelector:     selector,
+							},
+						},
+					},
+				},
+			}
+
+			Expect(
+				dg.SetDevicePluginAsDesired(context.Background(), &appsv1.DaemonSet{}, &mod),
+			).To(HaveOccurred())
+		},
+		Entry("no selector", nil),
+		Entry("other module", &metav1.LabelSelector{MatchLabels: map[string]string{constants.ModuleNameLabel: "other"}}),
+		Entry(
+			"invalid selector",
+			&metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "a", Operator: "Invalid"}},
+			},
+		),
+	)
+
+	It("should set the user labels on the DaemonSet without overriding the managed ones", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					DaemonSetLabels: map[string]string{
+						"team":                  "accelerators",
+						constants.DaemonSetRole: "other-role",
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		expected := map[string]string{
+			"team":                    "accelerators",
+			constants.DaemonSetRole:   "device-plugin",
+			constants.ModuleNameLabel: moduleName,
+		}
+
+		for i := 0; i < 2; i++ {
+			err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Labels).To(Equal(expected))
+		}
+	})
+
+	DescribeTable("should set the tolerations on the pod template",
+		func(tolerations []v1.Toleration) {
+			mod := kmmv1beta1.Module{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: kmmv1beta1.GroupVersion.String(),
+					Kind:       "Module",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container:   kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+						Tolerations: tolerations,
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Tolerations).To(Equal(tolerations))
+			Expect(ds.OwnerReferences).To(HaveLen(1))
+			Expect(ds.OwnerReferences[0].Name).To(Equal(moduleName))
+			Expect(*ds.OwnerReferences[0].Controller).To(BeTrue())
+		},
+		Entry("no tolerations", nil),
+		Entry(
+			"one toleration",
+			[]v1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: v1.TolerationOpEqual, Value: "present", Effect: v1.TaintEffectNoSchedule},
+			},
+		),
+		Entry(
+			"multiple tolerations",
+			[]v1.Toleration{
+				{Key: "nvidia.com/gpu", Operator: v1.TolerationOpEqual, Value: "present", Effect: v1.TaintEffectNoSchedule},
+				{Key: "node-role.kubernetes.io/master", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoExecute},
+			},
+		),
+	)
+
+	DescribeTable("should set the image pull secrets on the pod template",
+		func(secrets []v1.LocalObjectReference) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					},
+					ImageRepoSecrets: secrets,
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.ImagePullSecrets).To(Equal(secrets))
+		},
+		Entry("no secrets", nil),
+		Entry("one secret", []v1.LocalObjectReference{{Name: "secret1"}}),
+		Entry("multiple secrets", []v1.LocalObjectReference{{Name: "secret1"}, {Name: "secret2"}}),
+	)
+
+	DescribeTable("should set the security context of the container",
+		func(sc *v1.SecurityContext, expected *v1.SecurityContext) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container: kmmv1beta1.DevicePluginContainerSpec{
+							Image:           devicePluginImage,
+							SecurityContext: sc,
+						},
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.Containers[0].SecurityContext).To(Equal(expected))
+		},
+		Entry("default", nil, &v1.SecurityContext{Privileged: pointer.Bool(true)}),
+		Entry(
+			"explicit capabilities",
+			&v1.SecurityContext{
+				Capabilities: &v1.Capabilities{Add: []v1.Capability{"SYS_ADMIN", "SYS_RAWIO"}},
+			},
+			&v1.SecurityContext{
+				Capabilities: &v1.Capabilities{Add: []v1.Capability{"SYS_ADMIN", "SYS_RAWIO"}},
+			},
+		),
+		Entry(
+			"explicitly unprivileged",
+			&v1.SecurityContext{Privileged: pointer.Bool(false), RunAsUser: pointer.Int64(1000)},
+			&v1.SecurityContext{Privileged: pointer.Bool(false), RunAsUser: pointer.Int64(1000)},
+		),
+		Entry(
+			"other options merged with the privileged default",
+			&v1.SecurityContext{RunAsUser: pointer.Int64(0)},
+			&v1.SecurityContext{Privileged: pointer.Bool(true), RunAsUser: pointer.Int64(0)},
+		),
+	)
+
+	DescribeTable("should set the priority class name on the pod template",
+		func(priorityClassName, expected string) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container:         kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+						PriorityClassName: priorityClassName,
+					},
+				},
+			}
+
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.Template.Spec.PriorityClassName).To(Equal(expected))
+		},
+		Entry("default", "", "system-node-critical"),
+		Entry("overridden", "custom-priority", "custom-priority"),
+	)
+
+	It("should work as expected", func() {
+		const (
+			dsName             = "ds-name"
+			serviceAccountName = "some-service-account"
+		)
+
+		dpVol := v1.Volume{
+			Name:         "test-volume",
+			VolumeSource: v1.VolumeSource{},
+		}
+
+		dpVolMount := v1.VolumeMount{
+			Name:      "some-dp-volume-mount",
+			MountPath: "/some/path",
+		}
+
+		repoSecret := v1.LocalObjectReference{Name: "pull-secret-name"}
+
+		env := []v1.EnvVar{
+			{
+				Name:  "ENV_KEY",
+				Value: "ENV_VALUE",
+			},
+		}
+
+		resources := v1.ResourceRequirements{
+			Limits: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:    resource.MustParse("200m"),
+				v1.ResourceMemory: resource.MustParse("4G"),
+			},
+			Requests: map[v1.ResourceName]resource.Quantity{
+				v1.ResourceCPU:    resource.MustParse("100m"),
+				v1.ResourceMemory: resource.MustParse("2G"),
+			},
+		}
+
+		args := []string{"some", "args"}
+		command := []string{"some", "command"}
+
+		const ipp = v1.PullIfNotPresent
+
+		mod := kmmv1beta1.Module{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: kmmv1beta1.GroupVersion.String(),
+				Kind:       "Module",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{
+						Args:            args,
+						Command:         command,
+						Env:             env,
+						Image:           devicePluginImage,
+						ImagePullPolicy: ipp,
+						Resources:       resources,
+						VolumeMounts:    []v1.VolumeMount{dpVolMount},
+					},
+					ServiceAccountName: serviceAccountName,
+					Volumes:            []v1.Volume{dpVol},
+				},
+				ImageRepoSecret: &repoSecret,
+				Selector:        map[string]string{"has-feature-x": "true"},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      dsName,
+				Namespace: namespace,
+			},
+		}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		podLabels := map[string]string{
+			constants.ModuleNameLabel: moduleName,
+			constants.DaemonSetRole:   "device-plugin",
+		}
+
+		directory := v1.HostPathDirectory
+
+		expected := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      dsName,
+				Namespace: namespace,
+				Labels:    podLabels,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion:         mod.APIVersion,
+						BlockOwnerDeletion: pointer.Bool(true),
+						Controller:         pointer.Bool(true),
+						Kind:               mod.Kind,
+						Name:               moduleName,
+						UID:                mod.UID,
+					},
+				},
+			},
+			Spec: appsv1.DaemonSetSpec{
+				Selector: &metav1.LabelSelector{MatchLabels: podLabels},
+				Template: v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:     podLabels,
+						Finalizers: []string{constants.NodeLabelerFinalizer},
+					},
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{
+								Args:            args,
+								Command:         command,
+								Env:             env,
+								Image:           devicePluginImage,
+								ImagePullPolicy: ipp,
+								Name:            "device-plugin",
+								Resources:       resources,
+								SecurityContext: &v1.SecurityContext{
+									Privileged: pointer.Bool(true),
+								},
+								VolumeMounts: []v1.VolumeMount{
+									dpVolMount,
+									{
+										Name:      "kubelet-device-plugins",
+										MountPath: "/var/lib/kubelet/device-plugins",
+									},
+								},
 							},
 						},
 						ImagePullSecrets: []v1.LocalObjectReference{repoSecret},
 						NodeSelector: map[string]string{
-							getDriverContainerNodeLabel(mod.Name): "",
+							getDriverContainerNodeLabel(DefaultNodeLabelPrefix, mod.Name): "",
+							"has-feature-x": "true",
+						},
+						PriorityClassName:  "system-node-critical",
+						ServiceAccountName: serviceAccountName,
+						Volumes: []v1.Volume{
+							{
+								Name: "kubelet-device-plugins",
+								VolumeSource: v1.VolumeSource{
+									HostPath: &v1.HostPathVolumeSource{
+										Path: "/var/lib/kubelet/device-plugins",
+										Type: &directory,
+									},
+								},
+							},
+							dpVol,
+						},
+					},
+				},
+			},
+		}
+
+		Expect(
+			cmp.Equal(expected, ds),
+		).To(
+			BeTrue(), cmp.Diff(expected, ds),
+		)
+	})
+})
+
+var _ = Describe("SetDevicePluginPodDisruptionBudgetAsDesired", func() {
+	dg := NewCreator(nil, kernelLabel, scheme)
+
+	It("should return an error if the PodDisruptionBudget is nil", func() {
+		Expect(
+			dg.SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), nil, &kmmv1beta1.Module{}),
+		).To(HaveOccurred())
+	})
+
+	It("should return an error if no PodDisruptionBudget is configured", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{DevicePlugin: &kmmv1beta1.DevicePluginSpec{}},
+		}
+
+		Expect(
+			dg.SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), &policyv1.PodDisruptionBudget{}, &mod),
+		).To(HaveOccurred())
+	})
+
+	It("should return an error if maxUnavailable is invalid", func() {
+		mod := kmmv1beta1.Module{
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					PodDisruptionBudget: &kmmv1beta1.DevicePluginPodDisruptionBudget{
+						MaxUnavailable: intstr.FromString("150%"),
+					},
+				},
+			},
+		}
+
+		Expect(
+			dg.SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), &policyv1.PodDisruptionBudget{}, &mod),
+		).To(HaveOccurred())
+	})
+
+	It("should select the pods of the device plugin DaemonSet", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					PodDisruptionBudget: &kmmv1beta1.DevicePluginPodDisruptionBudget{
+						MaxUnavailable: intstr.FromString("25%"),
+					},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		pdb := policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err = dg.SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), &pdb, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		maxUnavailable := intstr.FromString("25%")
+
+		Expect(pdb.Spec.MaxUnavailable).To(Equal(&maxUnavailable))
+		Expect(pdb.Spec.Selector).To(Equal(ds.Spec.Selector))
+		Expect(pdb.Labels).To(Equal(ds.Spec.Template.Labels))
+
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(selector.Matches(labels.Set(ds.Spec.Template.Labels))).To(BeTrue())
+
+		Expect(pdb.OwnerReferences).To(HaveLen(1))
+		Expect(pdb.OwnerReferences[0].Name).To(Equal(moduleName))
+	})
+
+	It("should annotate the objects with the Module instead of owning them if they are in another namespace", func() {
+		const dpNamespace = "device-plugin-namespace"
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					Namespace: dpNamespace,
+					PodDisruptionBudget: &kmmv1beta1.DevicePluginPodDisruptionBudget{
+						MaxUnavailable: intstr.FromInt(1),
+					},
+				},
+			},
+		}
+
+		Expect(DevicePluginNamespace(&mod)).To(Equal(dpNamespace))
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: dpNamespace},
+		}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		pdb := policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: dpNamespace},
+		}
+
+		err = dg.SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), &pdb, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		owner := namespace + "/" + moduleName
+
+		Expect(ds.OwnerReferences).To(BeEmpty())
+		Expect(ds.Annotations).To(HaveKeyWithValue(constants.OwnerAnnotation, owner))
+		Expect(pdb.OwnerReferences).To(BeEmpty())
+		Expect(pdb.Annotations).To(HaveKeyWithValue(constants.OwnerAnnotation, owner))
+	})
+
+	It("should own the objects without annotating them if they are in the Module's namespace", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					Namespace: namespace,
+					PodDisruptionBudget: &kmmv1beta1.DevicePluginPodDisruptionBudget{
+						MaxUnavailable: intstr.FromInt(1),
+					},
+				},
+			},
+		}
+
+		Expect(DevicePluginNamespace(&mod)).To(Equal(namespace))
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		pdb := policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err = dg.SetDevicePluginPodDisruptionBudgetAsDesired(context.Background(), &pdb, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ds.OwnerReferences).To(HaveLen(1))
+		Expect(ds.Annotations).NotTo(HaveKey(constants.OwnerAnnotation))
+		Expect(pdb.OwnerReferences).To(HaveLen(1))
+		Expect(pdb.Annotations).NotTo(HaveKey(constants.OwnerAnnotation))
+	})
+})
+
+var _ = Describe("DeleteCrossNamespaceDevicePlugins", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	owner := namespace + "/" + moduleName
+
+	makeMeta := func(ns, owner string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{
+			Name:        moduleName + "-device-plugin",
+			Namespace:   ns,
+			Annotations: map[string]string{constants.OwnerAnnotation: owner},
+		}
+	}
+
+	It("should return an error if the DaemonSets cannot be listed", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, &appsv1.DaemonSetList{}, gomock.Any()).Return(errors.New("some error"))
+
+		_, err := NewCreator(clnt, kernelLabel, scheme).DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should only delete the objects annotated with the Module outside of keepNamespace", func() {
+		ctx := context.Background()
+
+		dsOwned := appsv1.DaemonSet{ObjectMeta: makeMeta("ns-0", owner)}
+		dsKept := appsv1.DaemonSet{ObjectMeta: makeMeta("ns-1", owner)}
+		dsOther := appsv1.DaemonSet{ObjectMeta: makeMeta("ns-2", "other/module")}
+		pdbOwned := policyv1.PodDisruptionBudget{ObjectMeta: makeMeta("ns-0", owner)}
+
+		gomock.InOrder(
+			clnt.EXPECT().List(ctx, &appsv1.DaemonSetList{}, gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *appsv1.DaemonSetList, opts ...ctrlclient.ListOption) error {
+					Expect(opts).To(Equal([]ctrlclient.ListOption{
+						ctrlclient.MatchingLabels{
+							constants.ModuleNameLabel: moduleName,
+							constants.DaemonSetRole:   constants.DevicePluginRole,
+						},
+					}))
+
+					list.Items = []appsv1.DaemonSet{dsOwned, dsKept, dsOther}
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, &policyv1.PodDisruptionBudgetList{}, gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *policyv1.PodDisruptionBudgetList, _ ...ctrlclient.ListOption) error {
+					list.Items = []policyv1.PodDisruptionBudget{pdbOwned}
+					return nil
+				},
+			),
+			clnt.EXPECT().Delete(ctx, &dsOwned).Return(apierrors.NewNotFound(schema.GroupResource{}, dsOwned.Name)),
+			clnt.EXPECT().Delete(ctx, &pdbOwned),
+		)
+
+		deleted, err := NewCreator(clnt, kernelLabel, scheme).DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "ns-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deleted).To(Equal([]string{"ns-0/" + moduleName + "-device-plugin"}))
+	})
+
+	It("should return an error if an object cannot be deleted", func() {
+		ctx := context.Background()
+
+		ds := appsv1.DaemonSet{ObjectMeta: makeMeta("ns-0", owner)}
+
+		gomock.InOrder(
+			clnt.EXPECT().List(ctx, &appsv1.DaemonSetList{}, gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *appsv1.DaemonSetList, _ ...ctrlclient.ListOption) error {
+					list.Items = []appsv1.DaemonSet{ds}
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, &policyv1.PodDisruptionBudgetList{}, gomock.Any()),
+			clnt.EXPECT().Delete(ctx, &ds).Return(errors.New("some error")),
+		)
+
+		deleted, err := NewCreator(clnt, kernelLabel, scheme).DeleteCrossNamespaceDevicePlugins(ctx, moduleName, namespace, "")
+		Expect(err).To(HaveOccurred())
+		Expect(deleted).To(BeEmpty())
+	})
+})
+
+var _ = Describe("GarbageCollect", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	It("should only delete one of the two DaemonSets if only one is not used", func() {
+		const (
+			legitKernelVersion    = "legit-kernel-version"
+			legitName             = "legit"
+			notLegitKernelVersion = "not-legit-kernel-version"
+			notLegitName          = "not-legit"
+		)
+
+		dsLegit := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: legitName, Namespace: namespace, Labels: map[string]string{kernelLabel: legitKernelVersion}},
+		}
+
+		dsNotLegit := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: notLegitName, Namespace: namespace, Labels: map[string]string{kernelLabel: notLegitKernelVersion}},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsNotLegit).AnyTimes()
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			legitKernelVersion:    &dsLegit,
+			notLegitKernelVersion: &dsNotLegit,
+		}
+
+		validKernels := sets.NewString(legitKernelVersion)
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, validKernels, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{notLegitName}))
+	})
+
+	It("should delete the DaemonSets in parallel up to the concurrency limit and aggregate the errors", func() {
+		const concurrency = 2
+
+		existingDS := make(map[string]*appsv1.DaemonSet)
+
+		for i := 0; i < 6; i++ {
+			kv := fmt.Sprintf("stale-kernel-%d", i)
+
+			existingDS[kv] = &appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      fmt.Sprintf("ds-%d", i),
+					Namespace: namespace,
+					Labels:    map[string]string{kernelLabel: kv},
+				},
+			}
+		}
+
+		var (
+			mu          sync.Mutex
+			arrived     int
+			inFlight    int
+			maxInFlight int
+		)
+
+		// released once the first concurrency deletions are in flight at the same time
+		barrier := make(chan struct{})
+
+		clnt.
+			EXPECT().
+			Delete(context.Background(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, obj ctrlclient.Object, _ ...ctrlclient.DeleteOption) error {
+				mu.Lock()
+				arrived++
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				if arrived == concurrency {
+					close(barrier)
+				}
+				held := arrived <= concurrency
+				mu.Unlock()
+
+				// do not hang if the deletions are not parallel; maxInFlight then reveals it
+				if held {
+					select {
+					case <-barrier:
+					case <-time.After(5 * time.Second):
+					}
+				}
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+
+				if name := obj.GetName(); name == "ds-1" || name == "ds-4" {
+					return fmt.Errorf("could not delete %s", name)
+				}
+
+				return nil
+			}).
+			Times(len(existingDS))
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithGarbageCollectionConcurrency(concurrency))
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString("valid-kernel"), false)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("could not delete ds-1"))
+		Expect(err.Error()).To(ContainSubstring("could not delete ds-4"))
+		Expect(res.Failed).To(HaveLen(2))
+		Expect(res.Failed).To(HaveKey("ds-1"))
+		Expect(res.Failed).To(HaveKey("ds-4"))
+		Expect(res.Deleted).To(ConsistOf("ds-0", "ds-2", "ds-3", "ds-5"))
+		Expect(maxInFlight).To(Equal(concurrency))
+	})
+
+	DescribeTable("should default the concurrency limit",
+		func(opts []Option, expected int) {
+			dc := NewCreator(clnt, kernelLabel, scheme, opts...).(*daemonSetGenerator)
+			Expect(dc.gcConcurrency).To(Equal(expected))
+		},
+		Entry("no option", nil, DefaultGarbageCollectionConcurrency),
+		Entry("zero", []Option{WithGarbageCollectionConcurrency(0)}, DefaultGarbageCollectionConcurrency),
+		Entry("explicit value", []Option{WithGarbageCollectionConcurrency(1)}, 1),
+	)
+
+	It("should not delete pinned DaemonSets", func() {
+		dsPinned := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pinned",
+				Namespace:   namespace,
+				Labels:      map[string]string{kernelLabel: "pinned-kernel-version"},
+				Annotations: map[string]string{constants.PinAnnotation: "true"},
+			},
+		}
+
+		dsUnpinned := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "unpinned",
+				Namespace:   namespace,
+				Labels:      map[string]string{kernelLabel: "unpinned-kernel-version"},
+				Annotations: map[string]string{constants.PinAnnotation: "false"},
+			},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsUnpinned)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"pinned-kernel-version":   &dsPinned,
+			"unpinned-kernel-version": &dsUnpinned,
+		}
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(kernelVersion), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{"unpinned"}))
+		Expect(dc.GarbageCollectPlan(existingDS, sets.NewString(kernelVersion), false)).To(Equal([]string{"unpinned"}))
+	})
+
+	It("should classify the DaemonSets by their role label rather than by their kernel version", func() {
+		dsDriver := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "driver-without-kernel",
+				Namespace: namespace,
+				Labels:    map[string]string{kernelLabel: "", constants.DaemonSetRole: constants.ModuleLoaderRole},
+			},
+		}
+
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "device-plugin",
+				Namespace: namespace,
+				Labels:    map[string]string{constants.DaemonSetRole: constants.DevicePluginRole},
+			},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsDriver)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		res, err := dc.GarbageCollect(context.Background(), map[string]*appsv1.DaemonSet{"": &dsDriver}, sets.NewString(kernelVersion), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{"driver-without-kernel"}))
+
+		res, err = dc.GarbageCollect(context.Background(), map[string]*appsv1.DaemonSet{"": &dsDevicePlugin}, sets.NewString(kernelVersion), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(BeEmpty())
+	})
+
+	It("should keep the module loader DaemonSets if there are too few valid kernel versions", func() {
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-plugin", Namespace: namespace},
+		}
+
+		dsStale := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: namespace, Labels: map[string]string{kernelLabel: kernelVersion}},
+		}
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"":            &dsDevicePlugin,
+			kernelVersion: &dsStale,
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsDevicePlugin)
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithGarbageCollectionMinValidKernels(1))
+
+		Expect(dc.GarbageCollectPlan(existingDS, sets.NewString(), false)).To(Equal([]string{"device-plugin"}))
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), false)
+		Expect(err).To(MatchError(ErrGarbageCollectionSafeguard))
+		Expect(res.Deleted).To(Equal([]string{"device-plugin"}))
+		Expect(res.Failed).To(BeEmpty())
+	})
+
+	It("should delete stale module loader DaemonSets if there are enough valid kernel versions", func() {
+		dsStale := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale", Namespace: namespace, Labels: map[string]string{kernelLabel: kernelVersion}},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsStale)
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithGarbageCollectionMinValidKernels(1))
+
+		res, err := dc.GarbageCollect(context.Background(), map[string]*appsv1.DaemonSet{kernelVersion: &dsStale}, sets.NewString("4.5.6"), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{"stale"}))
+	})
+
+	It("should delete the device plugin DaemonSet if the device plugin was removed from the Module", func() {
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-plugin", Namespace: namespace},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsDevicePlugin)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{"": &dsDevicePlugin}
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{"device-plugin"}))
+	})
+
+	It("should keep the device plugin DaemonSet and delete driver containers for invalid kernels", func() {
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-plugin", Namespace: namespace},
+		}
+
+		dsNotLegit := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-legit", Namespace: namespace, Labels: map[string]string{kernelLabel: kernelVersion}},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsNotLegit)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"":            &dsDevicePlugin,
+			kernelVersion: &dsNotLegit,
+		}
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{"not-legit"}))
+	})
+
+	It("should log each deletion with its reason", func() {
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-plugin", Namespace: namespace},
+		}
+
+		dsNotLegit := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-legit", Namespace: namespace, Labels: map[string]string{kernelLabel: kernelVersion}},
+		}
+
+		dsFailed := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "failed", Namespace: namespace, Labels: map[string]string{kernelLabel: "4.5.6"}},
+		}
+
+		lines := make([]string, 0)
+
+		logger := funcr.New(
+			func(_, args string) {
+				lines = append(lines, args)
+			},
+			funcr.Options{},
+		)
+
+		ctx := log.IntoContext(context.Background(), logger)
+
+		clnt.EXPECT().Delete(ctx, &dsDevicePlugin)
+		clnt.EXPECT().Delete(ctx, &dsNotLegit)
+		clnt.EXPECT().Delete(ctx, &dsFailed).Return(errors.New("some error"))
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"":            &dsDevicePlugin,
+			kernelVersion: &dsNotLegit,
+			"4.5.6":       &dsFailed,
+		}
+
+		_, err := dc.GarbageCollect(ctx, existingDS, sets.NewString(), false)
+		Expect(err).To(HaveOccurred())
+		Expect(lines).To(ConsistOf(
+			`"level"=0 "msg"="Garbage-collected DaemonSet" "name"="device-plugin" "kernel version"="" "reason"="device plugin disabled"`,
+			`"level"=0 "msg"="Garbage-collected DaemonSet" "name"="not-legit" "kernel version"="1.2.3" "reason"="kernel version no longer targeted"`,
+		))
+	})
+
+	It("should return an error if a deletion failed", func() {
+		clnt.EXPECT().Delete(context.Background(), gomock.Any()).Return(
+			errors.New("client returns some error"),
+		)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		dsNotLegit := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace", Labels: map[string]string{kernelLabel: "kernel version"}},
+		}
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"some-kernel-version": &dsNotLegit,
+		}
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), false)
+		Expect(err).To(HaveOccurred())
+		Expect(res.Failed).To(HaveKey("name"))
+	})
+
+	It("should increment the garbage collection counter for each deleted DaemonSet", func() {
+		reg := prometheus.NewRegistry()
+		RegisterMetrics(reg)
+
+		counter := garbageCollectedDaemonSets.WithLabelValues(moduleName, namespace)
+		before := testutil.ToFloat64(counter)
+
+		dsLabels := map[string]string{constants.ModuleNameLabel: moduleName, kernelLabel: kernelVersion}
+
+		ds1 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "ds1", Namespace: namespace, Labels: dsLabels},
+		}
+
+		ds2 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "ds2", Namespace: namespace, Labels: dsLabels},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), gomock.Any()).Times(2)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"kernel-1": &ds1,
+			"kernel-2": &ds2,
+		}
+
+		_, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.ToFloat64(counter) - before).To(Equal(float64(2)))
+
+		n, err := testutil.GatherAndCount(reg, "kmmo_garbage_collected_daemonsets_total")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n).NotTo(BeZero())
+	})
+
+	It("should keep deleting DaemonSets after a deletion failed", func() {
+		dsFailing := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "failing", Namespace: namespace, Labels: map[string]string{kernelLabel: "kernel-1"}},
+		}
+
+		dsDeleted := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "deleted", Namespace: namespace, Labels: map[string]string{kernelLabel: "kernel-2"}},
+		}
+
+		deleteErr := errors.New("client returns some error")
+
+		clnt.EXPECT().Delete(context.Background(), &dsFailing).Return(deleteErr)
+		clnt.EXPECT().Delete(context.Background(), &dsDeleted)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"kernel-1": &dsFailing,
+			"kernel-2": &dsDeleted,
+		}
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), false)
+		Expect(err).To(HaveOccurred())
+		Expect(res.Deleted).To(Equal([]string{"deleted"}))
+		Expect(res.Failed).To(Equal(map[string]error{"failing": deleteErr}))
+	})
+
+	It("should only delete the DaemonSets that have been stale for longer than the retention window", func() {
+		const (
+			justStaleKernelVersion = "just-stale-kernel-version"
+			longStaleKernelVersion = "long-stale-kernel-version"
+			noAnnotationKernel     = "no-annotation-kernel-version"
+		)
+
+		now := time.Now()
+
+		dsJustStale := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "just-stale",
+				Namespace:   namespace,
+				Labels:      map[string]string{kernelLabel: justStaleKernelVersion},
+				Annotations: map[string]string{constants.LastSeenValidAnnotation: now.Add(-time.Minute).UTC().Format(time.RFC3339)},
+			},
+		}
+
+		dsLongStale := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "long-stale",
+				Namespace:   namespace,
+				Labels:      map[string]string{kernelLabel: longStaleKernelVersion},
+				Annotations: map[string]string{constants.LastSeenValidAnnotation: now.Add(-time.Hour).UTC().Format(time.RFC3339)},
+			},
+		}
+
+		dsNoAnnotation := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "no-annotation",
+				Namespace: namespace,
+				Labels:    map[string]string{kernelLabel: noAnnotationKernel},
+			},
+		}
+
+		clnt.EXPECT().Delete(context.Background(), &dsLongStale)
+		clnt.EXPECT().Delete(context.Background(), &dsNoAnnotation)
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithGarbageCollectionRetention(10*time.Minute))
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			justStaleKernelVersion: &dsJustStale,
+			longStaleKernelVersion: &dsLongStale,
+			noAnnotationKernel:     &dsNoAnnotation,
+		}
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString(), false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(ConsistOf("long-stale", "no-annotation"))
+		Expect(res.RetainedUntil).To(BeTemporally("~", now.Add(10*time.Minute), time.Second))
+	})
+})
+
+var _ = Describe("GarbageCollectPlan", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	It("should return the DaemonSets that GarbageCollect deletes without deleting them", func() {
+		dsLegit := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "legit", Namespace: namespace, Labels: map[string]string{kernelLabel: "legit-kernel"}},
+		}
+
+		dsNotLegit1 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-legit-1", Namespace: namespace, Labels: map[string]string{kernelLabel: "kernel-1"}},
+		}
+
+		dsNotLegit2 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-legit-2", Namespace: namespace, Labels: map[string]string{kernelLabel: "kernel-2"}},
+		}
+
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-plugin", Namespace: namespace},
+		}
+
+		existingDS := map[string]*appsv1.DaemonSet{
+			"legit-kernel": &dsLegit,
+			"kernel-1":     &dsNotLegit1,
+			"kernel-2":     &dsNotLegit2,
+			"":             &dsDevicePlugin,
+		}
+
+		validKernels := sets.NewString("legit-kernel")
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		// no Delete call is expected on the client in dry-run
+		plan := dc.GarbageCollectPlan(existingDS, validKernels, true)
+		Expect(plan).To(Equal([]string{"not-legit-1", "not-legit-2"}))
+
+		clnt.EXPECT().Delete(context.Background(), gomock.Any()).Times(2)
+
+		res, err := dc.GarbageCollect(context.Background(), existingDS, validKernels, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Deleted).To(ConsistOf(plan))
+	})
+
+	It("should include the device plugin DaemonSet if the device plugin is not enabled", func() {
+		dsDevicePlugin := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "device-plugin", Namespace: namespace},
+		}
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		Expect(
+			dc.GarbageCollectPlan(map[string]*appsv1.DaemonSet{"": &dsDevicePlugin}, sets.NewString(), false),
+		).To(
+			Equal([]string{"device-plugin"}),
+		)
+	})
+})
+
+var _ = Describe("DuplicateDaemonSetError", func() {
+	It("should be extractable from a wrapped error", func() {
+		err := fmt.Errorf("could not reconcile: %w", &DuplicateDaemonSetError{
+			KernelVersion: kernelVersion,
+			Names:         []string{"ds-1", "ds-2"},
+		})
+
+		var dupErr *DuplicateDaemonSetError
+
+		Expect(errors.As(err, &dupErr)).To(BeTrue())
+		Expect(dupErr.KernelVersion).To(Equal(kernelVersion))
+		Expect(dupErr.Names).To(Equal([]string{"ds-1", "ds-2"}))
+		Expect(dupErr.Error()).To(Equal(`multiple DaemonSets found for kernel "1.2.3": ds-1, ds-2`))
+	})
+})
+
+var _ = Describe("AllModuleDaemonSets", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	It("should return an error if the DaemonSets cannot be listed", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+		_, err := NewCreator(clnt, kernelLabel, scheme).AllModuleDaemonSets(ctx)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should group the DaemonSets of all namespaces by Module", func() {
+		makeDS := func(name, ns, modName string) appsv1.DaemonSet {
+			return appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: ns,
+					Labels:    map[string]string{constants.ModuleNameLabel: modName},
+				},
+			}
+		}
+
+		ds0 := makeDS("ds-0", "ns-0", moduleName)
+		ds1 := makeDS("ds-1", "ns-0", moduleName)
+		ds2 := makeDS("ds-2", "ns-0", "other-module")
+		ds3 := makeDS("ds-3", "ns-1", moduleName)
+
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, opts ...ctrlclient.ListOption) error {
+				Expect(opts).To(Equal([]ctrlclient.ListOption{ctrlclient.HasLabels{constants.ModuleNameLabel}}))
+
+				list.Items = []appsv1.DaemonSet{ds0, ds1, ds2, ds3}
+				return nil
+			},
+		)
+
+		m, err := NewCreator(clnt, kernelLabel, scheme).AllModuleDaemonSets(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(Equal(map[types.NamespacedName][]appsv1.DaemonSet{
+			{Namespace: "ns-0", Name: moduleName}:     {ds0, ds1},
+			{Namespace: "ns-0", Name: "other-module"}: {ds2},
+			{Namespace: "ns-1", Name: moduleName}:     {ds3},
+		}))
+	})
+})
+
+var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	It("should return an empty map if no DaemonSets are present", func() {
+		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any())
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, _, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(BeEmpty())
+	})
+
+	It("should only list the DaemonSets bearing the configured module name label", func() {
+		const moduleNameLabel = "fork.example.com/module.name"
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds",
+				Namespace: namespace,
+				Labels: map[string]string{
+					moduleNameLabel: moduleName,
+					kernelLabel:     kernelVersion,
+				},
+			},
+		}
+
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, opts ...ctrlclient.ListOption) error {
+				Expect(opts).To(ContainElement(ctrlclient.MatchingLabels{moduleNameLabel: moduleName}))
+
+				list.Items = []appsv1.DaemonSet{ds}
+				return nil
+			},
+		)
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithModuleNameLabel(moduleNameLabel))
+
+		m, _, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(Equal(map[string]*appsv1.DaemonSet{kernelVersion: &ds}))
+	})
+
+	It("should keep the newest DaemonSet if two DaemonSets are present for the same kernel", func() {
+		dsLabels := map[string]string{
+			"kmm.node.kubernetes.io/module.name": moduleName,
+			kernelLabel:                          kernelVersion,
+		}
+
+		now := metav1.Now()
+
+		dsOld := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "ds-old",
+				Namespace:         namespace,
+				Labels:            dsLabels,
+				CreationTimestamp: metav1.NewTime(now.Add(-time.Hour)),
+			},
+		}
+
+		dsNew := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "ds-new",
+				Namespace:         namespace,
+				Labels:            dsLabels,
+				CreationTimestamp: now,
+			},
+		}
+
+		ctx := context.Background()
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{dsNew, dsOld}
+				return nil
+			},
+		)
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, duplicates, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(HaveLen(1))
+		Expect(m).To(HaveKeyWithValue(kernelVersion, &dsNew))
+		Expect(duplicates).To(Equal([]*appsv1.DaemonSet{&dsOld}))
+	})
+
+	It("should key DaemonSets by the real kernel version when the label was sanitized", func() {
+		const debugKernelVersion = "5.14.0-284.11.1.el9_2.x86_64+debug"
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "ds-debug",
+				Namespace:   namespace,
+				Annotations: map[string]string{constants.KernelVersionAnnotation: debugKernelVersion},
+				Labels: map[string]string{
+					"kmm.node.kubernetes.io/module.name": moduleName,
+					kernelLabel:                          KernelLabelValue(debugKernelVersion),
+				},
+			},
+		}
+
+		ctx := context.Background()
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{ds}
+				return nil
+			},
+		)
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, _, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(HaveLen(1))
+		Expect(m).To(HaveKeyWithValue(debugKernelVersion, &ds))
+	})
+
+	It("should key DaemonSets of the same kernel by architecture", func() {
+		dsLabels := func(arch string) map[string]string {
+			return map[string]string{
+				"kmm.node.kubernetes.io/module.name": moduleName,
+				kernelLabel:                          kernelVersion,
+				constants.ArchLabel:                  arch,
+			}
+		}
+
+		dsAMD64 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "ds-amd64", Namespace: namespace, Labels: dsLabels("amd64")},
+		}
+
+		dsARM64 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "ds-arm64", Namespace: namespace, Labels: dsLabels("arm64")},
+		}
+
+		// created before the architecture label was introduced
+		dsLegacy := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds-legacy",
+				Namespace: namespace,
+				Labels: map[string]string{
+					"kmm.node.kubernetes.io/module.name": moduleName,
+					kernelLabel:                          "4.5.6",
+				},
+			},
+			Spec: appsv1.DaemonSetSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						NodeSelector: map[string]string{v1.LabelArchStable: "s390x"},
+					},
+				},
+			},
+		}
+
+		ctx := context.Background()
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{dsAMD64, dsARM64, dsLegacy}
+				return nil
+			},
+		)
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, duplicates, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(duplicates).To(BeEmpty())
+		Expect(m).To(Equal(map[string]*appsv1.DaemonSet{
+			kernelVersion + "/amd64": &dsAMD64,
+			kernelVersion + "/arm64": &dsARM64,
+			"4.5.6/s390x":            &dsLegacy,
+		}))
+	})
+
+	It("should use the UID as a tiebreaker if two DaemonSets were created at the same time", func() {
+		dsLabels := map[string]string{
+			"kmm.node.kubernetes.io/module.name": moduleName,
+			kernelLabel:                          kernelVersion,
+		}
+
+		now := metav1.Now()
+
+		ds1 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "ds1",
+				Namespace:         namespace,
+				Labels:            dsLabels,
+				CreationTimestamp: now,
+				UID:               "bbb",
+			},
+		}
+
+		ds2 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "ds2",
+				Namespace:         namespace,
+				Labels:            dsLabels,
+				CreationTimestamp: now,
+				UID:               "aaa",
+			},
+		}
+
+		ctx := context.Background()
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{ds1, ds2}
+				return nil
+			},
+		)
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, duplicates, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds2))
+		Expect(duplicates).To(Equal([]*appsv1.DaemonSet{&ds1}))
+	})
+
+	It("should return a map if two DaemonSets are present for different kernels", func() {
+		const otherKernelVersion = "4.5.6"
+
+		ds1 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds1",
+				Namespace: namespace,
+				Labels: map[string]string{
+					"kmm.node.kubernetes.io/module.name": moduleName,
+					kernelLabel:                          kernelVersion,
+				},
+			},
+		}
+
+		ds2 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds2",
+				Namespace: namespace,
+				Labels: map[string]string{
+					"kmm.node.kubernetes.io/module.name": moduleName,
+					kernelLabel:                          otherKernelVersion,
+				},
+			},
+		}
+
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{ds1, ds2}
+				return nil
+			},
+		)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, _, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(HaveLen(2))
+		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
+		Expect(m).To(HaveKeyWithValue(otherKernelVersion, &ds2))
+	})
+
+	It("should include a map entry for device plugin", func() {
+		ds1 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds1",
+				Namespace: namespace,
+				Labels: map[string]string{
+					"kmm.node.kubernetes.io/module.name": moduleName,
+					kernelLabel:                          kernelVersion,
+				},
+			},
+		}
+
+		ds2 := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds2",
+				Namespace: namespace,
+				Labels: map[string]string{
+					"kmm.node.kubernetes.io/module.name": moduleName,
+				},
+			},
+		}
+
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{ds1, ds2}
+				return nil
+			},
+		)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		m, _, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(m).To(HaveLen(2))
+		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
+		Expect(m).To(HaveKeyWithValue(devicePluginKernelVersion, &ds2))
+
+		Expect(testutil.ToFloat64(moduleDaemonSets.WithLabelValues(moduleName, namespace))).To(Equal(float64(2)))
+		Expect(testutil.ToFloat64(moduleKernelVersions.WithLabelValues(moduleName, namespace))).To(Equal(float64(1)))
+	})
+})
+
+var _ = Describe("WaitForModuleDaemonSetsReady", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	makeDS := func(kernelVersion string, ready, desired int32) appsv1.DaemonSet {
+		return appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "ds-" + kernelVersion,
+				Namespace: namespace,
+				Labels: map[string]string{
+					constants.ModuleNameLabel: moduleName,
+					kernelLabel:               kernelVersion,
+				},
+			},
+			Status: appsv1.DaemonSetStatus{
+				DesiredNumberScheduled: desired,
+				NumberReady:            ready,
+			},
+		}
+	}
+
+	newCreator := func() DaemonSetCreator {
+		dc := NewCreator(clnt, kernelLabel, scheme).(*daemonSetGenerator)
+		dc.readyPollInterval = time.Millisecond
+
+		return dc
+	}
+
+	It("should return once all DaemonSets are ready", func() {
+		ctx := context.Background()
+
+		statuses := [][]appsv1.DaemonSet{
+			{makeDS("k1", 0, 2), makeDS("k2", 0, 3)},
+			{makeDS("k1", 2, 2), makeDS("k2", 1, 3)},
+			{makeDS("k1", 2, 2), makeDS("k2", 3, 3)},
+		}
+
+		calls := make([]*gomock.Call, 0, len(statuses))
+
+		for _, s := range statuses {
+			items := s
+
+			calls = append(
+				calls,
+				clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+						list.Items = items
+						return nil
+					},
+				),
+			)
+		}
+
+		gomock.InOrder(calls...)
+
+		Expect(
+			newCreator().WaitForModuleDaemonSetsReady(ctx, moduleName, namespace, time.Minute),
+		).NotTo(
+			HaveOccurred(),
+		)
+	})
+
+	It("should return an error listing the lagging kernels on timeout", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
+				list.Items = []appsv1.DaemonSet{makeDS("k1", 2, 2), makeDS("k2", 1, 3)}
+				return nil
+			},
+		).MinTimes(1)
+
+		err := newCreator().WaitForModuleDaemonSetsReady(ctx, moduleName, namespace, 20*time.Millisecond)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("k2 (1/3 ready)"))
+		Expect(err.Error()).NotTo(ContainSubstring("k1"))
+	})
+
+	It("should return an error if the DaemonSets cannot be listed", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+		Expect(
+			newCreator().WaitForModuleDaemonSetsReady(ctx, moduleName, namespace, time.Minute),
+		).To(
+			HaveOccurred(),
+		)
+	})
+})
+
+// applyServerDefaults sets the fields of ds that the API server defaults when a DaemonSet is created.
+func applyServerDefaults(ds *appsv1.DaemonSet) {
+	ds.Spec.RevisionHistoryLimit = pointer.Int32(10)
+
+	if ds.Spec.UpdateStrategy.Type == "" {
+		ds.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+			Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+				MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+				MaxSurge:       &intstr.IntOrString{Type: intstr.Int, IntVal: 0},
+			},
+		}
+	}
+
+	ps := &ds.Spec.Template.Spec
+
+	ps.RestartPolicy = v1.RestartPolicyAlways
+	ps.SchedulerName = v1.DefaultSchedulerName
+
+	if ps.DNSPolicy == "" {
+		ps.DNSPolicy = v1.DNSClusterFirst
+	}
+
+	if ps.TerminationGracePeriodSeconds == nil {
+		ps.TerminationGracePeriodSeconds = pointer.Int64(30)
+	}
+
+	if ps.SecurityContext == nil {
+		ps.SecurityContext = &v1.PodSecurityContext{}
+	}
+
+	for i := range ps.Volumes {
+		vs := &ps.Volumes[i].VolumeSource
+
+		if vs.HostPath != nil && vs.HostPath.Type == nil {
+			hostPathUnset := v1.HostPathUnset
+			vs.HostPath.Type = &hostPathUnset
+		}
+
+		if vs.ConfigMap != nil && vs.ConfigMap.DefaultMode == nil {
+			vs.ConfigMap.DefaultMode = pointer.Int32(v1.ConfigMapVolumeSourceDefaultMode)
+		}
+	}
+
+	for _, containers := range [][]v1.Container{ps.InitContainers, ps.Containers} {
+		for i := range containers {
+			c := &containers[i]
+
+			c.TerminationMessagePath = v1.TerminationMessagePathDefault
+			c.TerminationMessagePolicy = v1.TerminationMessageReadFile
+
+			if c.ImagePullPolicy == "" {
+				c.ImagePullPolicy = defaultImagePullPolicy(c.Image)
+			}
+
+			for _, p := range []*v1.Probe{c.LivenessProbe, c.ReadinessProbe, c.StartupProbe} {
+				if p != nil && p.SuccessThreshold == 0 {
+					p.SuccessThreshold = 1
+				}
+			}
+		}
+	}
+}
+
+var _ = Describe("preserveDefaultedFields", func() {
+	dg := NewCreator(nil, kernelLabel, scheme)
+
+	It("should keep the server-defaulted fields of the module loader DaemonSet", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					InitContainers: []v1.Container{{Name: "verify", Image: "verifier:1.0"}},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		applyServerDefaults(&ds)
+		defaulted := ds.DeepCopy()
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec).To(Equal(defaulted.Spec))
+	})
+
+	It("should keep the server-defaulted fields of the device plugin DaemonSet", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+			Spec: kmmv1beta1.ModuleSpec{
+				DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+					Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{}
+
+		err := dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+
+		applyServerDefaults(&ds)
+		defaulted := ds.DeepCopy()
+
+		err = dg.SetDevicePluginAsDesired(context.Background(), &ds, &mod)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec).To(Equal(defaulted.Spec))
+	})
+
+	It("should reset the fields that are no longer set by the Module", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						ImagePullPolicy: v1.PullNever,
+						Modprobe: kmmv1beta1.ModprobeSpec{
+							ModuleName:               "some-kmod",
+							UnloadGracePeriodSeconds: pointer.Int64(60),
+						},
+					},
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType},
+				},
+			},
+		}
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		applyServerDefaults(&ds)
+
+		mod.Spec.ModuleLoader.Container.ImagePullPolicy = ""
+		mod.Spec.ModuleLoader.Container.Modprobe.UnloadGracePeriodSeconds = nil
+		mod.Spec.ModuleLoader.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{}
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ds.Spec.UpdateStrategy).To(Equal(appsv1.DaemonSetUpdateStrategy{}))
+		Expect(ds.Spec.Template.Spec.TerminationGracePeriodSeconds).To(BeNil())
+		Expect(ds.Spec.Template.Spec.Containers[0].ImagePullPolicy).To(BeEmpty())
+		Expect(ds.Spec.RevisionHistoryLimit).To(Equal(pointer.Int32(10)))
+	})
+
+	DescribeTable("defaultImagePullPolicy",
+		func(image string, expected v1.PullPolicy) {
+			Expect(defaultImagePullPolicy(image)).To(Equal(expected))
+		},
+		Entry("no tag", "example.org/repo/image", v1.PullAlways),
+		Entry("latest tag", "example.org/repo/image:latest", v1.PullAlways),
+		Entry("other tag", "example.org/repo/image:1.0", v1.PullIfNotPresent),
+		Entry(
+			"digest",
+			"example.org/repo/image@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+			v1.PullIfNotPresent,
+		),
+	)
+})
+
+var _ = Describe("DaemonSetNeedsUpdate", func() {
+	dg := NewCreator(nil, kernelLabel, scheme)
+
+	DescribeTable("should compare the fields managed by KMM",
+		func(mutate func(*appsv1.DaemonSet), expected bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						},
+					},
+					Selector: map[string]string{"has-feature-x": "true"},
+				},
+			}
+
+			desired := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &desired, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			live := desired.DeepCopy()
+			mutate(live)
+
+			Expect(DaemonSetNeedsUpdate(live, &desired)).To(Equal(expected))
+		},
+		Entry("equal DaemonSets", func(*appsv1.DaemonSet) {}, false),
+		Entry(
+			"unmanaged fields only",
+			func(ds *appsv1.DaemonSet) {
+				ds.Labels["added-by-someone-else"] = ""
+				ds.ResourceVersion = "123"
+				ds.Spec.Template.Spec.DNSPolicy = v1.DNSClusterFirst
+			},
+			false,
+		),
+		Entry("image", func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Containers[0].Image = "other-image" }, true),
+		Entry("label", func(ds *appsv1.DaemonSet) { ds.Labels[constants.DaemonSetRole] = "other" }, true),
+		Entry("missing label", func(ds *appsv1.DaemonSet) { delete(ds.Labels, kernelLabel) }, true),
+		Entry("pod template label", func(ds *appsv1.DaemonSet) { ds.Spec.Template.Labels["a"] = "b" }, true),
+		Entry("node selector", func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.NodeSelector["a"] = "b" }, true),
+		Entry("volumes", func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Volumes = nil }, true),
+		Entry(
+			"modprobe configuration hash",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Annotations[constants.ModprobeConfigHashAnnotation] = "other"
+			},
+			true,
+		),
+		Entry(
+			"lifecycle commands",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Containers[0].Lifecycle.PostStart.Exec.Command = []string{"true"}
+			},
+			true,
+		),
+		Entry(
+			"defaulted update strategy",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{
+					Type: appsv1.RollingUpdateDaemonSetStrategyType,
+					RollingUpdate: &appsv1.RollingUpdateDaemonSet{
+						MaxUnavailable: &intstr.IntOrString{Type: intstr.Int, IntVal: 1},
+					},
+				}
+			},
+			false,
+		),
+		Entry(
+			"containers",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Containers = append(ds.Spec.Template.Spec.Containers, v1.Container{})
+			},
+			true,
+		),
+		Entry(
+			"init containers",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.InitContainers = []v1.Container{{Name: "verify", Image: "verifier"}}
+			},
+			true,
+		),
+		Entry(
+			"pod template annotation added by someone else",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Annotations["added-by-someone-else"] = "" },
+			false,
+		),
+		Entry("server defaults", applyServerDefaults, false),
+		Entry(
+			"container command",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Containers[0].Command = []string{"sleep"} },
+			true,
+		),
+		Entry(
+			"container args",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Containers[0].Args = []string{"infinity"} },
+			true,
+		),
+		Entry(
+			"container env",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{{Name: "a", Value: "b"}}
+			},
+			true,
+		),
+		Entry(
+			"container resources",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Containers[0].Resources = v1.ResourceRequirements{
+					Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+				}
+			},
+			true,
+		),
+		Entry(
+			"container security context",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Containers[0].SecurityContext = nil },
+			true,
+		),
+		Entry(
+			"container volume mounts",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Containers[0].VolumeMounts = nil },
+			true,
+		),
+		Entry(
+			"container readiness probe",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Containers[0].ReadinessProbe = &v1.Probe{PeriodSeconds: 10}
+			},
+			true,
+		),
+		Entry(
+			"container liveness probe",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Containers[0].LivenessProbe = &v1.Probe{PeriodSeconds: 10}
+			},
+			true,
+		),
+		Entry(
+			"container image pull policy",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.Containers[0].ImagePullPolicy = v1.PullNever },
+			true,
+		),
+		Entry(
+			"tolerations",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Tolerations = []v1.Toleration{{Key: "a", Operator: v1.TolerationOpExists}}
+			},
+			true,
+		),
+		Entry(
+			"affinity",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.Affinity = &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{}}
+			},
+			true,
+		),
+		Entry(
+			"service account name",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.ServiceAccountName = "other" },
+			true,
+		),
+		Entry(
+			"image pull secrets",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.ImagePullSecrets = []v1.LocalObjectReference{{Name: "other"}}
+			},
+			true,
+		),
+		Entry("host network", func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.HostNetwork = true }, true),
+		Entry(
+			"priority class name",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.PriorityClassName = "other" },
+			true,
+		),
+		Entry(
+			"pod security context",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.SecurityContext = &v1.PodSecurityContext{RunAsUser: pointer.Int64(1000)}
+			},
+			true,
+		),
+		Entry(
+			"termination grace period",
+			func(ds *appsv1.DaemonSet) { ds.Spec.Template.Spec.TerminationGracePeriodSeconds = pointer.Int64(60) },
+			true,
+		),
+		Entry(
+			"DNS config",
+			func(ds *appsv1.DaemonSet) {
+				ds.Spec.Template.Spec.DNSConfig = &v1.PodDNSConfig{Nameservers: []string{"1.1.1.1"}}
+			},
+			true,
+		),
+	)
+
+	It("should ignore the fields defaulted by the API server in the probes and volumes", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe:       kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						ReadinessProbe: &kmmv1beta1.ReadinessProbeSpec{},
+						LivenessProbe:  &kmmv1beta1.LivenessProbeSpec{},
+						StartupProbe:   &kmmv1beta1.StartupProbeSpec{},
+					},
+					ModprobeConfigMap: &v1.LocalObjectReference{Name: "modprobe-config"},
+				},
+			},
+		}
+
+		desired := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &desired, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		live := desired.DeepCopy()
+		applyServerDefaults(live)
+
+		Expect(live.Spec.Template.Spec).NotTo(Equal(desired.Spec.Template.Spec))
+		Expect(DaemonSetNeedsUpdate(live, &desired)).To(BeFalse())
+
+		live.Spec.Template.Spec.Containers[0].ReadinessProbe.SuccessThreshold = 2
+
+		Expect(DaemonSetNeedsUpdate(live, &desired)).To(BeTrue())
+	})
+
+	DescribeTable("should require an update when only the module parameters change",
+		func(disableLifecycleHooks bool) {
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      moduleName,
+					Namespace: namespace,
+				},
+				Spec: kmmv1beta1.ModuleSpec{
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							DisableLifecycleHooks: disableLifecycleHooks,
+							Modprobe: kmmv1beta1.ModprobeSpec{
+								ModuleName: "some-kmod",
+								Parameters: []string{"a=1"},
+							},
+						},
+					},
+				},
+			}
+
+			live := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dg.SetDriverContainerAsDesired(context.Background(), &live, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			mod.Spec.ModuleLoader.Container.Modprobe.Parameters = []string{"a=2"}
+
+			desired := live.DeepCopy()
+
+			err = dg.SetDriverContainerAsDesired(context.Background(), desired, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(desired.Spec.Template).NotTo(Equal(live.Spec.Template))
+			Expect(
+				desired.Spec.Template.Annotations[constants.ModprobeConfigHashAnnotation],
+			).NotTo(
+				Equal(live.Spec.Template.Annotations[constants.ModprobeConfigHashAnnotation]),
+			)
+			Expect(DaemonSetNeedsUpdate(&live, desired)).To(BeTrue())
+		},
+		Entry("with lifecycle hooks", false),
+		Entry("without lifecycle hooks", true),
+	)
+
+	It("should require an update when the module loader version changes", func() {
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+						Version:  "abc123",
+					},
+				},
+			},
+		}
+
+		live := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		}
+
+		err := dg.SetDriverContainerAsDesired(context.Background(), &live, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		desired := live.DeepCopy()
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), desired, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(DaemonSetNeedsUpdate(&live, desired)).To(BeFalse())
+
+		mod.Spec.ModuleLoader.Container.Version = "def456"
+
+		err = dg.SetDriverContainerAsDesired(context.Background(), desired, "test-image", mod, kernelVersion, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(DaemonSetNeedsUpdate(&live, desired)).To(BeTrue())
+	})
+
+	Context("with the OnDelete update strategy", func() {
+		var dc DaemonSetCreator
+
+		BeforeEach(func() {
+			ctrl = gomock.NewController(GinkgoT())
+			clnt = client.NewMockClient(ctrl)
+
+			// no expectations are set: any call to the API server, such as a pod deletion, fails the test
+			dc = NewCreator(clnt, kernelLabel, scheme)
+		})
+
+		mod := kmmv1beta1.Module{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      moduleName,
+				Namespace: namespace,
+			},
+			Spec: kmmv1beta1.ModuleSpec{
+				ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+					Container: kmmv1beta1.ModuleLoaderContainerSpec{
+						Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
+					},
+					UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType},
+				},
+			},
+		}
+
+		It("should take the strategy from the Module", func() {
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dc.SetDriverContainerAsDesired(context.Background(), &ds, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ds.Spec.UpdateStrategy).To(Equal(mod.Spec.ModuleLoader.UpdateStrategy))
+		})
+
+		It("should replace the server-defaulted RollingUpdate strategy of an existing DaemonSet", func() {
+			live := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			modRollingUpdate := *mod.DeepCopy()
+			modRollingUpdate.Spec.ModuleLoader.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{}
+
+			err := dc.SetDriverContainerAsDesired(context.Background(), &live, "test-image", modRollingUpdate, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			applyServerDefaults(&live)
+			Expect(live.Spec.UpdateStrategy.Type).To(Equal(appsv1.RollingUpdateDaemonSetStrategyType))
+
+			desired := live.DeepCopy()
+
+			err = dc.SetDriverContainerAsDesired(context.Background(), desired, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(desired.Spec.UpdateStrategy).To(Equal(mod.Spec.ModuleLoader.UpdateStrategy))
+			Expect(DaemonSetNeedsUpdate(&live, desired)).To(BeTrue())
+		})
+
+		It("should detect a strategy change", func() {
+			desired := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dc.SetDriverContainerAsDesired(context.Background(), &desired, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			live := desired.DeepCopy()
+			live.Spec.UpdateStrategy = appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType}
+
+			Expect(DaemonSetNeedsUpdate(live, &desired)).To(BeTrue())
+		})
+
+		It("should only report the template change, without touching the pods", func() {
+			live := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+			}
+
+			err := dc.SetDriverContainerAsDesired(context.Background(), &live, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+
+			applyServerDefaults(&live)
+
+			desired := live.DeepCopy()
+
+			err = dc.SetDriverContainerAsDesired(context.Background(), desired, "test-image-v2", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(DaemonSetNeedsUpdate(&live, desired)).To(BeTrue())
+			Expect(desired.Spec.Template.Spec.Containers[0].Image).To(Equal("test-image-v2"))
+			Expect(desired.Spec.UpdateStrategy).To(Equal(live.Spec.UpdateStrategy))
+
+			// only the image differs: applying desired changes the template, and the pods keep running
+			live.Spec.Template.Spec.Containers[0].Image = "test-image-v2"
+			Expect(DaemonSetNeedsUpdate(&live, desired)).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("MissingKernelDaemonSets", func() {
+	ds := &appsv1.DaemonSet{}
+
+	DescribeTable("should return the kernel versions without a DaemonSet",
+		func(existing map[string]*appsv1.DaemonSet, observed, expected []string) {
+			Expect(
+				MissingKernelDaemonSets(existing, sets.NewString(observed...)),
+			).To(
+				Equal(sets.NewString(expected...)),
+			)
+		},
+		Entry("no DaemonSets", nil, []string{"k1", "k2"}, []string{"k1", "k2"}),
+		Entry("no observed kernels", map[string]*appsv1.DaemonSet{"k1/amd64": ds}, nil, nil),
+		Entry(
+			"overlapping sets",
+			map[string]*appsv1.DaemonSet{"k1/amd64": ds, "k2/amd64": ds},
+			[]string{"k2", "k3"},
+			[]string{"k3"},
+		),
+		Entry(
+			"disjoint sets",
+			map[string]*appsv1.DaemonSet{"k1/amd64": ds},
+			[]string{"k2", "k3"},
+			[]string{"k2", "k3"},
+		),
+		Entry(
+			"kernel on several architectures",
+			map[string]*appsv1.DaemonSet{"k1/amd64": ds, "k1/arm64": ds},
+			[]string{"k1", "k2"},
+			[]string{"k2"},
+		),
+		Entry(
+			"DaemonSet created before the architecture was part of the key",
+			map[string]*appsv1.DaemonSet{"k1": ds},
+			[]string{"k1", "k2"},
+			[]string{"k2"},
+		),
+		Entry(
+			"device plugin DaemonSet",
+			map[string]*appsv1.DaemonSet{"": ds, "k1/amd64": ds},
+			[]string{"", "k1", "k2"},
+			[]string{"k2"},
+		),
+	)
+})
+
+var _ = Describe("DaemonSetStatusByKernel", func() {
+	makeDS := func(desired, ready, available int32) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			Status: appsv1.DaemonSetStatus{
+				DesiredNumberScheduled: desired,
+				NumberReady:            ready,
+				NumberAvailable:        available,
+			},
+		}
+	}
+
+	It("should return an empty map if there are no DaemonSets", func() {
+		Expect(DaemonSetStatusByKernel(nil)).To(BeEmpty())
+	})
+
+	It("should return the status of each module loader DaemonSet", func() {
+		existing := map[string]*appsv1.DaemonSet{
+			"":         makeDS(3, 3, 3),
+			"ready":    makeDS(3, 3, 3),
+			"rolling":  makeDS(3, 2, 1),
+			"starting": makeDS(2, 0, 0),
+			"nowhere":  makeDS(0, 0, 0),
+		}
+
+		Expect(
+			DaemonSetStatusByKernel(existing),
+		).To(
+			Equal(map[string]DSStatus{
+				"ready":    {Desired: 3, Ready: 3, Available: 3},
+				"rolling":  {Desired: 3, Ready: 2, Available: 1},
+				"starting": {Desired: 2},
+				"nowhere":  {},
+			}),
+		)
+	})
+
+	It("should sum the statuses of the DaemonSets running the same kernel on different architectures", func() {
+		existing := map[string]*appsv1.DaemonSet{
+			"k1/amd64": makeDS(3, 3, 3),
+			"k1/arm64": makeDS(2, 1, 1),
+			"k2/amd64": makeDS(1, 0, 0),
+			"k3":       makeDS(1, 1, 1),
+		}
+
+		Expect(
+			DaemonSetStatusByKernel(existing),
+		).To(
+			Equal(map[string]DSStatus{
+				"k1": {Desired: 5, Ready: 4, Available: 4},
+				"k2": {Desired: 1},
+				"k3": {Desired: 1, Ready: 1, Available: 1},
+			}),
+		)
+	})
+})
+
+var _ = Describe("KernelVersionFromKey", func() {
+	DescribeTable("should return the kernel version",
+		func(kernelVersion, arch string) {
+			Expect(
+				KernelVersionFromKey(DaemonSetKey(kernelVersion, arch)),
+			).To(
+				Equal(kernelVersion),
+			)
+		},
+		Entry("no architecture", "5.14.0-284.el9.x86_64", ""),
+		Entry("architecture", "5.14.0-284.el9.x86_64", "amd64"),
+		Entry("device plugin", "", ""),
+	)
+})
+
+var _ = Describe("StaleImageDaemonSets", func() {
+	makeNamedDS := func(name, containerName, image string) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: appsv1.DaemonSetSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
+						Containers: []v1.Container{
+							{Name: containerName, Image: image},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	makeDS := func(name, image string) *appsv1.DaemonSet {
+		return makeNamedDS(name, "module-loader", image)
+	}
+
+	DescribeTable("should return the DaemonSets running a stale image",
+		func(existing map[string]*appsv1.DaemonSet, desiredImages map[string]string, expected []string) {
+			Expect(
+				StaleImageDaemonSets(existing, desiredImages, "module-loader"),
+			).To(
+				Equal(expected),
+			)
+		},
+		Entry("no DaemonSets", nil, map[string]string{"k1": "image-v1"}, []string{}),
+		Entry(
+			"matching images",
+			map[string]*appsv1.DaemonSet{"k1": makeDS("ds-1", "image-v1"), "k2": makeDS("ds-2", "image-v2")},
+			map[string]string{"k1": "image-v1", "k2": "image-v2"},
+			[]string{},
+		),
+		Entry(
+			"mismatching images",
+			map[string]*appsv1.DaemonSet{
+				"k1": makeDS("ds-1", "image-v1"),
+				"k2": makeDS("ds-2", "image-v1"),
+				"k3": makeDS("ds-3", "image-v1"),
+			},
+			map[string]string{"k1": "image-v1", "k2": "image-v2", "k3": "image-v2"},
+			[]string{"ds-2", "ds-3"},
+		),
+		Entry(
+			"missing desired image",
+			map[string]*appsv1.DaemonSet{"k1": makeDS("ds-1", "image-v1"), "k2": makeDS("ds-2", "image-v1")},
+			map[string]string{"k2": "image-v2"},
+			[]string{"ds-2"},
+		),
+		Entry(
+			"missing module loader container",
+			map[string]*appsv1.DaemonSet{"k1": {ObjectMeta: metav1.ObjectMeta{Name: "ds-1"}}},
+			map[string]string{"k1": "image-v1"},
+			[]string{"ds-1"},
+		),
+		Entry(
+			"device plugin DaemonSet",
+			map[string]*appsv1.DaemonSet{"": makeDS("ds-dp", "device-plugin")},
+			map[string]string{"": "image-v1"},
+			[]string{},
+		),
+		Entry(
+			"images per architecture",
+			map[string]*appsv1.DaemonSet{
+				"k1/amd64": makeDS("ds-1-amd64", "image-amd64-v1"),
+				"k1/arm64": makeDS("ds-1-arm64", "image-arm64-v1"),
+			},
+			map[string]string{"k1/amd64": "image-amd64-v1", "k1/arm64": "image-arm64-v2"},
+			[]string{"ds-1-arm64"},
+		),
+		Entry(
+			"image for all architectures",
+			map[string]*appsv1.DaemonSet{
+				"k1/amd64": makeDS("ds-1-amd64", "image-v1"),
+				"k1/arm64": makeDS("ds-1-arm64", "image-v2"),
+				"k2/amd64": makeDS("ds-2-amd64", "image-v1"),
+			},
+			map[string]string{"k1": "image-v2", "k2/amd64": "image-v1"},
+			[]string{"ds-1-amd64"},
+		),
+	)
+
+	It("should look up the module loader container by the given name", func() {
+		existing := map[string]*appsv1.DaemonSet{
+			"k1": makeNamedDS("ds-1", "kmm-loader", "image-v1"),
+			"k2": makeNamedDS("ds-2", "kmm-loader", "image-v1"),
+		}
+
+		Expect(
+			StaleImageDaemonSets(existing, map[string]string{"k1": "image-v1", "k2": "image-v2"}, "kmm-loader"),
+		).To(
+			Equal([]string{"ds-2"}),
+		)
+	})
+})
+
+var _ = Describe("GetPodPullSecrets", func() {
+	It("should return nil if the secret is nil", func() {
+		Expect(
+			GetPodPullSecrets(nil),
+		).To(
+			BeNil(),
+		)
+	})
+
+	It("should a slice with the secret inside", func() {
+		lor := v1.LocalObjectReference{Name: "test"}
+
+		Expect(
+			GetPodPullSecrets(&lor),
+		).To(
+			Equal([]v1.LocalObjectReference{lor}),
+		)
+	})
+
+	It("should return the additional secrets if the secret is nil", func() {
+		secrets := []v1.LocalObjectReference{{Name: "test1"}, {Name: "test2"}}
+
+		Expect(
+			GetPodPullSecrets(nil, secrets...),
+		).To(
+			Equal(secrets),
+		)
+	})
+
+	It("should return the secret first followed by the additional secrets, without duplicates", func() {
+		lor := v1.LocalObjectReference{Name: "test"}
+
+		Expect(
+			GetPodPullSecrets(&lor, v1.LocalObjectReference{Name: "test1"}, v1.LocalObjectReference{Name: "test"}),
+		).To(
+			Equal([]v1.LocalObjectReference{lor, {Name: "test1"}}),
+		)
+	})
+})
+
+var _ = Describe("OverrideLabels", func() {
+	It("should create a labels map if it was empty", func() {
+		overrides := map[string]string{"a": "b"}
+
+		Expect(
+			OverrideLabels(nil, overrides),
+		).To(
+			Equal(overrides),
+		)
+	})
+
+	It("should override existing values", func() {
+		labels := map[string]string{"a": "b", "c": "d"}
+		overrides := map[string]string{"a": "z"}
+
+		Expect(
+			OverrideLabels(labels, overrides),
+		).To(
+			Equal(map[string]string{"a": "z", "c": "d"}),
+		)
+	})
+
+	It("should not modify its arguments", func() {
+		labels := map[string]string{"a": "b", "c": "d"}
+		overrides := map[string]string{"a": "z", "e": "f"}
+
+		merged := OverrideLabels(labels, overrides)
+		Expect(merged).To(Equal(map[string]string{"a": "z", "c": "d", "e": "f"}))
+		Expect(labels).To(Equal(map[string]string{"a": "b", "c": "d"}))
+		Expect(overrides).To(Equal(map[string]string{"a": "z", "e": "f"}))
+
+		merged["g"] = "h"
+		Expect(labels).NotTo(HaveKey("g"))
+	})
+})
+
+var _ = Describe("KernelVersion", func() {
+	dc := NewCreator(nil, kernelLabel, scheme)
+
+	It("should return the kernel version of a driver container DaemonSet", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					constants.ModuleNameLabel: moduleName,
+					kernelLabel:               kernelVersion,
+				},
+			},
+		}
+
+		Expect(dc.KernelVersion(&ds)).To(Equal(kernelVersion))
+	})
+
+	It("should return an empty string for a device plugin DaemonSet", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{constants.ModuleNameLabel: moduleName},
+			},
+		}
+
+		kv := dc.KernelVersion(&ds)
+		Expect(kv).To(BeEmpty())
+		Expect(IsDevicePluginKernelVersion(kv)).To(BeTrue())
+	})
+
+	It("should prefer the kernel version annotation over the sanitized label", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{constants.KernelVersionAnnotation: "5.14.0-284.11.1.el9_2.x86_64+debug"},
+				Labels: map[string]string{
+					constants.ModuleNameLabel: moduleName,
+					kernelLabel:               KernelLabelValue("5.14.0-284.11.1.el9_2.x86_64+debug"),
+				},
+			},
+		}
+
+		Expect(dc.KernelVersion(&ds)).To(Equal("5.14.0-284.11.1.el9_2.x86_64+debug"))
+	})
+})
+
+var _ = Describe("KernelLabelValue", func() {
+	It("should return valid label values unchanged", func() {
+		Expect(KernelLabelValue("4.18.0-305.45.1.el8_4.x86_64")).To(Equal("4.18.0-305.45.1.el8_4.x86_64"))
+	})
+
+	DescribeTable("should return valid and distinct label values",
+		func(kernelVersion string) {
+			v := KernelLabelValue(kernelVersion)
+
+			Expect(validation.IsValidLabelValue(v)).To(BeEmpty())
+			Expect(v).NotTo(Equal(KernelLabelValue(kernelVersion + "x")))
+			Expect(KernelLabelValue(kernelVersion)).To(Equal(v))
+		},
+		Entry("debug kernel", "5.14.0-284.11.1.el9_2.x86_64+debug"),
+		Entry("64k pages kernel", "5.14.0-284.11.1.el9_2.aarch64+64k"),
+		Entry("real-time kernel", "4.18.0-372.9.1.rt7.166.el8.x86_64+rt"),
+		Entry("long kernel", "5.15.0-1034-azure-fips-with-a-very-long-local-version-suffix.x86_64"),
+		Entry("long kernel with invalid characters", "6.1.0-rc7+gf3b4c2a1d9e8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4+"),
+	)
+})
+
+var _ = Describe("DaemonSetName", func() {
+	It("should be deterministic and start with the module name", func() {
+		name := DaemonSetName(moduleName, "4.18.0-305.45.1.el8_4.x86_64", "")
+
+		Expect(name).To(Equal(DaemonSetName(moduleName, "4.18.0-305.45.1.el8_4.x86_64", "")))
+		Expect(name).To(HavePrefix(moduleName + "-4-18-0-305-45-1-el8-4-x86-64-"))
+	})
+
+	It("should include the architecture in the name", func() {
+		name := DaemonSetName(moduleName, "5.14.0-284.el9", "arm64")
+
+		Expect(name).To(HavePrefix(moduleName + "-5-14-0-284-el9-arm64-"))
+		Expect(name).NotTo(Equal(DaemonSetName(moduleName, "5.14.0-284.el9", "amd64")))
+		Expect(name).NotTo(Equal(DaemonSetName(moduleName, "5.14.0-284.el9", "")))
+	})
+
+	It("should return distinct names for module and kernel versions that sanitize identically", func() {
+		Expect(DaemonSetName("a", "b-c", "")).NotTo(Equal(DaemonSetName("a-b", "c", "")))
+		Expect(DaemonSetName(moduleName, "1.2.3", "")).NotTo(Equal(DaemonSetName(moduleName, "1_2_3", "")))
+	})
+
+	DescribeTable("should return valid and distinct names",
+		func(kernelVersion string) {
+			name := DaemonSetName(moduleName, kernelVersion, "")
+
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+			Expect(name).NotTo(Equal(DaemonSetName(moduleName, kernelVersion+"x", "")))
+			Expect(name).NotTo(Equal(DaemonSetName(moduleName+"x", kernelVersion, "")))
+		},
+		Entry("normal kernel", "5.14.0-284.11.1.el9_2.x86_64"),
+		Entry("debug kernel", "5.14.0-284.11.1.el9_2.x86_64+debug"),
+		Entry("upper-case kernel", "5.15.0-1034-Azure"),
+		Entry("long kernel", "5.15.0-1034-azure-fips-with-a-very-long-local-version-suffix.x86_64"),
+		Entry("long kernel with invalid characters", "6.1.0-rc7+gf3b4c2a1d9e8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4+"),
+		Entry("kernel with only invalid characters", "+++"),
+	)
+})
+
+var _ = Describe("KernelLabel", func() {
+	It("should return the kernel label passed to NewCreator", func() {
+		Expect(NewCreator(nil, kernelLabel, scheme).KernelLabel()).To(Equal(kernelLabel))
+	})
+})
+
+var _ = Describe("GetNodeLabelFromPod", func() {
+	var dc DaemonSetCreator
+
+	BeforeEach(func() {
+		dc = NewCreator(clnt, kernelLabel, scheme)
+	})
+
+	It("should return a driver container label", func() {
+		pod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					constants.ModuleNameLabel: moduleName,
+					kernelLabel:               "some kernel",
+				},
+			},
+		}
+		res := dc.GetNodeLabelFromPod(&pod, "module-name")
+		Expect(res).To(Equal(getDriverContainerNodeLabel(DefaultNodeLabelPrefix, "module-name")))
+	})
+
+	It("should return a device plugin label", func() {
+		pod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{
+					constants.ModuleNameLabel: moduleName,
+				},
+			},
+		}
+		res := dc.GetNodeLabelFromPod(&pod, "module-name")
+		Expect(res).To(Equal(getDevicePluginNodeLabel(DefaultNodeLabelPrefix, "module-name")))
+	})
+
+	DescribeTable("should return the node label matching the role of the pod",
+		func(labels map[string]string, devicePlugin bool) {
+			pod := v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+			}
+
+			expected := getDriverContainerNodeLabel(DefaultNodeLabelPrefix, "module-name")
+			if devicePlugin {
+				expected = getDevicePluginNodeLabel(DefaultNodeLabelPrefix, "module-name")
+			}
+
+			Expect(dc.GetNodeLabelFromPod(&pod, "module-name")).To(Equal(expected))
+		},
+		Entry(
+			"module loader role",
+			map[string]string{constants.DaemonSetRole: constants.ModuleLoaderRole, kernelLabel: "some kernel"},
+			false,
+		),
+		Entry(
+			"module loader role without kernel label",
+			map[string]string{constants.DaemonSetRole: constants.ModuleLoaderRole},
+			false,
+		),
+		Entry(
+			"device plugin role",
+			map[string]string{constants.DaemonSetRole: constants.DevicePluginRole},
+			true,
+		),
+		Entry(
+			"device plugin role with a kernel label",
+			map[string]string{constants.DaemonSetRole: constants.DevicePluginRole, kernelLabel: "some kernel"},
+			true,
+		),
+		Entry("no role and a kernel label", map[string]string{kernelLabel: "some kernel"}, false),
+		Entry("no role and no kernel label", map[string]string{}, true),
+		Entry("unknown role and a kernel label", map[string]string{constants.DaemonSetRole: "other", kernelLabel: "some kernel"}, false),
+	)
+
+	It("should use a custom node label prefix", func() {
+		dc = NewCreator(clnt, kernelLabel, scheme, WithNodeLabelPrefix("tenant-a.example.com"))
+
+		driverPod := v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{kernelLabel: "some kernel"},
+			},
+		}
+
+		Expect(
+			dc.GetNodeLabelFromPod(&driverPod, "module-name"),
+		).To(
+			Equal("tenant-a.example.com/module-name.ready"),
+		)
+
+		Expect(
+			dc.GetNodeLabelFromPod(&v1.Pod{}, "module-name"),
+		).To(
+			Equal("tenant-a.example.com/module-name.device-plugin-ready"),
+		)
+	})
+})
+
+var _ = Describe("ValidateModprobeSpec", func() {
+	It("should accept a valid spec", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:         "/kmm/firmware",
+			InTreeModuleToRemove: "in_tree-kmod",
+			ModuleName:           "some-kmod",
+			ModulesLoadingOrder:  []string{"some-kmod", "some_dependency"},
+		}
+
+		Expect(ValidateModprobeSpec(spec)).NotTo(HaveOccurred())
+	})
+
+	It("should accept forcing the load with insmod", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Force:      true,
+			ModuleName: "some-kmod",
+			ModulePath: "/some-kmod.ko",
+			UseInsmod:  true,
+		}
+
+		Expect(ValidateModprobeSpec(spec)).NotTo(HaveOccurred())
+	})
+
+	It("should accept an empty module name if raw arguments are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}},
+		}
+
+		Expect(ValidateModprobeSpec(spec)).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("should reject an invalid spec",
+		func(spec kmmv1beta1.ModprobeSpec) {
+			Expect(ValidateModprobeSpec(spec)).To(HaveOccurred())
+		},
+		Entry("empty module name", kmmv1beta1.ModprobeSpec{}),
+		Entry("whitespace in the module name", kmmv1beta1.ModprobeSpec{ModuleName: "some kmod"}),
+		Entry("path separator in the module name", kmmv1beta1.ModprobeSpec{ModuleName: "../some-kmod"}),
+		Entry("shell metacharacter in the module name", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod;reboot"}),
+		Entry(
+			"invalid module in the loading order",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", ModulesLoadingOrder: []string{"some-kmod", "$(reboot)"}},
+		),
+		Entry(
+			"module name missing from the loading order",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", ModulesLoadingOrder: []string{"some_dependency"}},
+		),
+		Entry(
+			"invalid in-tree module name",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", InTreeModuleToRemove: "in-tree kmod"},
+		),
+		Entry("insmod without a module path", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", UseInsmod: true}),
+		Entry(
+			"relative firmware path",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwarePath: "kmm/firmware"},
+		),
+		Entry(
+			"firmware tarball without a firmware path",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwareSource: kmmv1beta1.FirmwareSourceTarball},
+		),
+		Entry(
+			"invalid firmware source",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwarePath: "/firmware", FirmwareSource: "Zip"},
+		),
+		Entry(
+			"relative firmware host path",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwarePath: "/kmm/firmware", FirmwareHostPath: "lib/firmware"},
+		),
+		Entry(
+			"empty pre-load command",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", PreLoadCommand: []string{"echo 1 > /sys/some/toggle", " "}},
+		),
+		Entry(
+			"empty post-load verification command",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", PostLoadVerify: []string{""}},
+		),
+		Entry(
+			"empty post-unload command",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", PostUnloadCommand: []string{""}},
+		),
+		Entry(
+			"empty pre-unload command",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", PreUnloadCommand: []string{""}},
+		),
+		Entry(
+			"force with raw load arguments",
+			kmmv1beta1.ModprobeSpec{Force: true, RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}}},
+		),
+		Entry(
+			"forceVermagic with raw load arguments",
+			kmmv1beta1.ModprobeSpec{ForceVermagic: true, RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}}},
+		),
+		Entry(
+			"forceModversion with raw load arguments",
+			kmmv1beta1.ModprobeSpec{ForceModversion: true, RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}}},
+		),
+		Entry(
+			"forceVermagic with insmod",
+			kmmv1beta1.ModprobeSpec{ForceVermagic: true, ModuleName: "some-kmod", ModulePath: "/some-kmod.ko", UseInsmod: true},
+		),
+		Entry(
+			"forceModversion with insmod",
+			kmmv1beta1.ModprobeSpec{ForceModversion: true, ModuleName: "some-kmod", ModulePath: "/some-kmod.ko", UseInsmod: true},
+		),
+		Entry(
+			"insmod with a loading order",
+			kmmv1beta1.ModprobeSpec{
+				ModuleName:          "some-kmod",
+				ModulePath:          "/some-kmod.ko",
+				ModulesLoadingOrder: []string{"some-kmod", "dep-a"},
+				UseInsmod:           true,
+			},
+		),
+		Entry("relative shell path", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", ShellPath: "bin/sh"}),
+		Entry("absolute firmware subdirectory", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwareSubDir: "/acme"}),
+		Entry("firmware subdirectory outside of the host path", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwareSubDir: "acme/../.."}),
+		Entry("firmware subdirectory equal to the host path", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwareSubDir: "./"}),
+	)
+
+	rawLoad := &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}}
+	rawUnload := &kmmv1beta1.ModprobeArgs{Unload: []string{"-rv", "some-kmod"}}
+
+	DescribeTable("should reject structured fields ignored because of raw arguments",
+		func(spec kmmv1beta1.ModprobeSpec, phase, field string) {
+			err := ValidateModprobeSpec(spec)
+
+			conflictErr := &RawArgsConflictError{}
+			Expect(errors.As(err, &conflictErr)).To(BeTrue())
+			Expect(conflictErr.Phase).To(Equal(phase))
+			Expect(conflictErr.Fields).To(Equal([]string{field}))
+			Expect(err.Error()).To(Equal(fmt.Sprintf("rawArgs.%s cannot be used with %s", phase, field)))
+		},
+		Entry("load: args", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, Args: &kmmv1beta1.ModprobeArgs{Load: []string{"-v"}}}, "load", "args.load"),
+		Entry("load: dirName", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, DirName: "/usr"}, "load", "dirName"),
+		Entry("load: parameters", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, Parameters: []string{"a=b"}}, "load", "parameters"),
+		Entry("load: modulesLoadingOrder", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, ModulesLoadingOrder: []string{"some-kmod"}}, "load", "modulesLoadingOrder"),
+		Entry("load: firmwarePath", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, FirmwarePath: "/firmware"}, "load", "firmwarePath"),
+		Entry("load: runDepmod", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, RunDepmod: true}, "load", "runDepmod"),
+		Entry("load: force", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, Force: true}, "load", "force"),
+		Entry("load: forceVermagic", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, ForceVermagic: true}, "load", "forceVermagic"),
+		Entry("load: forceModversion", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, ForceModversion: true}, "load", "forceModversion"),
+		Entry("load: useInsmod", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, UseInsmod: true}, "load", "useInsmod"),
+		Entry("load: modulePath", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, ModulePath: "/some-kmod.ko"}, "load", "modulePath"),
+		Entry("load: inTreeModuleToRemove", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, InTreeModuleToRemove: "in-tree"}, "load", "inTreeModuleToRemove"),
+		Entry("load: preLoadCommand", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, PreLoadCommand: []string{"true"}}, "load", "preLoadCommand"),
+		Entry("load: postLoadVerify", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, PostLoadVerify: []string{"true"}}, "load", "postLoadVerify"),
+		Entry("unload: args", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, Args: &kmmv1beta1.ModprobeArgs{Unload: []string{"-r"}}}, "unload", "args.unload"),
+		Entry("unload: dirName", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, DirName: "/usr"}, "unload", "dirName"),
+		Entry("unload: unloadParameters", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, UnloadParameters: []string{"a=b"}}, "unload", "unloadParameters"),
+		Entry("unload: modulesLoadingOrder", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, ModulesLoadingOrder: []string{"some-kmod"}}, "unload", "modulesLoadingOrder"),
+		Entry("unload: firmwarePath", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, FirmwarePath: "/firmware"}, "unload", "firmwarePath"),
+		Entry("unload: useInsmod", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, UseInsmod: true, ModulePath: "/some-kmod.ko"}, "unload", "useInsmod"),
+		Entry("unload: inTreeModuleToRemove", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, InTreeModuleToRemove: "in-tree"}, "unload", "inTreeModuleToRemove"),
+		Entry("unload: postUnloadCommand", kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, PostUnloadCommand: []string{"true"}}, "unload", "postUnloadCommand"),
+	)
+
+	It("should list all the conflicting fields", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			RawArgs:      rawLoad,
+			Parameters:   []string{"a=b"},
+			FirmwarePath: "/firmware",
+		}
+
+		Expect(
+			ValidateModprobeSpec(spec),
+		).To(
+			MatchError("rawArgs.load cannot be used with parameters, firmwarePath"),
+		)
+	})
+
+	DescribeTable("should accept raw arguments with the fields they do not ignore",
+		func(spec kmmv1beta1.ModprobeSpec) {
+			Expect(ValidateModprobeSpec(spec)).NotTo(HaveOccurred())
+		},
+		Entry("load: default dirName", kmmv1beta1.ModprobeSpec{RawArgs: rawLoad, DirName: "/opt"}),
+		Entry(
+			"load: command settings",
+			kmmv1beta1.ModprobeSpec{
+				RawArgs:            rawLoad,
+				ExecForm:           true,
+				LoadTimeoutSeconds: pointer.Int64(10),
+				ModprobePath:       "/usr/sbin/modprobe",
+				ShellPath:          "/bin/bash",
+			},
+		),
+		Entry(
+			"load: structured unload fields",
+			kmmv1beta1.ModprobeSpec{
+				RawArgs:           rawLoad,
+				ModuleName:        "some-kmod",
+				UnloadParameters:  []string{"a=b"},
+				PostUnloadCommand: []string{"true"},
+			},
+		),
+		Entry(
+			"unload: structured load fields",
+			kmmv1beta1.ModprobeSpec{
+				RawArgs:        rawUnload,
+				ModuleName:     "some-kmod",
+				DirName:        "/opt",
+				Parameters:     []string{"a=b"},
+				PreLoadCommand: []string{"true"},
+			},
+		),
+		Entry(
+			"unload: pre-unload command",
+			kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", RawArgs: rawUnload, PreUnloadCommand: []string{"true"}},
+		),
+		Entry(
+			"both",
+			kmmv1beta1.ModprobeSpec{RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod"}, Unload: []string{"-rv", "some-kmod"}}},
+		),
+	)
+})
+
+var _ = Describe("validateUpdateStrategy", func() {
+	maxUnavailable := func(v intstr.IntOrString) appsv1.DaemonSetUpdateStrategy {
+		return appsv1.DaemonSetUpdateStrategy{
+			Type:          appsv1.RollingUpdateDaemonSetStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDaemonSet{MaxUnavailable: &v},
+		}
+	}
+
+	DescribeTable("should accept valid strategies",
+		func(s appsv1.DaemonSetUpdateStrategy) {
+			Expect(validateUpdateStrategy(s)).NotTo(HaveOccurred())
+		},
+		Entry("empty", appsv1.DaemonSetUpdateStrategy{}),
+		Entry("on delete", appsv1.DaemonSetUpdateStrategy{Type: appsv1.OnDeleteDaemonSetStrategyType}),
+		Entry("rolling update without parameters", appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType}),
+		Entry("integer maxUnavailable", maxUnavailable(intstr.FromInt(20))),
+		Entry("percentage maxUnavailable", maxUnavailable(intstr.FromString("10%"))),
+	)
+
+	DescribeTable("should reject invalid strategies",
+		func(s appsv1.DaemonSetUpdateStrategy) {
+			Expect(validateUpdateStrategy(s)).To(HaveOccurred())
+		},
+		Entry("unknown type", appsv1.DaemonSetUpdateStrategy{Type: "Recreate"}),
+		Entry(
+			"on delete with rolling update parameters",
+			appsv1.DaemonSetUpdateStrategy{
+				Type:          appsv1.OnDeleteDaemonSetStrategyType,
+				RollingUpdate: &appsv1.RollingUpdateDaemonSet{},
+			},
+		),
+		Entry("negative maxUnavailable", maxUnavailable(intstr.FromInt(-1))),
+		Entry("non-percentage string maxUnavailable", maxUnavailable(intstr.FromString("ten"))),
+		Entry("invalid percentage maxUnavailable", maxUnavailable(intstr.FromString("a%"))),
+		Entry("percentage maxUnavailable over 100%", maxUnavailable(intstr.FromString("150%"))),
+	)
+})
+
+var _ = Describe("GetNodeLabelerFinalizer", func() {
+	DescribeTable("should return the finalizer set on the pod templates",
+		func(opts []Option, expected string) {
+			dc := NewCreator(nil, kernelLabel, scheme, opts...)
+
+			Expect(dc.GetNodeLabelerFinalizer()).To(Equal(expected))
+
+			mod := kmmv1beta1.Module{
+				ObjectMeta: metav1.ObjectMeta{Name: moduleName},
+				Spec: kmmv1beta1.ModuleSpec{
+					DevicePlugin: &kmmv1beta1.DevicePluginSpec{
+						Container: kmmv1beta1.DevicePluginContainerSpec{Image: devicePluginImage},
+					},
+					ModuleLoader: kmmv1beta1.ModuleLoaderSpec{
+						Container: kmmv1beta1.ModuleLoaderContainerSpec{
+							Modprobe: kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"},
 						},
-						PriorityClassName:  "system-node-critical",
-						ServiceAccountName: serviceAccountName,
+					},
+				},
+			}
+
+			driverDS := appsv1.DaemonSet{}
+
+			err := dc.SetDriverContainerAsDesired(context.Background(), &driverDS, "test-image", mod, kernelVersion, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(driverDS.Spec.Template.Finalizers).To(Equal([]string{expected}))
+
+			devicePluginDS := appsv1.DaemonSet{}
+
+			err = dc.SetDevicePluginAsDesired(context.Background(), &devicePluginDS, &mod)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(devicePluginDS.Spec.Template.Finalizers).To(Equal([]string{expected}))
+		},
+		Entry("default", nil, constants.NodeLabelerFinalizer),
+		Entry(
+			"derived from the node label prefix",
+			[]Option{WithNodeLabelPrefix("tenant-a.example.com")},
+			"tenant-a.example.com/node-labeler",
+		),
+		Entry(
+			"overridden",
+			[]Option{WithNodeLabelPrefix("tenant-a.example.com"), WithNodeLabelerFinalizer("example.com/custom")},
+			"example.com/custom",
+		),
+	)
+})
+
+var _ = Describe("MakeLoadCommand", func() {
+	const (
+		kernelModuleName = "some-kmod"
+		moduleName       = "module-name"
+	)
+
+	It("should only use raw arguments if they are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			RawArgs: &kmmv1beta1.ModprobeArgs{
+				Load: []string{"load", "arguments"},
+			},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"modprobe load arguments",
+			}),
+		)
+	})
+
+	DescribeTable("should run simple load commands without a shell in exec form",
+		func(spec kmmv1beta1.ModprobeSpec, expected []string) {
+			spec.ExecForm = true
+
+			Expect(
+				MakeLoadCommand(spec, moduleName, kernelVersion),
+			).To(
+				Equal(expected),
+			)
+		},
+		Entry(
+			"modprobe",
+			kmmv1beta1.ModprobeSpec{
+				ModuleName: kernelModuleName,
+				Parameters: []string{`param=a b`, `other="quoted"`},
+			},
+			[]string{"modprobe", "-v", kernelModuleName, `param=a b`, `other="quoted"`},
+		),
+		Entry(
+			"modprobe with all options",
+			kmmv1beta1.ModprobeSpec{
+				ModuleName:         kernelModuleName,
+				Args:               &kmmv1beta1.ModprobeArgs{Load: []string{"-a"}},
+				ForceVermagic:      true,
+				DirName:            "/opt",
+				LoadTimeoutSeconds: pointer.Int64(30),
+				ModprobePath:       "/usr/sbin/modprobe",
+				Parameters:         []string{"p=1"},
+			},
+			[]string{"timeout", "30s", "/usr/sbin/modprobe", "-a", "--force-vermagic", "-d", "/opt", kernelModuleName, "p=1"},
+		),
+		Entry(
+			"modprobe with a single module in the loading order",
+			kmmv1beta1.ModprobeSpec{
+				ModuleName:          kernelModuleName,
+				ModulesLoadingOrder: []string{"dep"},
+				Parameters:          []string{"p=1"},
+			},
+			[]string{"modprobe", "-v", "dep"},
+		),
+		Entry(
+			"insmod",
+			kmmv1beta1.ModprobeSpec{
+				ModuleName: kernelModuleName,
+				UseInsmod:  true,
+				Force:      true,
+				ModulePath: "/opt/some-kmod.ko",
+				Parameters: []string{"p=1"},
+			},
+			[]string{"insmod", "-f", "/opt/some-kmod.ko", "p=1"},
+		),
+		Entry(
+			"raw arguments",
+			kmmv1beta1.ModprobeSpec{
+				ModuleName:   kernelModuleName,
+				FirmwarePath: "/firmware",
+				RawArgs:      &kmmv1beta1.ModprobeArgs{Load: []string{"load", "it's"}},
+			},
+			[]string{"modprobe", "load", "it's"},
+		),
+	)
+
+	DescribeTable("should fall back to the shell in exec form if the load command chains several commands",
+		func(spec kmmv1beta1.ModprobeSpec) {
+			spec.ModuleName = kernelModuleName
+			spec.ExecForm = true
+
+			Expect(
+				MakeLoadCommand(spec, moduleName, kernelVersion)[:2],
+			).To(
+				Equal([]string{"/bin/sh", "-c"}),
+			)
+		},
+		Entry("firmware", kmmv1beta1.ModprobeSpec{FirmwarePath: "/firmware"}),
+		Entry("pre-load command", kmmv1beta1.ModprobeSpec{PreLoadCommand: []string{"true"}}),
+		Entry("post-load verification", kmmv1beta1.ModprobeSpec{PostLoadVerify: []string{"true"}}),
+		Entry("in-tree module", kmmv1beta1.ModprobeSpec{InTreeModuleToRemove: "in-tree"}),
+		Entry("depmod", kmmv1beta1.ModprobeSpec{RunDepmod: true}),
+		Entry("several modules", kmmv1beta1.ModprobeSpec{ModulesLoadingOrder: []string{kernelModuleName, "dep"}}),
+	)
+
+	It("should build the command from the spec as expected", func() {
+		const (
+			arg1 = "arg1"
+			arg2 = "arg2"
+			dir  = "/some-dir"
+		)
+
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			Parameters: []string{arg1, arg2},
+			DirName:    dir,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -v -d %s %s %s %s", dir, kernelModuleName, arg1, arg2),
+			}),
+		)
+	})
+
+	It("should use provided arguments if provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Load: []string{"-z", "-k"},
+			},
+			ModuleName: kernelModuleName,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -z -k %s", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should use the firmware path if provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath: "/kmm/firmware/mymodule",
+			ModuleName:   kernelModuleName,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && modprobe -v %s", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should use insmod with the module path and parameters if requested", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			ModulePath: "/opt/some-kmod.ko",
+			Parameters: []string{"a=b", "c=d"},
+			UseInsmod:  true,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"insmod /opt/some-kmod.ko a=b c=d",
+			}),
+		)
+	})
+
+	It("should run depmod for the kernel version before modprobe if requested", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			DirName:      "/some-dir",
+			FirmwarePath: "/kmm/firmware/mymodule",
+			ModuleName:   kernelModuleName,
+			RunDepmod:    true,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && depmod -b /some-dir %s && modprobe -v -d /some-dir %s",
+					kernelVersion,
+					kernelModuleName,
+				),
+			}),
+		)
+	})
+
+	It("should copy the firmware to a kernel version directory if FirmwareVersionScoped is set", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:          "/kmm/firmware/mymodule",
+			FirmwareVersionScoped: true,
+			ModuleName:            kernelModuleName,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, "5.14.0-284.el9.x86_64"),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"mkdir -p /var/lib/firmware/module-name/5.14.0-284.el9.x86_64 && " +
+					"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name/5.14.0-284.el9.x86_64 && " +
+					"modprobe -v some-kmod",
+			}),
+		)
+	})
+
+	It("should copy the firmware to the same kernel version directory layout when the container restarts", func() {
+		fwDir := filepath.Join(GinkgoT().TempDir(), "mymodule")
+		Expect(os.Mkdir(fwDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(fwDir, "fw.bin"), []byte("fw"), 0644)).To(Succeed())
+
+		hostPath := GinkgoT().TempDir()
+
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwareHostPath:      hostPath,
+			FirmwarePath:          fwDir,
+			FirmwareVersionScoped: true,
+			ModuleName:            kernelModuleName,
+			ModprobePath:          "true",
+		}
+
+		cmd := MakeLoadCommand(spec, moduleName, kernelVersion)
+
+		// the host directory outlives the container, so the second start finds the kernel version directory
+		for i := 0; i < 2; i++ {
+			out, err := exec.Command(cmd[0], cmd[1:]...).CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(out))
+		}
+
+		files, err := filepath.Glob(filepath.Join(hostPath, moduleName, kernelVersion, "*", "*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(Equal([]string{filepath.Join(hostPath, moduleName, kernelVersion, "mymodule", "fw.bin")}))
+	})
+
+	It("should decompress the firmware if requested", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwareDecompress: true,
+			FirmwarePath:       "/kmm/firmware/mymodule",
+			ModuleName:         kernelModuleName,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && " +
+					`find /var/lib/firmware/module-name -name '*.zst' -exec zstd -d -q -f --rm {} + && ` +
+					`find /var/lib/firmware/module-name -name '*.xz' -exec unxz -f {} + && ` +
+					fmt.Sprintf("modprobe -v %s", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should overwrite the firmware decompressed by a previous pod and report failures", func() {
+		cmd := makeFirmwareDecompressCommand("/var/lib/firmware/module-name")
+
+		Expect(cmd).To(ContainSubstring("zstd -d -q -f --rm {} +"))
+		Expect(cmd).To(ContainSubstring("unxz -f {} +"))
+		Expect(cmd).NotTo(ContainSubstring(`\;`))
+	})
+
+	DescribeTable("should only copy the firmware directory",
+		func(source kmmv1beta1.FirmwareSource, expected string) {
+			spec := kmmv1beta1.ModprobeSpec{
+				FirmwarePath:   "/kmm/firmware/mymodule",
+				FirmwareSource: source,
+				ModuleName:     kernelModuleName,
+			}
+
+			Expect(
+				MakeLoadCommand(spec, moduleName, kernelVersion),
+			).To(
+				Equal([]string{"/bin/sh", "-c", expected}),
+			)
+		},
+		Entry(
+			"default",
+			kmmv1beta1.FirmwareSource(""),
+			"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && modprobe -v "+kernelModuleName,
+		),
+		Entry(
+			"directory",
+			kmmv1beta1.FirmwareSourceDirectory,
+			"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && modprobe -v "+kernelModuleName,
+		),
+		Entry("tarball", kmmv1beta1.FirmwareSourceTarball, "modprobe -v "+kernelModuleName),
+	)
+
+	It("should not need a shell in exec form for a firmware tarball", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ExecForm:       true,
+			FirmwarePath:   "/kmm/firmware.tar",
+			FirmwareSource: kmmv1beta1.FirmwareSourceTarball,
+			ModuleName:     kernelModuleName,
+		}
+
+		Expect(MakeLoadCommand(spec, moduleName, kernelVersion)).To(Equal([]string{"modprobe", "-v", kernelModuleName}))
+	})
+
+	It("should extract and decompress the firmware tarball to the versioned directory", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwareDecompress:    true,
+			FirmwarePath:          "/kmm/firmware.tar",
+			FirmwareSource:        kmmv1beta1.FirmwareSourceTarball,
+			FirmwareVersionScoped: true,
+			ModuleName:            kernelModuleName,
+		}
+
+		Expect(
+			makeFirmwareExtractCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"mkdir -p /var/lib/firmware/module-name/1.2.3 && " +
+					"tar -xf /kmm/firmware.tar -C /var/lib/firmware/module-name/1.2.3 && " +
+					`find /var/lib/firmware/module-name/1.2.3 -name '*.zst' -exec zstd -d -q -f --rm {} + && ` +
+					`find /var/lib/firmware/module-name/1.2.3 -name '*.xz' -exec unxz -f {} +`,
+			}),
+		)
+	})
+
+	It("should remove the in-tree module before loading the out-of-tree one", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:         "/kmm/firmware/mymodule",
+			InTreeModuleToRemove: "in-tree-kmod",
+			ModuleName:           kernelModuleName,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					"(modprobe -rv in-tree-kmod || true) && cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && modprobe -v %s",
+					kernelModuleName,
+				),
+			}),
+		)
+	})
+
+	It("should load the modules in the provided order", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			DirName:             "/some-dir",
+			ModuleName:          kernelModuleName,
+			ModulesLoadingOrder: []string{kernelModuleName, "dep-a", "dep-b"},
+			Parameters:          []string{"a=b"},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					"modprobe -v -d /some-dir %s a=b && modprobe -v -d /some-dir dep-a && modprobe -v -d /some-dir dep-b",
+					kernelModuleName,
+				),
+			}),
+		)
+	})
+
+	It("should copy the firmware once before loading the modules in order", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:        "/kmm/firmware/mymodule",
+			ModuleName:          kernelModuleName,
+			ModulesLoadingOrder: []string{kernelModuleName, "dep-a"},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && modprobe -v %s && modprobe -v dep-a",
+					kernelModuleName,
+				),
+			}),
+		)
+	})
+
+	It("should run the pre-load commands after copying the firmware and before loading the module", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:         "/kmm/firmware/mymodule",
+			InTreeModuleToRemove: "in-tree-kmod",
+			ModuleName:           kernelModuleName,
+			PreLoadCommand:       []string{"echo 'options some-kmod a=b' > /etc/modprobe.d/some-kmod.conf", "echo 1 > /sys/some/toggle"},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"(modprobe -rv in-tree-kmod || true) && " +
+					"cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && " +
+					"echo 'options some-kmod a=b' > /etc/modprobe.d/some-kmod.conf && " +
+					"echo 1 > /sys/some/toggle && " +
+					"modprobe -v " + kernelModuleName,
+			}),
+		)
+	})
+
+	It("should run the post-load verification commands after loading the module", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName:     kernelModuleName,
+			PostLoadVerify: []string{"test -e /proc/driver/foo", "grep -q 1.2.3 /proc/driver/foo/version"},
+			PreLoadCommand: []string{"echo 1 > /sys/some/toggle"},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"echo 1 > /sys/some/toggle && " +
+					"modprobe -v " + kernelModuleName + " && " +
+					"test -e /proc/driver/foo && " +
+					"grep -q 1.2.3 /proc/driver/foo/version",
+			}),
+		)
+	})
+
+	It("should not add any post-load verification command if none is set", func() {
+		spec := kmmv1beta1.ModprobeSpec{ModuleName: kernelModuleName}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{"/bin/sh", "-c", "modprobe -v " + kernelModuleName}),
+		)
+	})
+
+	It("should use the overridden shell and modprobe paths", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			InTreeModuleToRemove: "in-tree-kmod",
+			ModuleName:           kernelModuleName,
+			ModprobePath:         "/sbin/modprobe",
+			ShellPath:            "/busybox/sh",
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/busybox/sh",
+				"-c",
+				fmt.Sprintf("(/sbin/modprobe -rv in-tree-kmod || true) && /sbin/modprobe -v %s", kernelModuleName),
+			}),
+		)
+
+		spec.RawArgs = &kmmv1beta1.ModprobeArgs{Load: []string{"-v", kernelModuleName}}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{"/busybox/sh", "-c", "/sbin/modprobe -v " + kernelModuleName}),
+		)
+	})
+
+	DescribeTable("should prefix the load commands with a timeout only if one is set",
+		func(loadTimeoutSeconds *int64, useInsmod bool, expected string) {
+			spec := kmmv1beta1.ModprobeSpec{
+				LoadTimeoutSeconds: loadTimeoutSeconds,
+				ModuleName:         kernelModuleName,
+				ModulePath:         "/opt/some-kmod.ko",
+				UseInsmod:          useInsmod,
+			}
+
+			Expect(
+				MakeLoadCommand(spec, moduleName, kernelVersion),
+			).To(
+				Equal([]string{"/bin/sh", "-c", expected}),
+			)
+		},
+		Entry("no timeout", nil, false, "modprobe -v "+kernelModuleName),
+		Entry("zero timeout", pointer.Int64(0), false, "modprobe -v "+kernelModuleName),
+		Entry("modprobe", pointer.Int64(120), false, "timeout 120s modprobe -v "+kernelModuleName),
+		Entry("insmod", pointer.Int64(30), true, "timeout 30s insmod /opt/some-kmod.ko"),
+	)
+
+	DescribeTable("should add the force flags",
+		func(force, forceVermagic, forceModversion bool, expectedFlags string) {
+			spec := kmmv1beta1.ModprobeSpec{
+				Force:           force,
+				ForceModversion: forceModversion,
+				ForceVermagic:   forceVermagic,
+				ModuleName:      kernelModuleName,
+			}
+
+			Expect(
+				MakeLoadCommand(spec, moduleName, kernelVersion),
+			).To(
+				Equal([]string{"/bin/sh", "-c", fmt.Sprintf("modprobe -v%s %s", expectedFlags, kernelModuleName)}),
+			)
+		},
+		Entry("none", false, false, false, ""),
+		Entry("force", true, false, false, " --force"),
+		Entry("vermagic", false, true, false, " --force-vermagic"),
+		Entry("modversion", false, false, true, " --force-modversion"),
+		Entry("vermagic and modversion", false, true, true, " --force-vermagic --force-modversion"),
+		Entry("all", true, true, true, " --force"),
+	)
+
+	It("should force the load with insmod", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Force:      true,
+			ModuleName: kernelModuleName,
+			ModulePath: "/opt/some-kmod.ko",
+			UseInsmod:  true,
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{"/bin/sh", "-c", "insmod -f /opt/some-kmod.ko"}),
+		)
+	})
+
+	It("should prefix the raw load arguments with a timeout", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			LoadTimeoutSeconds: pointer.Int64(60),
+			RawArgs:            &kmmv1beta1.ModprobeArgs{Load: []string{"-v", kernelModuleName}},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{"/bin/sh", "-c", "timeout 60s modprobe -v " + kernelModuleName}),
+		)
+	})
+
+	It("should quote the parameters, arguments and directory name", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args:       &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "$(reboot)"}},
+			DirName:    "/opt dir",
+			ModuleName: kernelModuleName,
+			Parameters: []string{"a=b; rm -rf /", "c=`id`", "d='e'"},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					`modprobe -v '$(reboot)' -d '/opt dir' %s 'a=b; rm -rf /' 'c=`+"`id`"+`' 'd='\''e'\'''`,
+					kernelModuleName,
+				),
+			}),
+		)
+	})
+
+	It("should quote the raw arguments", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			RawArgs: &kmmv1beta1.ModprobeArgs{Load: []string{"-v", "some-kmod", "&&", "reboot"}},
+		}
+
+		Expect(
+			MakeLoadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{"/bin/sh", "-c", "modprobe -v some-kmod '&&' reboot"}),
+		)
+	})
+})
+
+var _ = Describe("staleFirmwareHostPath", func() {
+	makeDS := func(hostPath string) *appsv1.DaemonSet {
+		return &appsv1.DaemonSet{
+			Spec: appsv1.DaemonSetSpec{
+				Template: v1.PodTemplateSpec{
+					Spec: v1.PodSpec{
 						Volumes: []v1.Volume{
 							{
-								Name: "kubelet-device-plugins",
+								Name: "node-var-lib-firmware",
 								VolumeSource: v1.VolumeSource{
-									HostPath: &v1.HostPathVolumeSource{
-										Path: "/var/lib/kubelet/device-plugins",
-										Type: &directory,
-									},
+									HostPath: &v1.HostPathVolumeSource{Path: hostPath},
 								},
 							},
-							dpVol,
 						},
 					},
 				},
 			},
 		}
+	}
+
+	It("should be a no-op if the firmware was never configured", func() {
+		Expect(
+			staleFirmwareHostPath(&appsv1.DaemonSet{}, kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"}, moduleName),
+		).To(BeEmpty())
+	})
+
+	It("should be a no-op if the firmware directory did not change", func() {
+		spec := kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod", FirmwarePath: "/firmware"}
+
+		Expect(
+			staleFirmwareHostPath(makeDS("/var/lib/firmware/"+moduleName), spec, moduleName),
+		).To(BeEmpty())
+	})
+
+	It("should return the firmware directory of the live DaemonSet if the firmware was removed", func() {
+		Expect(
+			staleFirmwareHostPath(makeDS("/opt/firmware/vendor"), kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"}, moduleName),
+		).To(Equal("/opt/firmware/vendor"))
+	})
+
+	It("should return the previous firmware directory if it changed", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName:       "some-kmod",
+			FirmwarePath:     "/firmware",
+			FirmwareHostPath: "/opt/firmware",
+		}
+
+		Expect(
+			staleFirmwareHostPath(makeDS("/var/lib/firmware/"+moduleName), spec, moduleName),
+		).To(Equal("/var/lib/firmware/" + moduleName))
+	})
+
+	It("should return the directory already being cleaned up", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Generation:  2,
+				Annotations: map[string]string{constants.StaleFirmwarePathAnnotation: "/var/lib/firmware/" + moduleName},
+			},
+			Status: appsv1.DaemonSetStatus{
+				ObservedGeneration:     2,
+				DesiredNumberScheduled: 3,
+				UpdatedNumberScheduled: 3,
+				NumberAvailable:        2,
+			},
+		}
+
+		Expect(
+			staleFirmwareHostPath(&ds, kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"}, moduleName),
+		).To(Equal("/var/lib/firmware/" + moduleName))
+	})
+
+	DescribeTable("should only stop the cleanup once the rollout completed",
+		func(generation int64, status appsv1.DaemonSetStatus, expected string) {
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Generation:  generation,
+					Annotations: map[string]string{constants.StaleFirmwarePathAnnotation: "/var/lib/firmware/" + moduleName},
+				},
+				Status: status,
+			}
+
+			Expect(
+				staleFirmwareHostPath(&ds, kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"}, moduleName),
+			).To(Equal(expected))
+		},
+		Entry(
+			"template not observed yet",
+			int64(3),
+			appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 2},
+			"/var/lib/firmware/"+moduleName,
+		),
+		Entry(
+			"pods not updated yet",
+			int64(3),
+			appsv1.DaemonSetStatus{ObservedGeneration: 3, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 1, NumberAvailable: 2},
+			"/var/lib/firmware/"+moduleName,
+		),
+		Entry(
+			"rollout completed",
+			int64(3),
+			appsv1.DaemonSetStatus{ObservedGeneration: 3, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 2},
+			"",
+		),
+	)
+
+	It("should keep cleaning up a firmware volume that is still mounted once the rollout completed", func() {
+		ds := makeDS("/opt/firmware/vendor")
+		ds.Status = appsv1.DaemonSetStatus{DesiredNumberScheduled: 1, UpdatedNumberScheduled: 1, NumberAvailable: 1}
+
+		Expect(
+			staleFirmwareHostPath(ds, kmmv1beta1.ModprobeSpec{ModuleName: "some-kmod"}, moduleName),
+		).To(Equal("/opt/firmware/vendor"))
+	})
+})
+
+var _ = Describe("MakeUnloadCommand", func() {
+	const (
+		kernelModuleName = "some-kmod"
+		moduleName       = "module-name"
+	)
+
+	It("should only use raw arguments if they are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			RawArgs: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"unload", "arguments"},
+			},
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"modprobe unload arguments",
+			}),
+		)
+	})
+
+	It("should build the command from the spec as expected", func() {
+		const dir = "/some-dir"
+
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName: kernelModuleName,
+			DirName:    dir,
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -rv -d %s %s", dir, kernelModuleName),
+			}),
+		)
+	})
+
+	It("should use provided arguments if provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"-z", "-k"},
+			},
+			ModuleName: kernelModuleName,
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -z -k %s", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should use the firmware path if provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath: "/kmm/firmware/mymodule",
+			ModuleName:   kernelModuleName,
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -rv %s; ret=$?; rm -rf /var/lib/firmware/module-name; exit $ret", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should not remove the firmware if RetainFirmwareOnUnload is set", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:           "/kmm/firmware/mymodule",
+			ModuleName:             kernelModuleName,
+			RetainFirmwareOnUnload: true,
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -rv %s", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should only remove the firmware of its kernel version if FirmwareVersionScoped is set", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:          "/kmm/firmware/mymodule",
+			FirmwareVersionScoped: true,
+			ModuleName:            kernelModuleName,
+		}
+
+		unloadCommand := MakeUnloadCommand(spec, moduleName, "5.14.0-284.el9.x86_64")
+
+		Expect(unloadCommand).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"modprobe -rv some-kmod; ret=$?; " +
+					"rm -rf /var/lib/firmware/module-name/5.14.0-284.el9.x86_64; " +
+					"rmdir /var/lib/firmware/module-name 2>/dev/null; exit $ret",
+			}),
+		)
+
+		Expect(unloadCommand[2]).NotTo(ContainSubstring("5.14.0-362.el9.x86_64"))
+		Expect(
+			MakeLoadCommand(spec, moduleName, "5.14.0-362.el9.x86_64")[2],
+		).To(
+			ContainSubstring("/var/lib/firmware/module-name/5.14.0-362.el9.x86_64"),
+		)
+	})
+
+	It("should ignore the unload parameters if raw arguments are provided", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"-z"},
+			},
+			ModuleName: kernelModuleName,
+			RawArgs: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"unload", "arguments"},
+			},
+			UnloadParameters: []string{"a=b"},
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				"modprobe unload arguments",
+			}),
+		)
+	})
+
+	It("should append the unload parameters after the provided arguments and the module name", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"-r", "--remove-dependencies"},
+			},
+			ModuleName:          kernelModuleName,
+			ModulesLoadingOrder: []string{kernelModuleName, "dep-a"},
+			UnloadParameters:    []string{"a=b", "c=d"},
+		}
 
 		Expect(
-			cmp.Equal(expected, ds),
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
 		).To(
-			BeTrue(), cmp.Diff(expected, ds),
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -r --remove-dependencies dep-a && modprobe -r --remove-dependencies %s a=b c=d", kernelModuleName),
+			}),
 		)
 	})
-})
 
-var _ = Describe("GarbageCollect", func() {
-	BeforeEach(func() {
-		ctrl = gomock.NewController(GinkgoT())
-		clnt = client.NewMockClient(ctrl)
-	})
+	It("should use rmmod if insmod is used to load the module", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath: "/kmm/firmware/mymodule",
+			ModuleName:   kernelModuleName,
+			ModulePath:   "/opt/some-kmod.ko",
+			UseInsmod:    true,
+		}
 
-	It("should only delete one of the two DaemonSets if only one is not used", func() {
-		const (
-			legitKernelVersion    = "legit-kernel-version"
-			legitName             = "legit"
-			notLegitKernelVersion = "not-legit-kernel-version"
-			notLegitName          = "not-legit"
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("rmmod %s; ret=$?; rm -rf /var/lib/firmware/module-name; exit $ret", kernelModuleName),
+			}),
 		)
+	})
 
-		dsLegit := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{Name: legitName, Namespace: namespace, Labels: map[string]string{kernelLabel: legitKernelVersion}},
+	It("should remove the firmware after restoring the in-tree module, even if unloading failed", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:         "/kmm/firmware/mymodule",
+			ModuleName:           kernelModuleName,
+			InTreeModuleToRemove: "intree",
 		}
 
-		dsNotLegit := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{Name: notLegitName, Namespace: namespace, Labels: map[string]string{kernelLabel: notLegitKernelVersion}},
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					"modprobe -rv %s && (modprobe -v intree || true); ret=$?; rm -rf /var/lib/firmware/module-name; exit $ret",
+					kernelModuleName,
+				),
+			}),
+		)
+	})
+
+	It("should restore the in-tree module after unloading the out-of-tree one", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			InTreeModuleToRemove: "in-tree-kmod",
+			ModuleName:           kernelModuleName,
 		}
 
-		clnt.EXPECT().Delete(context.Background(), &dsNotLegit).AnyTimes()
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -rv %s && (modprobe -v in-tree-kmod || true)", kernelModuleName),
+			}),
+		)
+	})
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+	It("should unload the modules in the reverse order", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args: &kmmv1beta1.ModprobeArgs{
+				Unload: []string{"-r"},
+			},
+			ModuleName:          kernelModuleName,
+			ModulesLoadingOrder: []string{kernelModuleName, "dep-a", "dep-b"},
+		}
 
-		existingDS := map[string]*appsv1.DaemonSet{
-			legitKernelVersion:    &dsLegit,
-			notLegitKernelVersion: &dsNotLegit,
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -r dep-b && modprobe -r dep-a && modprobe -r %s", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should remove the firmware after unloading all the modules", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:        "/kmm/firmware/mymodule",
+			ModuleName:          kernelModuleName,
+			ModulesLoadingOrder: []string{kernelModuleName, "dep-a"},
 		}
 
-		validKernels := sets.NewString(legitKernelVersion)
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -rv dep-a && modprobe -rv %s; ret=$?; rm -rf /var/lib/firmware/module-name; exit $ret", kernelModuleName),
+			}),
+		)
+	})
 
-		res, err := dc.GarbageCollect(context.Background(), existingDS, validKernels)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(res).To(Equal([]string{notLegitName}))
+	It("should run the post-unload commands after unloading the module", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			FirmwarePath:      "/kmm/firmware/mymodule",
+			ModuleName:        kernelModuleName,
+			PostUnloadCommand: []string{"rm -f /etc/modprobe.d/some-kmod.conf"},
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf(
+					"modprobe -rv %s && rm -f /etc/modprobe.d/some-kmod.conf; ret=$?; rm -rf /var/lib/firmware/module-name; exit $ret",
+					kernelModuleName,
+				),
+			}),
+		)
 	})
 
-	It("should return an error if a deletion failed", func() {
-		clnt.EXPECT().Delete(context.Background(), gomock.Any()).Return(
-			errors.New("client returns some error"),
+	It("should use the overridden shell and modprobe paths", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			InTreeModuleToRemove: "in-tree-kmod",
+			ModuleName:           kernelModuleName,
+			ModprobePath:         "/sbin/modprobe",
+			ShellPath:            "/busybox/sh",
+		}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/busybox/sh",
+				"-c",
+				fmt.Sprintf("/sbin/modprobe -rv %s && (/sbin/modprobe -v in-tree-kmod || true)", kernelModuleName),
+			}),
 		)
 
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		spec.RawArgs = &kmmv1beta1.ModprobeArgs{Unload: []string{"-rv", kernelModuleName}}
 
-		dsNotLegit := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{Name: "name", Namespace: "namespace", Labels: map[string]string{kernelLabel: "kernel version"}},
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{"/busybox/sh", "-c", "/sbin/modprobe -rv " + kernelModuleName}),
+		)
+	})
+
+	It("should quote the unload parameters and arguments", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			Args:             &kmmv1beta1.ModprobeArgs{Unload: []string{"-r", "|reboot"}},
+			ModuleName:       kernelModuleName,
+			UnloadParameters: []string{"x=$HOME"},
 		}
 
-		existingDS := map[string]*appsv1.DaemonSet{
-			"some-kernel-version": &dsNotLegit,
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("modprobe -r '|reboot' %s 'x=$HOME'", kernelModuleName),
+			}),
+		)
+	})
+
+	It("should wait and run the pre-unload commands before unloading", func() {
+		spec := kmmv1beta1.ModprobeSpec{
+			ModuleName:               kernelModuleName,
+			PreUnloadCommand:         []string{"/usr/local/bin/drain-device"},
+			UnloadGracePeriodSeconds: pointer.Int64(20),
 		}
 
-		_, err := dc.GarbageCollect(context.Background(), existingDS, sets.NewString())
-		Expect(err).To(HaveOccurred())
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("sleep 20 && /usr/local/bin/drain-device && modprobe -rv %s", kernelModuleName),
+			}),
+		)
+
+		spec.RawArgs = &kmmv1beta1.ModprobeArgs{Unload: []string{"-rv", kernelModuleName}}
+
+		Expect(
+			MakeUnloadCommand(spec, moduleName, kernelVersion),
+		).To(
+			Equal([]string{
+				"/bin/sh",
+				"-c",
+				fmt.Sprintf("sleep 20 && /usr/local/bin/drain-device && modprobe -rv %s", kernelModuleName),
+			}),
+		)
 	})
 })
 
-var _ = Describe("ModuleDaemonSetsByKernelVersion", func() {
+var _ = Describe("CheckSelectorMatchesNodes", func() {
 	BeforeEach(func() {
 		ctrl = gomock.NewController(GinkgoT())
 		clnt = client.NewMockClient(ctrl)
 	})
 
-	It("should return an empty map if no DaemonSets are present", func() {
-		clnt.EXPECT().List(context.Background(), gomock.Any(), gomock.Any())
+	mod := kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      moduleName,
+			Namespace: namespace,
+		},
+		Spec: kmmv1beta1.ModuleSpec{
+			Selector: map[string]string{"has-feature-x": "true"},
+		},
+	}
+
+	DescribeTable("should check that the selector matches nodes running the kernel versions",
+		func(nodes []v1.Node, kernelVersions []string, expectedErr error) {
+			ctx := context.Background()
+
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = nodes
+					return nil
+				},
+			)
+
+			dc := NewCreator(clnt, kernelLabel, scheme)
+
+			err := dc.CheckSelectorMatchesNodes(ctx, &mod, sets.NewString(kernelVersions...))
+			if expectedErr == nil {
+				Expect(err).NotTo(HaveOccurred())
+				return
+			}
+
+			Expect(err).To(MatchError(expectedErr))
+		},
+		Entry("no nodes", nil, []string{kernelVersion}, ErrNoMatchingNodes),
+		Entry(
+			"nodes matching the selector and the kernel version",
+			[]v1.Node{
+				{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{kernelLabel: "other-kernel"}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{kernelLabel: kernelVersion}}},
+			},
+			[]string{kernelVersion},
+			nil,
+		),
+		Entry(
+			"nodes matching the selector but not the kernel versions",
+			[]v1.Node{
+				{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{kernelLabel: "other-kernel"}}},
+			},
+			[]string{kernelVersion},
+			ErrNoMatchingNodes,
+		),
+		Entry(
+			"nodes matching the selector with any kernel version",
+			[]v1.Node{
+				{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{kernelLabel: "other-kernel"}}},
+			},
+			nil,
+			nil,
+		),
+	)
+
+	It("should return an error if the nodes cannot be listed", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error"))
 
 		dc := NewCreator(clnt, kernelLabel, scheme)
 
-		m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(m).To(BeEmpty())
+		err := dc.CheckSelectorMatchesNodes(ctx, &mod, sets.NewString(kernelVersion))
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(MatchError(ErrNoMatchingNodes))
 	})
 
-	It("should return an error if two DaemonSets are present for the same kernel", func() {
-		dsLabels := map[string]string{
-			"kmm.node.kubernetes.io/module.name": moduleName,
-			kernelLabel:                          kernelVersion,
-		}
+	It("should look up the kernel version of the nodes with the node kernel label", func() {
+		const nodeKernelLabel = "feature.node.kubernetes.io/kernel-version.full"
 
-		ds1 := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "ds1",
-				Namespace: namespace,
-				Labels:    dsLabels,
-			},
-		}
+		ctx := context.Background()
 
-		ds2 := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "ds2",
-				Namespace: namespace,
-				Labels:    dsLabels,
+		nodes := []v1.Node{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node1",
+					Labels: map[string]string{kernelLabel: "other-kernel", nodeKernelLabel: kernelVersion},
+				},
 			},
 		}
 
-		ctx := context.Background()
 		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
-			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
-				list.Items = []appsv1.DaemonSet{ds1, ds2}
+			func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+				list.Items = nodes
 				return nil
 			},
-		)
-		dc := NewCreator(clnt, kernelLabel, scheme)
+		).Times(2)
 
-		_, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
-		Expect(err).To(HaveOccurred())
+		dc := NewCreator(clnt, kernelLabel, scheme, WithNodeKernelLabel(nodeKernelLabel))
+
+		Expect(dc.CheckSelectorMatchesNodes(ctx, &mod, sets.NewString(kernelVersion))).NotTo(HaveOccurred())
+		Expect(dc.CheckSelectorMatchesNodes(ctx, &mod, sets.NewString("other-kernel"))).To(MatchError(ErrNoMatchingNodes))
 	})
 
-	It("should return a map if two DaemonSets are present for different kernels", func() {
-		const otherKernelVersion = "4.5.6"
+	It("should match nodes labeled with the sanitized kernel version", func() {
+		const debugKernelVersion = "1.2.3+debug"
 
-		ds1 := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "ds1",
-				Namespace: namespace,
-				Labels: map[string]string{
-					"kmm.node.kubernetes.io/module.name": moduleName,
-					kernelLabel:                          kernelVersion,
-				},
-			},
-		}
+		ctx := context.Background()
 
-		ds2 := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "ds2",
-				Namespace: namespace,
-				Labels: map[string]string{
-					"kmm.node.kubernetes.io/module.name": moduleName,
-					kernelLabel:                          otherKernelVersion,
+		nodes := []v1.Node{
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   "node1",
+					Labels: map[string]string{kernelLabel: KernelLabelValue(debugKernelVersion)},
 				},
 			},
 		}
 
-		ctx := context.Background()
-
 		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
-			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
-				list.Items = []appsv1.DaemonSet{ds1, ds2}
+			func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+				list.Items = nodes
 				return nil
 			},
 		)
 
 		dc := NewCreator(clnt, kernelLabel, scheme)
 
-		m, err := dc.ModuleDaemonSetsByKernelVersion(ctx, moduleName, namespace)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(m).To(HaveLen(2))
-		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
-		Expect(m).To(HaveKeyWithValue(otherKernelVersion, &ds2))
+		Expect(dc.CheckSelectorMatchesNodes(ctx, &mod, sets.NewString(debugKernelVersion))).NotTo(HaveOccurred())
 	})
+})
 
-	It("should include a map entry for device plugin", func() {
-		ds1 := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "ds1",
-				Namespace: namespace,
-				Labels: map[string]string{
-					"kmm.node.kubernetes.io/module.name": moduleName,
-					kernelLabel:                          kernelVersion,
-				},
-			},
-		}
+var _ = Describe("NodeModuleStatus", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
 
-		ds2 := appsv1.DaemonSet{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      "ds2",
-				Namespace: namespace,
-				Labels: map[string]string{
-					"kmm.node.kubernetes.io/module.name": moduleName,
-				},
-			},
+	mod := kmmv1beta1.Module{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      moduleName,
+			Namespace: namespace,
+		},
+		Spec: kmmv1beta1.ModuleSpec{
+			Selector: map[string]string{"has-feature-x": "true"},
+		},
+	}
+
+	It("should count the nodes that have the module loaded", func() {
+		readyLabel := "kmm.node.kubernetes.io/module-name.ready"
+
+		nodes := []v1.Node{
+			{ObjectMeta: metav1.ObjectMeta{Name: "node1", Labels: map[string]string{readyLabel: ""}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node2", Labels: map[string]string{"other": ""}}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{readyLabel: ""}}},
 		}
 
 		ctx := context.Background()
 
 		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
-			func(_ interface{}, list *appsv1.DaemonSetList, _ ...interface{}) error {
-				list.Items = []appsv1.DaemonSet{ds1, ds2}
+			func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+				list.Items = nodes
 				return nil
 			},
 		)
 
 		dc := NewCreator(clnt, kernelLabel, scheme)
 
-		m, err := dc.ModuleDaemonSetsByKernelVersion(context.Background(), moduleName, namespace)
+		loaded, err := dc.NodeModuleStatus(ctx, &mod)
 		Expect(err).NotTo(HaveOccurred())
-		Expect(m).To(HaveLen(2))
-		Expect(m).To(HaveKeyWithValue(kernelVersion, &ds1))
-		Expect(m).To(HaveKeyWithValue(devicePluginKernelVersion, &ds2))
+		Expect(loaded).To(Equal(2))
+	})
+
+	It("should return an error if the nodes cannot be listed", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		_, err := dc.NodeModuleStatus(ctx, &mod)
+		Expect(err).To(HaveOccurred())
 	})
 })
 
-var _ = Describe("GetPodPullSecrets", func() {
-	It("should return nil if the secret is nil", func() {
-		Expect(
-			GetPodPullSecrets(nil),
-		).To(
-			BeNil(),
-		)
+var _ = Describe("ValidateDaemonSet", func() {
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
 	})
 
-	It("should a slice with the secret inside", func() {
-		lor := v1.LocalObjectReference{Name: "test"}
+	It("should do nothing if dry-run validation is disabled", func() {
+		dc := NewCreator(clnt, kernelLabel, scheme)
 
 		Expect(
-			GetPodPullSecrets(&lor),
-		).To(
-			Equal([]v1.LocalObjectReference{lor}),
+			dc.ValidateDaemonSet(context.Background(), &appsv1.DaemonSet{}),
+		).NotTo(
+			HaveOccurred(),
 		)
 	})
-})
 
-var _ = Describe("OverrideLabels", func() {
-	It("should create a labels map if it was empty", func() {
-		overrides := map[string]string{"a": "b"}
+	It("should create a new DaemonSet in dry-run mode", func() {
+		ctx := context.Background()
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{GenerateName: moduleName + "-", Namespace: namespace},
+		}
+
+		clnt.
+			EXPECT().
+			Create(ctx, gomock.Any(), ctrlclient.DryRunAll).
+			Do(func(_ context.Context, obj *appsv1.DaemonSet, _ ...ctrlclient.CreateOption) {
+				Expect(obj.GenerateName).To(Equal(moduleName + "-"))
+			})
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithDryRunValidation(true))
 
 		Expect(
-			OverrideLabels(nil, overrides),
-		).To(
-			Equal(overrides),
+			dc.ValidateDaemonSet(ctx, &ds),
+		).NotTo(
+			HaveOccurred(),
 		)
 	})
 
-	It("should override existing values", func() {
-		labels := map[string]string{"a": "b", "c": "d"}
-		overrides := map[string]string{"a": "z"}
+	It("should server-side apply an existing DaemonSet in dry-run mode", func() {
+		ctx := context.Background()
+
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "some-ds", Namespace: namespace, ResourceVersion: "123"},
+			Status:     appsv1.DaemonSetStatus{NumberReady: 2},
+		}
+
+		clnt.
+			EXPECT().
+			Patch(ctx, gomock.Any(), ctrlclient.Apply, ctrlclient.DryRunAll, ctrlclient.FieldOwner("kmm"), ctrlclient.ForceOwnership).
+			Do(func(_ context.Context, obj *appsv1.DaemonSet, _ ctrlclient.Patch, _ ...ctrlclient.PatchOption) {
+				Expect(obj.APIVersion).To(Equal("apps/v1"))
+				Expect(obj.Kind).To(Equal("DaemonSet"))
+				Expect(obj.ResourceVersion).To(BeEmpty())
+				Expect(obj.Status).To(Equal(appsv1.DaemonSetStatus{}))
+			})
+
+		dc := NewCreator(clnt, kernelLabel, scheme, WithDryRunValidation(true))
 
 		Expect(
-			OverrideLabels(labels, overrides),
-		).To(
-			Equal(map[string]string{"a": "z", "c": "d"}),
+			dc.ValidateDaemonSet(ctx, &ds),
+		).NotTo(
+			HaveOccurred(),
 		)
+
+		Expect(ds.ResourceVersion).To(Equal("123"))
+		Expect(ds.Status.NumberReady).To(BeEquivalentTo(2))
 	})
-})
 
-var _ = Describe("GetNodeLabelFromPod", func() {
-	var dc DaemonSetCreator
+	DescribeTable("should return a ValidationError if the API server rejects the DaemonSet",
+		func(ds appsv1.DaemonSet, apiErr error, validationErr bool) {
+			ctx := context.Background()
+
+			if ds.Name == "" {
+				clnt.EXPECT().Create(ctx, gomock.Any(), gomock.Any()).Return(apiErr)
+			} else {
+				clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any(), gomock.Any()).Return(apiErr)
+			}
+
+			dc := NewCreator(clnt, kernelLabel, scheme, WithDryRunValidation(true))
+
+			err := dc.ValidateDaemonSet(ctx, &ds)
+			Expect(err).To(HaveOccurred())
+
+			var target *ValidationError
+			Expect(errors.As(err, &target)).To(Equal(validationErr))
+		},
+		Entry(
+			"invalid new DaemonSet",
+			appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{GenerateName: "ds-"}},
+			apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "DaemonSet"}, "", nil),
+			true,
+		),
+		Entry(
+			"forbidden existing DaemonSet",
+			appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds"}},
+			apierrors.NewForbidden(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, "ds", errors.New("violates PodSecurity")),
+			true,
+		),
+		Entry(
+			"transient error",
+			appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ds"}},
+			apierrors.NewServerTimeout(schema.GroupResource{Group: "apps", Resource: "daemonsets"}, "patch", 1),
+			false,
+		),
+	)
+})
 
+var _ = Describe("ModulePods", func() {
 	BeforeEach(func() {
-		dc = NewCreator(clnt, kernelLabel, scheme)
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
 	})
 
-	It("should return a driver container label", func() {
-		pod := v1.Pod{
+	makePod := func(name, ns, modName string) v1.Pod {
+		return v1.Pod{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					constants.ModuleNameLabel: moduleName,
-					kernelLabel:               "some kernel",
-				},
+				Name:      name,
+				Namespace: ns,
+				Labels:    map[string]string{constants.ModuleNameLabel: modName},
 			},
 		}
-		res := dc.GetNodeLabelFromPod(&pod, "module-name")
-		Expect(res).To(Equal(getDriverContainerNodeLabel("module-name")))
-	})
+	}
 
-	It("should return a device plugin label", func() {
-		pod := v1.Pod{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: map[string]string{
-					constants.ModuleNameLabel: moduleName,
-				},
-			},
+	It("should return the pods of the Module across all pages", func() {
+		allPods := []v1.Pod{
+			makePod("pod-1", namespace, moduleName),
+			makePod("pod-2", namespace, "other-module"),
+			makePod("pod-3", "other-namespace", moduleName),
 		}
-		res := dc.GetNodeLabelFromPod(&pod, "module-name")
-		Expect(res).To(Equal(getDevicePluginNodeLabel("module-name")))
-	})
-})
-
-var _ = Describe("MakeLoadCommand", func() {
-	const (
-		kernelModuleName = "some-kmod"
-		moduleName       = "module-name"
-	)
 
-	It("should only use raw arguments if they are provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			RawArgs: &kmmv1beta1.ModprobeArgs{
-				Load: []string{"load", "arguments"},
-			},
+		for i := 0; i < 2*modulePodsPageSize; i++ {
+			allPods = append(allPods, makePod(fmt.Sprintf("pod-%d", i+4), namespace, moduleName))
 		}
 
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				"modprobe load arguments",
-			}),
-		)
-	})
+		ctx := context.Background()
 
-	It("should build the command from the spec as expected", func() {
-		const (
-			arg1 = "arg1"
-			arg2 = "arg2"
-			dir  = "/some-dir"
-		)
+		// emulate the API server: filter the pods, then return them in pages
+		clnt.
+			EXPECT().
+			List(ctx, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ interface{}, list *v1.PodList, opts ...ctrlclient.ListOption) error {
+				lo := ctrlclient.ListOptions{}
+				lo.ApplyOptions(opts)
 
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			Parameters: []string{arg1, arg2},
-			DirName:    dir,
-		}
+				Expect(lo.Limit).To(BeEquivalentTo(modulePodsPageSize))
 
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -v -d %s %s %s %s", dir, kernelModuleName, arg1, arg2),
-			}),
-		)
-	})
+				matching := make([]v1.Pod, 0)
 
-	It("should use provided arguments if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			Args: &kmmv1beta1.ModprobeArgs{
-				Load: []string{"-z", "-k"},
-			},
-			ModuleName: kernelModuleName,
-		}
+				for _, p := range allPods {
+					if p.Namespace == lo.Namespace && lo.LabelSelector.Matches(labels.Set(p.Labels)) {
+						matching = append(matching, p)
+					}
+				}
 
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -z -k %s", kernelModuleName),
-			}),
-		)
-	})
+				start := 0
+				if lo.Continue != "" {
+					_, err := fmt.Sscan(lo.Continue, &start)
+					Expect(err).NotTo(HaveOccurred())
+				}
 
-	It("should use the firmware path if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			FirmwarePath: "/kmm/firmware/mymodule",
-			ModuleName:   kernelModuleName,
+				end := start + int(lo.Limit)
+				if end < len(matching) {
+					list.Continue = fmt.Sprint(end)
+				} else {
+					end = len(matching)
+				}
+
+				list.Items = matching[start:end]
+
+				return nil
+			}).
+			Times(3)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		pods, err := dc.ModulePods(ctx, moduleName, namespace)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods).To(HaveLen(1 + 2*modulePodsPageSize))
+
+		for _, p := range pods {
+			Expect(p.Namespace).To(Equal(namespace))
+			Expect(p.Labels).To(HaveKeyWithValue(constants.ModuleNameLabel, moduleName))
 		}
+	})
 
-		Expect(
-			MakeLoadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("cp -r /kmm/firmware/mymodule /var/lib/firmware/module-name && modprobe -v %s", kernelModuleName),
-			}),
-		)
+	It("should return an error if the pods cannot be listed", func() {
+		ctx := context.Background()
+
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		_, err := dc.ModulePods(ctx, moduleName, namespace)
+		Expect(err).To(HaveOccurred())
 	})
 })
 
-var _ = Describe("MakeUnloadCommand", func() {
+var _ = Describe("RemoveModuleNodeLabels", func() {
 	const (
-		kernelModuleName = "some-kmod"
-		moduleName       = "module-name"
+		driverLabel       = "kmm.node.kubernetes.io/module-name.ready"
+		devicePluginLabel = "kmm.node.kubernetes.io/module-name.device-plugin-ready"
 	)
 
-	It("should only use raw arguments if they are provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			RawArgs: &kmmv1beta1.ModprobeArgs{
-				Unload: []string{"unload", "arguments"},
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		clnt = client.NewMockClient(ctrl)
+	})
+
+	It("should only remove the module labels from the nodes that have them", func() {
+		node1 := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{driverLabel: "", devicePluginLabel: "", "other": ""},
 			},
 		}
 
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				"modprobe unload arguments",
-			}),
-		)
-	})
-
-	It("should build the command from the spec as expected", func() {
-		const dir = "/some-dir"
+		node2 := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node2",
+				Labels: map[string]string{driverLabel: ""},
+			},
+		}
 
-		spec := kmmv1beta1.ModprobeSpec{
-			ModuleName: kernelModuleName,
-			DirName:    dir,
+		node3 := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node3",
+				Labels: map[string]string{"other": ""},
+			},
 		}
 
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -rv -d %s %s", dir, kernelModuleName),
-			}),
+		ctx := context.Background()
+
+		patched := make(map[string]map[string]string)
+
+		gomock.InOrder(
+			clnt.EXPECT().List(ctx, gomock.Any(), ctrlclient.HasLabels{driverLabel}).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{node1, node2}
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), ctrlclient.HasLabels{devicePluginLabel}).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{*node1.DeepCopy(), node3}
+					return nil
+				},
+			),
 		)
+
+		clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+			func(_ interface{}, node *v1.Node, _ ctrlclient.Patch, _ ...interface{}) error {
+				patched[node.Name] = node.Labels
+				return nil
+			},
+		).Times(2)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		Expect(dc.RemoveModuleNodeLabels(ctx, moduleName)).To(Succeed())
+		Expect(patched).To(Equal(map[string]map[string]string{
+			"node1": {"other": ""},
+			"node2": {},
+		}))
 	})
 
-	It("should use provided arguments if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			Args: &kmmv1beta1.ModprobeArgs{
-				Unload: []string{"-z", "-k"},
+	It("should return an error if a node cannot be patched", func() {
+		node := v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{driverLabel: ""},
 			},
-			ModuleName: kernelModuleName,
 		}
 
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -z -k %s", kernelModuleName),
-			}),
+		ctx := context.Background()
+
+		gomock.InOrder(
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ interface{}, list *v1.NodeList, _ ...interface{}) error {
+					list.Items = []v1.Node{node}
+					return nil
+				},
+			),
+			clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()),
+			clnt.EXPECT().Patch(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error")),
 		)
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		Expect(dc.RemoveModuleNodeLabels(ctx, moduleName)).To(HaveOccurred())
 	})
 
-	It("should use the firmware path if provided", func() {
-		spec := kmmv1beta1.ModprobeSpec{
-			FirmwarePath: "/kmm/firmware/mymodule",
-			ModuleName:   kernelModuleName,
-		}
+	It("should return an error if the nodes cannot be listed", func() {
+		ctx := context.Background()
 
-		Expect(
-			MakeUnloadCommand(spec, moduleName),
-		).To(
-			Equal([]string{
-				"/bin/sh",
-				"-c",
-				fmt.Sprintf("modprobe -rv %s && rm -rf /var/lib/firmware/module-name", kernelModuleName),
-			}),
-		)
+		clnt.EXPECT().List(ctx, gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+		dc := NewCreator(clnt, kernelLabel, scheme)
+
+		Expect(dc.RemoveModuleNodeLabels(ctx, moduleName)).To(HaveOccurred())
 	})
 })
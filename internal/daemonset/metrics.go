@@ -0,0 +1,47 @@
+package daemonset
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// When adding metric names, see https://prometheus.io/docs/practices/naming/#metric-names
+const (
+	moduleDaemonSetsQuery          = "kmmo_module_daemonsets"
+	moduleKernelVersionsQuery      = "kmmo_module_kernel_versions"
+	garbageCollectedDaemonSetQuery = "kmmo_garbage_collected_daemonsets_total"
+)
+
+var (
+	moduleDaemonSets = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: moduleDaemonSetsQuery,
+			Help: "For a given kmmo and namespace, the number of existing DaemonSets.",
+		},
+		[]string{"kmmo", "namespace"},
+	)
+
+	moduleKernelVersions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: moduleKernelVersionsQuery,
+			Help: "For a given kmmo and namespace, the number of kernel versions for which a module loader DaemonSet exists.",
+		},
+		[]string{"kmmo", "namespace"},
+	)
+
+	garbageCollectedDaemonSets = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: garbageCollectedDaemonSetQuery,
+			Help: "For a given kmmo and namespace, the number of DaemonSets deleted by the garbage collection.",
+		},
+		[]string{"kmmo", "namespace"},
+	)
+)
+
+// RegisterMetrics registers the DaemonSet metrics with reg.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(
+		moduleDaemonSets,
+		moduleKernelVersions,
+		garbageCollectedDaemonSets,
+	)
+}
@@ -7,11 +7,14 @@ package daemonset
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	v1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	v1 "k8s.io/api/apps/v1"
 	v10 "k8s.io/api/core/v1"
+	v11 "k8s.io/api/policy/v1"
+	types "k8s.io/apimachinery/pkg/types"
 	sets "k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -38,19 +41,91 @@ func (m *MockDaemonSetCreator) EXPECT() *MockDaemonSetCreatorMockRecorder {
 	return m.recorder
 }
 
-// GarbageCollect mocks base method.
-func (m *MockDaemonSetCreator) GarbageCollect(ctx context.Context, existingDS map[string]*v1.DaemonSet, validKernels sets.String) ([]string, error) {
+// AllModuleDaemonSets mocks base method.
+func (m *MockDaemonSetCreator) AllModuleDaemonSets(ctx context.Context) (map[types.NamespacedName][]v1.DaemonSet, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GarbageCollect", ctx, existingDS, validKernels)
+	ret := m.ctrl.Call(m, "AllModuleDaemonSets", ctx)
+	ret0, _ := ret[0].(map[types.NamespacedName][]v1.DaemonSet)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllModuleDaemonSets indicates an expected call of AllModuleDaemonSets.
+func (mr *MockDaemonSetCreatorMockRecorder) AllModuleDaemonSets(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllModuleDaemonSets", reflect.TypeOf((*MockDaemonSetCreator)(nil).AllModuleDaemonSets), ctx)
+}
+
+// CheckSelectorMatchesNodes mocks base method.
+func (m *MockDaemonSetCreator) CheckSelectorMatchesNodes(ctx context.Context, mod *v1beta1.Module, kernelVersions sets.String) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckSelectorMatchesNodes", ctx, mod, kernelVersions)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckSelectorMatchesNodes indicates an expected call of CheckSelectorMatchesNodes.
+func (mr *MockDaemonSetCreatorMockRecorder) CheckSelectorMatchesNodes(ctx, mod, kernelVersions interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckSelectorMatchesNodes", reflect.TypeOf((*MockDaemonSetCreator)(nil).CheckSelectorMatchesNodes), ctx, mod, kernelVersions)
+}
+
+// DeleteCrossNamespaceDevicePlugins mocks base method.
+func (m *MockDaemonSetCreator) DeleteCrossNamespaceDevicePlugins(ctx context.Context, name, namespace, keepNamespace string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteCrossNamespaceDevicePlugins", ctx, name, namespace, keepNamespace)
 	ret0, _ := ret[0].([]string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// DeleteCrossNamespaceDevicePlugins indicates an expected call of DeleteCrossNamespaceDevicePlugins.
+func (mr *MockDaemonSetCreatorMockRecorder) DeleteCrossNamespaceDevicePlugins(ctx, name, namespace, keepNamespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteCrossNamespaceDevicePlugins", reflect.TypeOf((*MockDaemonSetCreator)(nil).DeleteCrossNamespaceDevicePlugins), ctx, name, namespace, keepNamespace)
+}
+
+// GarbageCollect mocks base method.
+func (m *MockDaemonSetCreator) GarbageCollect(ctx context.Context, existingDS map[string]*v1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) (*GCResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GarbageCollect", ctx, existingDS, validKernels, devicePluginEnabled)
+	ret0, _ := ret[0].(*GCResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // GarbageCollect indicates an expected call of GarbageCollect.
-func (mr *MockDaemonSetCreatorMockRecorder) GarbageCollect(ctx, existingDS, validKernels interface{}) *gomock.Call {
+func (mr *MockDaemonSetCreatorMockRecorder) GarbageCollect(ctx, existingDS, validKernels, devicePluginEnabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollect", reflect.TypeOf((*MockDaemonSetCreator)(nil).GarbageCollect), ctx, existingDS, validKernels, devicePluginEnabled)
+}
+
+// GarbageCollectPlan mocks base method.
+func (m *MockDaemonSetCreator) GarbageCollectPlan(existingDS map[string]*v1.DaemonSet, validKernels sets.String, devicePluginEnabled bool) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GarbageCollectPlan", existingDS, validKernels, devicePluginEnabled)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GarbageCollectPlan indicates an expected call of GarbageCollectPlan.
+func (mr *MockDaemonSetCreatorMockRecorder) GarbageCollectPlan(existingDS, validKernels, devicePluginEnabled interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollect", reflect.TypeOf((*MockDaemonSetCreator)(nil).GarbageCollect), ctx, existingDS, validKernels)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollectPlan", reflect.TypeOf((*MockDaemonSetCreator)(nil).GarbageCollectPlan), existingDS, validKernels, devicePluginEnabled)
+}
+
+// GetModuleNameLabel mocks base method.
+func (m *MockDaemonSetCreator) GetModuleNameLabel() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetModuleNameLabel")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetModuleNameLabel indicates an expected call of GetModuleNameLabel.
+func (mr *MockDaemonSetCreatorMockRecorder) GetModuleNameLabel() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetModuleNameLabel", reflect.TypeOf((*MockDaemonSetCreator)(nil).GetModuleNameLabel))
 }
 
 // GetNodeLabelFromPod mocks base method.
@@ -67,13 +142,56 @@ func (mr *MockDaemonSetCreatorMockRecorder) GetNodeLabelFromPod(pod, moduleName
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeLabelFromPod", reflect.TypeOf((*MockDaemonSetCreator)(nil).GetNodeLabelFromPod), pod, moduleName)
 }
 
+// GetNodeLabelerFinalizer mocks base method.
+func (m *MockDaemonSetCreator) GetNodeLabelerFinalizer() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeLabelerFinalizer")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetNodeLabelerFinalizer indicates an expected call of GetNodeLabelerFinalizer.
+func (mr *MockDaemonSetCreatorMockRecorder) GetNodeLabelerFinalizer() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeLabelerFinalizer", reflect.TypeOf((*MockDaemonSetCreator)(nil).GetNodeLabelerFinalizer))
+}
+
+// KernelLabel mocks base method.
+func (m *MockDaemonSetCreator) KernelLabel() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KernelLabel")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// KernelLabel indicates an expected call of KernelLabel.
+func (mr *MockDaemonSetCreatorMockRecorder) KernelLabel() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KernelLabel", reflect.TypeOf((*MockDaemonSetCreator)(nil).KernelLabel))
+}
+
+// KernelVersion mocks base method.
+func (m *MockDaemonSetCreator) KernelVersion(ds *v1.DaemonSet) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KernelVersion", ds)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// KernelVersion indicates an expected call of KernelVersion.
+func (mr *MockDaemonSetCreatorMockRecorder) KernelVersion(ds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KernelVersion", reflect.TypeOf((*MockDaemonSetCreator)(nil).KernelVersion), ds)
+}
+
 // ModuleDaemonSetsByKernelVersion mocks base method.
-func (m *MockDaemonSetCreator) ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*v1.DaemonSet, error) {
+func (m *MockDaemonSetCreator) ModuleDaemonSetsByKernelVersion(ctx context.Context, name, namespace string) (map[string]*v1.DaemonSet, []*v1.DaemonSet, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ModuleDaemonSetsByKernelVersion", ctx, name, namespace)
 	ret0, _ := ret[0].(map[string]*v1.DaemonSet)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].([]*v1.DaemonSet)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // ModuleDaemonSetsByKernelVersion indicates an expected call of ModuleDaemonSetsByKernelVersion.
@@ -82,6 +200,50 @@ func (mr *MockDaemonSetCreatorMockRecorder) ModuleDaemonSetsByKernelVersion(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModuleDaemonSetsByKernelVersion", reflect.TypeOf((*MockDaemonSetCreator)(nil).ModuleDaemonSetsByKernelVersion), ctx, name, namespace)
 }
 
+// ModulePods mocks base method.
+func (m *MockDaemonSetCreator) ModulePods(ctx context.Context, name, namespace string) ([]v10.Pod, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ModulePods", ctx, name, namespace)
+	ret0, _ := ret[0].([]v10.Pod)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ModulePods indicates an expected call of ModulePods.
+func (mr *MockDaemonSetCreatorMockRecorder) ModulePods(ctx, name, namespace interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ModulePods", reflect.TypeOf((*MockDaemonSetCreator)(nil).ModulePods), ctx, name, namespace)
+}
+
+// NodeModuleStatus mocks base method.
+func (m *MockDaemonSetCreator) NodeModuleStatus(ctx context.Context, mod *v1beta1.Module) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NodeModuleStatus", ctx, mod)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NodeModuleStatus indicates an expected call of NodeModuleStatus.
+func (mr *MockDaemonSetCreatorMockRecorder) NodeModuleStatus(ctx, mod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NodeModuleStatus", reflect.TypeOf((*MockDaemonSetCreator)(nil).NodeModuleStatus), ctx, mod)
+}
+
+// RemoveModuleNodeLabels mocks base method.
+func (m *MockDaemonSetCreator) RemoveModuleNodeLabels(ctx context.Context, moduleName string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveModuleNodeLabels", ctx, moduleName)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemoveModuleNodeLabels indicates an expected call of RemoveModuleNodeLabels.
+func (mr *MockDaemonSetCreatorMockRecorder) RemoveModuleNodeLabels(ctx, moduleName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveModuleNodeLabels", reflect.TypeOf((*MockDaemonSetCreator)(nil).RemoveModuleNodeLabels), ctx, moduleName)
+}
+
 // SetDevicePluginAsDesired mocks base method.
 func (m *MockDaemonSetCreator) SetDevicePluginAsDesired(ctx context.Context, ds *v1.DaemonSet, mod *v1beta1.Module) error {
 	m.ctrl.T.Helper()
@@ -96,16 +258,58 @@ func (mr *MockDaemonSetCreatorMockRecorder) SetDevicePluginAsDesired(ctx, ds, mo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDevicePluginAsDesired", reflect.TypeOf((*MockDaemonSetCreator)(nil).SetDevicePluginAsDesired), ctx, ds, mod)
 }
 
+// SetDevicePluginPodDisruptionBudgetAsDesired mocks base method.
+func (m *MockDaemonSetCreator) SetDevicePluginPodDisruptionBudgetAsDesired(ctx context.Context, pdb *v11.PodDisruptionBudget, mod *v1beta1.Module) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDevicePluginPodDisruptionBudgetAsDesired", ctx, pdb, mod)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDevicePluginPodDisruptionBudgetAsDesired indicates an expected call of SetDevicePluginPodDisruptionBudgetAsDesired.
+func (mr *MockDaemonSetCreatorMockRecorder) SetDevicePluginPodDisruptionBudgetAsDesired(ctx, pdb, mod interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDevicePluginPodDisruptionBudgetAsDesired", reflect.TypeOf((*MockDaemonSetCreator)(nil).SetDevicePluginPodDisruptionBudgetAsDesired), ctx, pdb, mod)
+}
+
 // SetDriverContainerAsDesired mocks base method.
-func (m *MockDaemonSetCreator) SetDriverContainerAsDesired(ctx context.Context, ds *v1.DaemonSet, image string, mod v1beta1.Module, kernelVersion string) error {
+func (m *MockDaemonSetCreator) SetDriverContainerAsDesired(ctx context.Context, ds *v1.DaemonSet, image string, mod v1beta1.Module, kernelVersion, arch string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetDriverContainerAsDesired", ctx, ds, image, mod, kernelVersion)
+	ret := m.ctrl.Call(m, "SetDriverContainerAsDesired", ctx, ds, image, mod, kernelVersion, arch)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetDriverContainerAsDesired indicates an expected call of SetDriverContainerAsDesired.
-func (mr *MockDaemonSetCreatorMockRecorder) SetDriverContainerAsDesired(ctx, ds, image, mod, kernelVersion interface{}) *gomock.Call {
+func (mr *MockDaemonSetCreatorMockRecorder) SetDriverContainerAsDesired(ctx, ds, image, mod, kernelVersion, arch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDriverContainerAsDesired", reflect.TypeOf((*MockDaemonSetCreator)(nil).SetDriverContainerAsDesired), ctx, ds, image, mod, kernelVersion, arch)
+}
+
+// ValidateDaemonSet mocks base method.
+func (m *MockDaemonSetCreator) ValidateDaemonSet(ctx context.Context, ds *v1.DaemonSet) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateDaemonSet", ctx, ds)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateDaemonSet indicates an expected call of ValidateDaemonSet.
+func (mr *MockDaemonSetCreatorMockRecorder) ValidateDaemonSet(ctx, ds interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateDaemonSet", reflect.TypeOf((*MockDaemonSetCreator)(nil).ValidateDaemonSet), ctx, ds)
+}
+
+// WaitForModuleDaemonSetsReady mocks base method.
+func (m *MockDaemonSetCreator) WaitForModuleDaemonSetsReady(ctx context.Context, name, namespace string, timeout time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForModuleDaemonSetsReady", ctx, name, namespace, timeout)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitForModuleDaemonSetsReady indicates an expected call of WaitForModuleDaemonSetsReady.
+func (mr *MockDaemonSetCreatorMockRecorder) WaitForModuleDaemonSetsReady(ctx, name, namespace, timeout interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDriverContainerAsDesired", reflect.TypeOf((*MockDaemonSetCreator)(nil).SetDriverContainerAsDesired), ctx, ds, image, mod, kernelVersion)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForModuleDaemonSetsReady", reflect.TypeOf((*MockDaemonSetCreator)(nil).WaitForModuleDaemonSetsReady), ctx, name, namespace, timeout)
 }
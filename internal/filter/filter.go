@@ -2,9 +2,12 @@ package filter
 
 import (
 	"context"
+	"strings"
 
 	"github.com/go-logr/logr"
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -48,7 +51,7 @@ func (f *Filter) ModuleReconcilerNodePredicate(kernelLabel string) predicate.Pre
 
 func (f *Filter) NodeKernelReconcilerPredicate(labelName string) predicate.Predicate {
 	labelMismatch := predicate.NewPredicateFuncs(func(o client.Object) bool {
-		return o.GetLabels()[labelName] != o.(*v1.Node).Status.NodeInfo.KernelVersion
+		return o.GetLabels()[labelName] != daemonset.KernelLabelValue(o.(*v1.Node).Status.NodeInfo.KernelVersion)
 	})
 
 	return predicate.And(skipDeletions, labelMismatch)
@@ -107,6 +110,31 @@ func (f *Filter) FindModulesForNode(node client.Object) []reconcile.Request {
 	return reqs
 }
 
+// CrossNamespaceDevicePluginPredicate returns a predicate that returns true for the device plugin DaemonSets created
+// in another namespace than their Module, which cannot be watched through owner references.
+func CrossNamespaceDevicePluginPredicate() predicate.Predicate {
+	return predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetLabels()[constants.DaemonSetRole] == constants.DevicePluginRole &&
+			o.GetAnnotations()[constants.OwnerAnnotation] != ""
+	})
+}
+
+// FindModuleForCrossNamespaceDevicePlugin returns a request for the Module that ds was created for, according to its
+// constants.OwnerAnnotation.
+func (f *Filter) FindModuleForCrossNamespaceDevicePlugin(ds client.Object) []reconcile.Request {
+	owner := ds.GetAnnotations()[constants.OwnerAnnotation]
+
+	parts := strings.SplitN(owner, string(types.Separator), 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		f.logger.Info("Ignoring invalid owner annotation", "daemonset", client.ObjectKeyFromObject(ds), "owner", owner)
+		return nil
+	}
+
+	nsn := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+
+	return []reconcile.Request{{NamespacedName: nsn}}
+}
+
 func (f *Filter) EnqueueAllPreflightValidations(mod client.Object) []reconcile.Request {
 	reqs := make([]reconcile.Request, 0)
 
@@ -7,6 +7,8 @@ import (
 	"github.com/golang/mock/gomock"
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	mockClient "github.com/kubernetes-sigs/kernel-module-management/internal/client"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
@@ -227,6 +229,27 @@ var _ = Describe("NodeKernelReconcilerPredicate", func() {
 			BeFalse(),
 		)
 	})
+
+	It("should return false if the label holds the sanitized kernel version", func() {
+		const debugKernelVersion = "1.2.3+debug"
+
+		ev := event.CreateEvent{
+			Object: &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{labelName: daemonset.KernelLabelValue(debugKernelVersion)},
+				},
+				Status: v1.NodeStatus{
+					NodeInfo: v1.NodeSystemInfo{KernelVersion: debugKernelVersion},
+				},
+			},
+		}
+
+		Expect(
+			p.Create(ev),
+		).To(
+			BeFalse(),
+		)
+	})
 })
 
 var _ = Describe("FindModulesForNode", func() {
@@ -387,6 +410,84 @@ var _ = Describe("PodReadinessChangedPredicate", func() {
 	)
 })
 
+var _ = Describe("CrossNamespaceDevicePluginPredicate", func() {
+	p := CrossNamespaceDevicePluginPredicate()
+
+	makeDS := func(role, owner string) *appsv1.DaemonSet {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{constants.DaemonSetRole: role},
+			},
+		}
+
+		if owner != "" {
+			ds.Annotations = map[string]string{constants.OwnerAnnotation: owner}
+		}
+
+		return &ds
+	}
+
+	DescribeTable("should return the expected value",
+		func(o client.Object, expected bool) {
+			Expect(
+				p.Update(event.UpdateEvent{ObjectOld: o, ObjectNew: o}),
+			).To(
+				Equal(expected),
+			)
+		},
+		Entry("no labels", &appsv1.DaemonSet{}, false),
+		Entry("device plugin in the Module's namespace", makeDS(constants.DevicePluginRole, ""), false),
+		Entry("module loader with an owner annotation", makeDS(constants.ModuleLoaderRole, "ns/mod"), false),
+		Entry("device plugin in another namespace", makeDS(constants.DevicePluginRole, "ns/mod"), true),
+	)
+
+	It("should return true for delete events", func() {
+		Expect(
+			p.Delete(event.DeleteEvent{Object: makeDS(constants.DevicePluginRole, "ns/mod")}),
+		).To(
+			BeTrue(),
+		)
+	})
+})
+
+var _ = Describe("FindModuleForCrossNamespaceDevicePlugin", func() {
+	f := New(nil, logr.Discard())
+
+	It("should return the Module from the owner annotation", func() {
+		ds := appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "mod-device-plugin",
+				Namespace:   "device-plugins",
+				Annotations: map[string]string{constants.OwnerAnnotation: "ns/mod"},
+			},
+		}
+
+		Expect(
+			f.FindModuleForCrossNamespaceDevicePlugin(&ds),
+		).To(
+			Equal([]reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mod"}},
+			}),
+		)
+	})
+
+	DescribeTable("should return no request for an invalid annotation",
+		func(owner string) {
+			ds := appsv1.DaemonSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{constants.OwnerAnnotation: owner},
+				},
+			}
+
+			Expect(f.FindModuleForCrossNamespaceDevicePlugin(&ds)).To(BeEmpty())
+		},
+		Entry("empty", ""),
+		Entry("no namespace", "mod"),
+		Entry("empty namespace", "/mod"),
+		Entry("empty name", "ns/"),
+	)
+})
+
 var _ = Describe("FindPreflightsForModule", func() {
 
 	BeforeEach(func() {
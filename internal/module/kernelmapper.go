@@ -19,6 +19,7 @@ const (
 )
 
 type NodeOSConfig struct {
+	Architecture       string `subst:"ARCH"`
 	KernelFullVersion  string `subst:"KERNEL_FULL_VERSION"`
 	KernelVersionMMP   string `subst:"KERNEL_XYZ"`
 	KernelVersionMajor string `subst:"KERNEL_X"`
@@ -67,6 +68,7 @@ func (k *kernelMapper) GetNodeOSConfig(node *v1.Node) *NodeOSConfig {
 
 	osConfigFieldsList := regexp.MustCompile("[.,-]").Split(node.Status.NodeInfo.KernelVersion, -1)
 
+	osConfig.Architecture = node.Status.NodeInfo.Architecture
 	osConfig.KernelFullVersion = node.Status.NodeInfo.KernelVersion
 	osConfig.KernelVersionMMP = strings.Join(osConfigFieldsList[:kernelVersionPatchIdx+1], ".")
 	osConfig.KernelVersionMajor = osConfigFieldsList[kernelVersionMajorIdx]
@@ -67,6 +67,7 @@ var _ = Describe("FindMappingForKernel", func() {
 var _ = Describe("PrepareKernelMapping", func() {
 	km := NewKernelMapper()
 	osConfig := NodeOSConfig{
+		Architecture:       "arm64",
 		KernelFullVersion:  "kernelFullVersion",
 		KernelVersionMMP:   "kernelMMP",
 		KernelVersionMajor: "kernelMajor",
@@ -120,6 +121,14 @@ var _ = Describe("PrepareKernelMapping", func() {
 		Expect(err).NotTo(HaveOccurred())
 		Expect(*res).To(Equal(expectMapping))
 	})
+
+	It("should substitute the architecture in the ContainerImage field", func() {
+		mapping := kmmv1beta1.KernelMapping{ContainerImage: "some image:${KERNEL_XYZ}-${ARCH}"}
+
+		res, err := km.PrepareKernelMapping(&mapping, &osConfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.ContainerImage).To(Equal("some image:kernelMMP-arm64"))
+	})
 })
 
 var _ = Describe("GetNodeOSConfig", func() {
@@ -129,6 +138,7 @@ var _ = Describe("GetNodeOSConfig", func() {
 		node := v1.Node{
 			Status: v1.NodeStatus{
 				NodeInfo: v1.NodeSystemInfo{
+					Architecture:  "amd64",
 					KernelVersion: "4.18.0-305.45.1.el8_4.x86_64",
 					OSImage:       "Red Hat Enterprise Linux CoreOS 410.84.202205191234-0 (Ootpa)",
 				},
@@ -136,6 +146,7 @@ var _ = Describe("GetNodeOSConfig", func() {
 		}
 
 		expectedOSConfig := NodeOSConfig{
+			Architecture:       "amd64",
 			KernelFullVersion:  "4.18.0-305.45.1.el8_4.x86_64",
 			KernelVersionMMP:   "4.18.0",
 			KernelVersionMajor: "4",
@@ -6,11 +6,14 @@ import (
 	"time"
 
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/metrics"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -63,6 +66,25 @@ func (m *moduleStatusUpdater) ModuleUpdateStatus(ctx context.Context,
 	targetedNodes []v1.Node,
 	dsByKernelVersion map[string]*appsv1.DaemonSet) error {
 
+	// dsByKernelVersion only contains the DaemonSets of the Module's namespace
+	if mod.Spec.DevicePlugin != nil && daemonset.DevicePluginNamespace(mod) != mod.Namespace {
+		ds, err := m.crossNamespaceDevicePlugin(ctx, mod)
+		if err != nil {
+			return err
+		}
+
+		if ds != nil {
+			withDevicePlugin := make(map[string]*appsv1.DaemonSet, len(dsByKernelVersion)+1)
+
+			for k, v := range dsByKernelVersion {
+				withDevicePlugin[k] = v
+			}
+
+			withDevicePlugin[daemonset.GetDevicePluginKernelVersion()] = ds
+			dsByKernelVersion = withDevicePlugin
+		}
+	}
+
 	nodesMatchingSelectorNumber := int32(len(targetedNodes))
 	numDesired := int32(len(kernelMappingNodes))
 	var numAvailableDevicePlugin int32
@@ -74,9 +96,14 @@ func (m *moduleStatusUpdater) ModuleUpdateStatus(ctx context.Context,
 			numAvailableKernelModule += ds.Status.NumberAvailable
 		}
 	}
+	numLoaded, err := m.daemonAPI.NodeModuleStatus(ctx, mod)
+	if err != nil {
+		return fmt.Errorf("could not get the number of nodes on which the module is loaded: %v", err)
+	}
 	mod.Status.ModuleLoader.NodesMatchingSelectorNumber = nodesMatchingSelectorNumber
 	mod.Status.ModuleLoader.DesiredNumber = numDesired
 	mod.Status.ModuleLoader.AvailableNumber = numAvailableKernelModule
+	mod.Status.ModuleLoader.LoadedNumber = int32(numLoaded)
 	if mod.Spec.DevicePlugin != nil {
 		mod.Status.DevicePlugin.NodesMatchingSelectorNumber = nodesMatchingSelectorNumber
 		mod.Status.DevicePlugin.DesiredNumber = numDesired
@@ -86,6 +113,29 @@ func (m *moduleStatusUpdater) ModuleUpdateStatus(ctx context.Context,
 	return m.client.Status().Update(ctx, mod)
 }
 
+// crossNamespaceDevicePlugin returns the device plugin DaemonSet of mod created in another namespace, or nil if it does
+// not exist or was not created for mod, according to its constants.OwnerAnnotation.
+func (m *moduleStatusUpdater) crossNamespaceDevicePlugin(ctx context.Context, mod *kmmv1beta1.Module) (*appsv1.DaemonSet, error) {
+	ds := appsv1.DaemonSet{}
+	nsn := types.NamespacedName{Name: daemonset.DevicePluginName(mod), Namespace: daemonset.DevicePluginNamespace(mod)}
+
+	if err := m.client.Get(ctx, nsn, &ds); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("could not get the device plugin DaemonSet %s: %v", nsn, err)
+	}
+
+	owner := types.NamespacedName{Name: mod.Name, Namespace: mod.Namespace}.String()
+
+	if ds.Annotations[constants.OwnerAnnotation] != owner {
+		return nil, nil
+	}
+
+	return &ds, nil
+}
+
 func (p *preflightStatusUpdater) PreflightPresetStatuses(ctx context.Context,
 	pv *kmmv1beta1.PreflightValidation, existingModules sets.String, newModules []string) error {
 
@@ -2,18 +2,23 @@ package statusupdater
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/golang/mock/gomock"
 	kmmv1beta1 "github.com/kubernetes-sigs/kernel-module-management/api/v1beta1"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/client"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/metrics"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -72,6 +77,7 @@ var _ = Describe("module status update", func() {
 						ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled)
 				}
 			}
+			mockDC.EXPECT().NodeModuleStatus(context.Background(), mod).Return(len(mappingsNodes), nil)
 			statusWrite := client.NewMockStatusWriter(ctrl)
 			clnt.EXPECT().Status().Return(statusWrite)
 			statusWrite.EXPECT().Update(context.Background(), mod).Return(nil)
@@ -79,6 +85,7 @@ var _ = Describe("module status update", func() {
 			res := su.ModuleUpdateStatus(context.Background(), mod, mappingsNodes, targetedNodes, dsMap)
 
 			Expect(res).To(BeNil())
+			Expect(mod.Status.ModuleLoader.LoadedNumber).To(Equal(int32(len(mappingsNodes))))
 			Expect(mod.Status.ModuleLoader.NodesMatchingSelectorNumber).To(Equal(int32(len(targetedNodes))))
 			Expect(mod.Status.ModuleLoader.DesiredNumber).To(Equal(int32(len(mappingsNodes))))
 			Expect(mod.Status.ModuleLoader.AvailableNumber).To(Equal(moduleLoaderAvailable))
@@ -117,6 +124,100 @@ var _ = Describe("module status update", func() {
 			true,
 		),
 	)
+
+	It("should count the available pods of a device plugin DaemonSet in another namespace", func() {
+		const dpNamespace = "dp-namespace"
+
+		mod.Spec.DevicePlugin = &kmmv1beta1.DevicePluginSpec{Namespace: dpNamespace}
+
+		dsMap := prepareDsByKernel([]daemonSetConfig{
+			{desiredNumber: 2, numberAvailable: 2, isDevicePlugin: false},
+		})
+
+		nodes := []v1.Node{{}, {}}
+
+		for kernelVersion := range dsMap {
+			mockMetrics.EXPECT().SetCompletedStage(name, namespace, kernelVersion, metrics.ModuleLoaderStage, true)
+		}
+
+		gomock.InOrder(
+			clnt.EXPECT().Get(
+				context.Background(),
+				types.NamespacedName{Name: name + "-device-plugin", Namespace: dpNamespace},
+				gomock.AssignableToTypeOf(&appsv1.DaemonSet{}),
+			).DoAndReturn(
+				func(_ interface{}, _ interface{}, ds *appsv1.DaemonSet) error {
+					ds.Annotations = map[string]string{constants.OwnerAnnotation: namespace + "/" + name}
+					ds.Status = appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberAvailable: 1}
+					return nil
+				},
+			),
+			mockDC.EXPECT().NodeModuleStatus(context.Background(), mod).Return(2, nil),
+		)
+
+		mockMetrics.EXPECT().SetCompletedStage(
+			name,
+			namespace,
+			daemonset.GetDevicePluginKernelVersion(),
+			metrics.DevicePluginStage,
+			false,
+		)
+
+		statusWrite := client.NewMockStatusWriter(ctrl)
+		clnt.EXPECT().Status().Return(statusWrite)
+		statusWrite.EXPECT().Update(context.Background(), mod).Return(nil)
+
+		err := su.ModuleUpdateStatus(context.Background(), mod, nodes, nodes, dsMap)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(mod.Status.ModuleLoader.AvailableNumber).To(Equal(int32(2)))
+		Expect(mod.Status.DevicePlugin.AvailableNumber).To(Equal(int32(1)))
+		Expect(dsMap).To(HaveLen(1))
+	})
+
+	DescribeTable("should ignore a device plugin DaemonSet in another namespace that does not belong to the Module",
+		func(getErr error, owner string) {
+			const dpNamespace = "dp-namespace"
+
+			mod.Spec.DevicePlugin = &kmmv1beta1.DevicePluginSpec{Namespace: dpNamespace}
+
+			gomock.InOrder(
+				clnt.EXPECT().Get(context.Background(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ interface{}, _ interface{}, ds *appsv1.DaemonSet) error {
+						ds.Annotations = map[string]string{constants.OwnerAnnotation: owner}
+						ds.Status = appsv1.DaemonSetStatus{DesiredNumberScheduled: 2, NumberAvailable: 2}
+						return getErr
+					},
+				),
+				mockDC.EXPECT().NodeModuleStatus(context.Background(), mod).Return(0, nil),
+			)
+
+			statusWrite := client.NewMockStatusWriter(ctrl)
+			clnt.EXPECT().Status().Return(statusWrite)
+			statusWrite.EXPECT().Update(context.Background(), mod).Return(nil)
+
+			err := su.ModuleUpdateStatus(context.Background(), mod, []v1.Node{}, []v1.Node{}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mod.Status.DevicePlugin.AvailableNumber).To(BeZero())
+		},
+		Entry("not found", apierrors.NewNotFound(schema.GroupResource{}, "whatever"), ""),
+		Entry("other owner", nil, namespace+"/other-module"),
+	)
+
+	It("should return an error if the device plugin DaemonSet in another namespace cannot be fetched", func() {
+		mod.Spec.DevicePlugin = &kmmv1beta1.DevicePluginSpec{Namespace: "dp-namespace"}
+
+		clnt.EXPECT().Get(context.Background(), gomock.Any(), gomock.Any()).Return(errors.New("some error"))
+
+		err := su.ModuleUpdateStatus(context.Background(), mod, []v1.Node{}, []v1.Node{}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return an error if the number of nodes with the module loaded cannot be determined", func() {
+		mockDC.EXPECT().NodeModuleStatus(context.Background(), mod).Return(0, errors.New("some error"))
+
+		err := su.ModuleUpdateStatus(context.Background(), mod, []v1.Node{}, []v1.Node{}, nil)
+		Expect(err).To(HaveOccurred())
+	})
 })
 
 var _ = Describe("preflight status updates", func() {
@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"os"
 	"runtime/debug"
+	"time"
 
 	"github.com/kubernetes-sigs/kernel-module-management/internal/build"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/build/job"
+	"github.com/kubernetes-sigs/kernel-module-management/internal/constants"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/daemonset"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/filter"
 	"github.com/kubernetes-sigs/kernel-module-management/internal/metrics"
@@ -46,6 +48,7 @@ import (
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	runtimemetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -60,10 +63,19 @@ func init() {
 
 func main() {
 	var (
-		configFile           string
-		metricsAddr          string
-		enableLeaderElection bool
-		probeAddr            string
+		configFile               string
+		dryRunValidation         bool
+		metricsAddr              string
+		enableLeaderElection     bool
+		gcConcurrency            int
+		gcMinValidKernels        int
+		gcRetention              time.Duration
+		imageDigestRequired      bool
+		kubeletDevicePluginsPath string
+		moduleNameLabel          string
+		nodeKernelLabel          string
+		nodeLabelPrefix          string
+		probeAddr                string
 	)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
@@ -74,6 +86,72 @@ func main() {
 
 	flag.StringVar(&configFile, "config", "", "The path to the configuration file.")
 
+	flag.IntVar(
+		&gcConcurrency,
+		"gc-concurrency",
+		daemonset.DefaultGarbageCollectionConcurrency,
+		"The maximum number of stale DaemonSets of a Module that are deleted in parallel.",
+	)
+
+	flag.IntVar(
+		&gcMinValidKernels,
+		"gc-min-valid-kernels",
+		1,
+		"The minimum number of kernel versions targeted by a Module for its stale module loader DaemonSets to be "+
+			"garbage-collected. Prevents deleting all of them while the kernel versions of the nodes are unknown; "+
+			"0 disables this safeguard.",
+	)
+
+	flag.DurationVar(
+		&gcRetention,
+		"gc-retention",
+		0,
+		"How long to keep the module loader DaemonSets of kernel versions that are not used anymore.",
+	)
+
+	flag.BoolVar(
+		&dryRunValidation,
+		"dry-run-validation",
+		false,
+		"Submit the module loader DaemonSets to the API server in dry-run mode before persisting them.",
+	)
+
+	flag.BoolVar(
+		&imageDigestRequired,
+		"require-image-digest",
+		false,
+		"Only accept module loader images referenced by digest.",
+	)
+
+	flag.StringVar(
+		&kubeletDevicePluginsPath,
+		"kubelet-device-plugins-path",
+		daemonset.DefaultKubeletDevicePluginsPath,
+		"The directory of the nodes in which the kubelet expects device plugins to create their socket.",
+	)
+
+	flag.StringVar(
+		&moduleNameLabel,
+		"module-name-label",
+		constants.ModuleNameLabel,
+		"The key of the label holding the name of the Module on the DaemonSets and their pods.",
+	)
+
+	flag.StringVar(
+		&nodeKernelLabel,
+		"node-kernel-label",
+		"",
+		"The key of the node label holding the kernel version, used to schedule the module loader pods. "+
+			"Defaults to the label set by KMM.",
+	)
+
+	flag.StringVar(
+		&nodeLabelPrefix,
+		"node-label-prefix",
+		daemonset.DefaultNodeLabelPrefix,
+		"The domain of the node labels that indicate that a module or its device plugin is ready.",
+	)
+
 	klog.InitFlags(flag.CommandLine)
 
 	flag.Parse()
@@ -111,6 +189,10 @@ func main() {
 
 	const kernelLabel = "kmm.node.kubernetes.io/kernel-version.full"
 
+	if nodeKernelLabel == "" {
+		nodeKernelLabel = kernelLabel
+	}
+
 	nodeKernelReconciler := controllers.NewNodeKernelReconciler(client, kernelLabel, filter)
 
 	if err = nodeKernelReconciler.SetupWithManager(mgr); err != nil {
@@ -120,11 +202,25 @@ func main() {
 
 	metricsAPI := metrics.New()
 	metricsAPI.Register()
+	daemonset.RegisterMetrics(runtimemetrics.Registry)
 	registryAPI := registry.NewRegistry()
 	helperAPI := build.NewHelper()
 	makerAPI := job.NewMaker(helperAPI, scheme)
 	buildAPI := job.NewBuildManager(client, makerAPI, helperAPI)
-	daemonAPI := daemonset.NewCreator(client, kernelLabel, scheme)
+	daemonAPI := daemonset.NewCreator(
+		client,
+		kernelLabel,
+		scheme,
+		daemonset.WithDryRunValidation(dryRunValidation),
+		daemonset.WithGarbageCollectionConcurrency(gcConcurrency),
+		daemonset.WithGarbageCollectionMinValidKernels(gcMinValidKernels),
+		daemonset.WithGarbageCollectionRetention(gcRetention),
+		daemonset.WithImageDigestRequired(imageDigestRequired),
+		daemonset.WithKubeletDevicePluginsPath(kubeletDevicePluginsPath),
+		daemonset.WithModuleNameLabel(moduleNameLabel),
+		daemonset.WithNodeKernelLabel(nodeKernelLabel),
+		daemonset.WithNodeLabelPrefix(nodeLabelPrefix),
+	)
 	kernelAPI := module.NewKernelMapper()
 	moduleStatusUpdaterAPI := statusupdater.NewModuleStatusUpdater(client, daemonAPI, metricsAPI)
 	preflightStatusUpdaterAPI := statusupdater.NewPreflightStatusUpdater(client)
@@ -132,7 +228,7 @@ func main() {
 
 	mc := controllers.NewModuleReconciler(client, buildAPI, daemonAPI, kernelAPI, metricsAPI, filter, registryAPI, moduleStatusUpdaterAPI)
 
-	if err = mc.SetupWithManager(mgr, kernelLabel); err != nil {
+	if err = mc.SetupWithManager(mgr, nodeKernelLabel); err != nil {
 		setupLogger.Error(err, "unable to create controller", "controller", "Module")
 		os.Exit(1)
 	}